@@ -0,0 +1,165 @@
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// AggResult is the result of an aggregation. Metric aggregations, eg avg or cardinality, set Value, which is nil if
+// there was nothing to aggregate. Bucket aggregations, eg terms, date_histogram or range, set Buckets. Single bucket
+// aggregations, eg filter or nested, set DocCount and hold their sub-aggregations in Aggs.
+type AggResult struct {
+	Value                   *float64
+	ValueAsString           string
+	DocCount                int64
+	DocCountErrorUpperBound int64
+	SumOtherDocCount        int64
+	Buckets                 []Bucket
+	Aggs                    map[string]AggResult
+}
+
+// Bucket is one bucket of a bucket aggregation. Key is a string or float64 depending on the field, and KeyAsString
+// is the formatted key, eg the date of a date_histogram bucket. From and To are set for range buckets.
+type Bucket struct {
+	Key         interface{}
+	KeyAsString string
+	DocCount    int64
+	From        *float64
+	To          *float64
+	Aggs        map[string]AggResult
+}
+
+// Agg returns the named aggregation result, or an error if there is none
+func (r *SearchResult) Agg(name string) (*AggResult, error) {
+	var a AggResult
+	if err := r.DecodeAgg(name, &a); err != nil {
+		return nil, errors.Wrap(err, "Agg")
+	}
+	return &a, nil
+}
+
+// Agg returns the named sub-aggregation result, or an empty result if there is none
+func (a AggResult) Agg(name string) AggResult {
+	return a.Aggs[name]
+}
+
+// Agg returns the named sub-aggregation result for the bucket, or an empty result if there is none
+func (b Bucket) Agg(name string) AggResult {
+	return b.Aggs[name]
+}
+
+// UnmarshalJSON reads the fields common to every aggregation type. Any other object valued field is taken to be a
+// sub-aggregation.
+func (a *AggResult) UnmarshalJSON(xb []byte) error {
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(xb, &m); err != nil {
+		return err
+	}
+
+	for k, v := range m {
+		var err error
+		switch k {
+		case "value":
+			err = json.Unmarshal(v, &a.Value)
+		case "value_as_string":
+			err = json.Unmarshal(v, &a.ValueAsString)
+		case "doc_count":
+			err = json.Unmarshal(v, &a.DocCount)
+		case "doc_count_error_upper_bound":
+			err = json.Unmarshal(v, &a.DocCountErrorUpperBound)
+		case "sum_other_doc_count":
+			err = json.Unmarshal(v, &a.SumOtherDocCount)
+		case "buckets":
+			a.Buckets, err = buckets(v)
+		default:
+			a.Aggs, err = subAgg(a.Aggs, k, v)
+		}
+		if err != nil {
+			return errors.Wrap(err, k)
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalJSON reads the bucket key, count and range, and any sub-aggregations
+func (b *Bucket) UnmarshalJSON(xb []byte) error {
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(xb, &m); err != nil {
+		return err
+	}
+
+	for k, v := range m {
+		var err error
+		switch k {
+		case "key":
+			err = json.Unmarshal(v, &b.Key)
+		case "key_as_string":
+			err = json.Unmarshal(v, &b.KeyAsString)
+		case "doc_count":
+			err = json.Unmarshal(v, &b.DocCount)
+		case "from":
+			err = json.Unmarshal(v, &b.From)
+		case "to":
+			err = json.Unmarshal(v, &b.To)
+		default:
+			b.Aggs, err = subAgg(b.Aggs, k, v)
+		}
+		if err != nil {
+			return errors.Wrap(err, k)
+		}
+	}
+
+	return nil
+}
+
+// buckets parses the buckets of an aggregation, which are an array, or an object keyed by bucket key when the
+// aggregation is keyed. Keyed buckets are sorted by key.
+func buckets(xb []byte) ([]Bucket, error) {
+
+	if xb = bytes.TrimSpace(xb); len(xb) == 0 || xb[0] != '{' {
+		var xbk []Bucket
+		err := json.Unmarshal(xb, &xbk)
+		return xbk, err
+	}
+
+	var m map[string]Bucket
+	if err := json.Unmarshal(xb, &m); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	xbk := make([]Bucket, len(keys))
+	for i, k := range keys {
+		xbk[i] = m[k]
+		if xbk[i].Key == nil {
+			xbk[i].Key = k
+		}
+	}
+	return xbk, nil
+}
+
+// subAgg adds the field to aggs if it is an object, and so a sub-aggregation result, ignoring it otherwise, eg
+// from_as_string or meta fields
+func subAgg(aggs map[string]AggResult, name string, xb []byte) (map[string]AggResult, error) {
+	if xb = bytes.TrimSpace(xb); len(xb) == 0 || xb[0] != '{' {
+		return aggs, nil
+	}
+	var a AggResult
+	if err := json.Unmarshal(xb, &a); err != nil {
+		return aggs, err
+	}
+	if aggs == nil {
+		aggs = map[string]AggResult{}
+	}
+	aggs[name] = a
+	return aggs, nil
+}
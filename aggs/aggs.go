@@ -0,0 +1,293 @@
+// Package aggs builds Elasticsearch aggregations. Each builder marshals to the JSON for its aggregation so it can be
+// used as a named entry in the "aggs" of a search request body.
+package aggs
+
+import "encoding/json"
+
+// Aggregation is an aggregation definition
+type Aggregation interface {
+	json.Marshaler
+
+	// Map returns the aggregation as it will be marshaled, eg {"terms": {"field": "category"}}
+	Map() map[string]interface{}
+}
+
+// TermsAggregation buckets documents by the unique values of a field
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-aggregations-bucket-terms-aggregation.html
+type TermsAggregation struct {
+	field       string
+	size        int
+	minDocCount *int
+	order       []map[string]string
+	subs        map[string]Aggregation
+}
+
+// Terms returns a terms aggregation on field. Without options it returns the 10 terms with the most documents.
+func Terms(field string) *TermsAggregation {
+	return &TermsAggregation{field: field}
+}
+
+// Size sets how many buckets are returned
+func (a *TermsAggregation) Size(n int) *TermsAggregation {
+	a.size = n
+	return a
+}
+
+// MinDocCount sets the number of documents a term needs to be returned as a bucket, 1 by default
+func (a *TermsAggregation) MinDocCount(n int) *TermsAggregation {
+	a.minDocCount = &n
+	return a
+}
+
+// OrderByCount orders the buckets by document count, which is the default, descending unless asc is true. Ascending
+// count order gives unbounded error on the counts and is best avoided.
+func (a *TermsAggregation) OrderByCount(asc bool) *TermsAggregation {
+	a.order = append(a.order, map[string]string{"_count": direction(asc)})
+	return a
+}
+
+// OrderByKey orders the buckets alphabetically by term, ascending unless asc is false. Calling more than one order
+// method sorts by each in turn.
+func (a *TermsAggregation) OrderByKey(asc bool) *TermsAggregation {
+	a.order = append(a.order, map[string]string{"_key": direction(asc)})
+	return a
+}
+
+// Map returns the aggregation as a map
+func (a *TermsAggregation) Map() map[string]interface{} {
+	p := map[string]interface{}{"field": a.field}
+	if a.size > 0 {
+		p["size"] = a.size
+	}
+	if a.minDocCount != nil {
+		p["min_doc_count"] = *a.minDocCount
+	}
+	if len(a.order) > 0 {
+		p["order"] = a.order
+	}
+	return withSubs(map[string]interface{}{"terms": p}, a.subs)
+}
+
+// MarshalJSON marshals the aggregation
+func (a *TermsAggregation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Map())
+}
+
+// direction returns the sort direction name
+func direction(asc bool) string {
+	if asc {
+		return "asc"
+	}
+	return "desc"
+}
+
+// SubAgg adds a sub-aggregation that is run within each bucket
+func (a *TermsAggregation) SubAgg(name string, sub Aggregation) *TermsAggregation {
+	a.subs = addSub(a.subs, name, sub)
+	return a
+}
+
+// DateHistogramAggregation buckets documents by date into intervals
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-aggregations-bucket-datehistogram-aggregation.html
+type DateHistogramAggregation struct {
+	field            string
+	calendarInterval string
+	fixedInterval    string
+	format           string
+	timeZone         string
+	minDocCount      *int
+	subs             map[string]Aggregation
+}
+
+// DateHistogram returns a date histogram aggregation on field. Set the interval with CalendarInterval or
+// FixedInterval.
+func DateHistogram(field string) *DateHistogramAggregation {
+	return &DateHistogramAggregation{field: field}
+}
+
+// CalendarInterval sets a calendar-aware interval, eg "day", "month" or "1y", that allows for varying month lengths,
+// leap years and daylight saving
+func (a *DateHistogramAggregation) CalendarInterval(interval string) *DateHistogramAggregation {
+	a.calendarInterval = interval
+	return a
+}
+
+// FixedInterval sets a fixed length interval, eg "30s", "12h" or "10d"
+func (a *DateHistogramAggregation) FixedInterval(interval string) *DateHistogramAggregation {
+	a.fixedInterval = interval
+	return a
+}
+
+// Format sets the date format of the bucket key_as_string, eg "yyyy-MM-dd"
+func (a *DateHistogramAggregation) Format(format string) *DateHistogramAggregation {
+	a.format = format
+	return a
+}
+
+// TimeZone sets the time zone the buckets are rounded in, eg "+10:00" or "Australia/Sydney", UTC by default
+func (a *DateHistogramAggregation) TimeZone(tz string) *DateHistogramAggregation {
+	a.timeZone = tz
+	return a
+}
+
+// MinDocCount sets the number of documents an interval needs to be returned as a bucket. It is 0 by default, so
+// empty intervals between the first and last bucket are included.
+func (a *DateHistogramAggregation) MinDocCount(n int) *DateHistogramAggregation {
+	a.minDocCount = &n
+	return a
+}
+
+// SubAgg adds a sub-aggregation that is run within each bucket
+func (a *DateHistogramAggregation) SubAgg(name string, sub Aggregation) *DateHistogramAggregation {
+	a.subs = addSub(a.subs, name, sub)
+	return a
+}
+
+// Map returns the aggregation as a map
+func (a *DateHistogramAggregation) Map() map[string]interface{} {
+	p := map[string]interface{}{"field": a.field}
+	if a.calendarInterval != "" {
+		p["calendar_interval"] = a.calendarInterval
+	}
+	if a.fixedInterval != "" {
+		p["fixed_interval"] = a.fixedInterval
+	}
+	if a.format != "" {
+		p["format"] = a.format
+	}
+	if a.timeZone != "" {
+		p["time_zone"] = a.timeZone
+	}
+	if a.minDocCount != nil {
+		p["min_doc_count"] = *a.minDocCount
+	}
+	return withSubs(map[string]interface{}{"date_histogram": p}, a.subs)
+}
+
+// MarshalJSON marshals the aggregation
+func (a *DateHistogramAggregation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Map())
+}
+
+// RangeAggregation buckets documents by ranges of a numeric field. Each range includes its from value and excludes
+// its to value.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-aggregations-bucket-range-aggregation.html
+type RangeAggregation struct {
+	field  string
+	ranges []map[string]interface{}
+	subs   map[string]Aggregation
+}
+
+// Range returns a range aggregation on field. Add the ranges with AddRange.
+func Range(field string) *RangeAggregation {
+	return &RangeAggregation{field: field}
+}
+
+// AddRange adds a bucket for values from from up to to. Either bound may be nil for an open-ended range, and key
+// may be empty to have Elasticsearch generate one, eg "10.0-20.0".
+func (a *RangeAggregation) AddRange(key string, from, to interface{}) *RangeAggregation {
+	r := map[string]interface{}{}
+	if key != "" {
+		r["key"] = key
+	}
+	if from != nil {
+		r["from"] = from
+	}
+	if to != nil {
+		r["to"] = to
+	}
+	a.ranges = append(a.ranges, r)
+	return a
+}
+
+// SubAgg adds a sub-aggregation that is run within each bucket
+func (a *RangeAggregation) SubAgg(name string, sub Aggregation) *RangeAggregation {
+	a.subs = addSub(a.subs, name, sub)
+	return a
+}
+
+// Map returns the aggregation as a map
+func (a *RangeAggregation) Map() map[string]interface{} {
+	p := map[string]interface{}{"field": a.field, "ranges": a.ranges}
+	return withSubs(map[string]interface{}{"range": p}, a.subs)
+}
+
+// MarshalJSON marshals the aggregation
+func (a *RangeAggregation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Map())
+}
+
+// MetricAggregation computes a single value from a field of the documents in scope, eg the documents in a bucket
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-aggregations-metrics.html
+type MetricAggregation struct {
+	kind    string
+	field   string
+	missing interface{}
+}
+
+// Avg returns an aggregation of the average of field
+func Avg(field string) *MetricAggregation {
+	return &MetricAggregation{kind: "avg", field: field}
+}
+
+// Sum returns an aggregation of the sum of field
+func Sum(field string) *MetricAggregation {
+	return &MetricAggregation{kind: "sum", field: field}
+}
+
+// Min returns an aggregation of the smallest value of field
+func Min(field string) *MetricAggregation {
+	return &MetricAggregation{kind: "min", field: field}
+}
+
+// Max returns an aggregation of the largest value of field
+func Max(field string) *MetricAggregation {
+	return &MetricAggregation{kind: "max", field: field}
+}
+
+// Cardinality returns an aggregation of the approximate number of distinct values of field
+func Cardinality(field string) *MetricAggregation {
+	return &MetricAggregation{kind: "cardinality", field: field}
+}
+
+// Missing sets the value used for documents that have no value for the field, which are otherwise ignored
+func (a *MetricAggregation) Missing(v interface{}) *MetricAggregation {
+	a.missing = v
+	return a
+}
+
+// Map returns the aggregation as a map
+func (a *MetricAggregation) Map() map[string]interface{} {
+	p := map[string]interface{}{"field": a.field}
+	if a.missing != nil {
+		p["missing"] = a.missing
+	}
+	return map[string]interface{}{a.kind: p}
+}
+
+// MarshalJSON marshals the aggregation
+func (a *MetricAggregation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Map())
+}
+
+// addSub adds a named sub-aggregation to subs, creating it if need be
+func addSub(subs map[string]Aggregation, name string, sub Aggregation) map[string]Aggregation {
+	if subs == nil {
+		subs = map[string]Aggregation{}
+	}
+	subs[name] = sub
+	return subs
+}
+
+// withSubs adds the sub-aggregations, if any, to the aggregation map m
+func withSubs(m map[string]interface{}, subs map[string]Aggregation) map[string]interface{} {
+	if len(subs) == 0 {
+		return m
+	}
+	xm := make(map[string]interface{}, len(subs))
+	for name, a := range subs {
+		xm[name] = a.Map()
+	}
+	m["aggs"] = xm
+	return m
+}
@@ -0,0 +1,55 @@
+package aggs_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic/aggs"
+)
+
+func TestTerms(t *testing.T) {
+	is := is.New(t)
+
+	xb, err := json.Marshal(aggs.Terms("category"))
+	is.NoErr(err)
+	is.Equal(string(xb), `{"terms":{"field":"category"}}`)
+
+	// Top 50 terms, alphabetically, appearing in at least 5 documents
+	xb, err = json.Marshal(aggs.Terms("category").Size(50).MinDocCount(5).OrderByKey(true))
+	is.NoErr(err)
+	is.Equal(string(xb), `{"terms":{"field":"category","min_doc_count":5,"order":[{"_key":"asc"}],"size":50}}`)
+}
+
+func TestSubAggs(t *testing.T) {
+	is := is.New(t)
+
+	// Monthly sales per category, with the average price and number of customers each month
+	a := aggs.Terms("category").SubAgg("per_month", aggs.DateHistogram("sold_at").
+		CalendarInterval("month").
+		Format("yyyy-MM").
+		SubAgg("avg_price", aggs.Avg("price")).
+		SubAgg("customers", aggs.Cardinality("customer_id")))
+
+	xb, err := json.Marshal(a)
+	is.NoErr(err)
+	is.Equal(string(xb), `{"aggs":{"per_month":{"aggs":{"avg_price":{"avg":{"field":"price"}},`+
+		`"customers":{"cardinality":{"field":"customer_id"}}},`+
+		`"date_histogram":{"calendar_interval":"month","field":"sold_at","format":"yyyy-MM"}}},`+
+		`"terms":{"field":"category"}}`)
+}
+
+func TestRange(t *testing.T) {
+	is := is.New(t)
+
+	a := aggs.Range("price").
+		AddRange("cheap", nil, 10).
+		AddRange("", 10, 100).
+		AddRange("dear", 100, nil).
+		SubAgg("total", aggs.Sum("price").Missing(0))
+
+	xb, err := json.Marshal(a)
+	is.NoErr(err)
+	is.Equal(string(xb), `{"aggs":{"total":{"sum":{"field":"price","missing":0}}},`+
+		`"range":{"field":"price","ranges":[{"key":"cheap","to":10},{"from":10,"to":100},{"from":100,"key":"dear"}]}}`)
+}
@@ -0,0 +1,136 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AliasInfo describes how an alias applies to one index
+type AliasInfo struct {
+	Filter        json.RawMessage `json:"filter"`
+	IndexRouting  string          `json:"index_routing"`
+	SearchRouting string          `json:"search_routing"`
+	IsWriteIndex  bool            `json:"is_write_index"`
+}
+
+// GetAlias returns the indices that an alias points to, keyed by index name, along with any filter and routing the
+// alias applies to each of them. The alias must be a single name; use ListAliases to match several.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-get-alias.html
+func (c *Client) GetAlias(ctx context.Context, alias string) (map[string]AliasInfo, error) {
+
+	if alias == "" {
+		return nil, errors.New("GetAlias - alias must be specified")
+	}
+	if strings.ContainsAny(alias, "*,") {
+		return nil, errors.Errorf("GetAlias - alias %q must not be a pattern or list", alias)
+	}
+
+	xb, err := c.request(ctx, "GET", "/_alias/"+url.PathEscape(alias), nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetAlias")
+	}
+
+	var m map[string]struct {
+		Aliases map[string]AliasInfo `json:"aliases"`
+	}
+	err = json.Unmarshal(xb, &m)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	ai := make(map[string]AliasInfo, len(m))
+	for index, v := range m {
+		ai[index] = v.Aliases[alias]
+	}
+
+	return ai, nil
+}
+
+// ListAliases returns every alias in the cluster, keyed by alias name and then by the name of each index it points to
+func (c *Client) ListAliases(ctx context.Context) (map[string]map[string]AliasInfo, error) {
+
+	xb, err := c.request(ctx, "GET", "/_alias", nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "ListAliases")
+	}
+
+	var m map[string]struct {
+		Aliases map[string]AliasInfo `json:"aliases"`
+	}
+	err = json.Unmarshal(xb, &m)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	aliases := map[string]map[string]AliasInfo{}
+	for index, v := range m {
+		for alias, ai := range v.Aliases {
+			if aliases[alias] == nil {
+				aliases[alias] = map[string]AliasInfo{}
+			}
+			aliases[alias][index] = ai
+		}
+	}
+
+	return aliases, nil
+}
+
+// AddAlias points alias at index, as well as any indices it already points to
+func (c *Client) AddAlias(ctx context.Context, index, alias string) error {
+	err := c.updateAliases(ctx, aliasAction{"add", index, alias})
+	if err != nil {
+		return errors.Wrap(err, "AddAlias")
+	}
+	return nil
+}
+
+// RemoveAlias stops alias pointing at index
+func (c *Client) RemoveAlias(ctx context.Context, index, alias string) error {
+	err := c.updateAliases(ctx, aliasAction{"remove", index, alias})
+	if err != nil {
+		return errors.Wrap(err, "RemoveAlias")
+	}
+	return nil
+}
+
+// SwapAlias moves alias from one index to another in a single atomic update, so there is no moment when the alias
+// points at neither or both, eg to switch searches to a reindexed copy with no downtime
+func (c *Client) SwapAlias(ctx context.Context, alias, from, to string) error {
+	err := c.updateAliases(ctx, aliasAction{"remove", from, alias}, aliasAction{"add", to, alias})
+	if err != nil {
+		return errors.Wrap(err, "SwapAlias")
+	}
+	return nil
+}
+
+// aliasAction is an add or remove action for the _aliases endpoint
+type aliasAction struct {
+	action string
+	index  string
+	alias  string
+}
+
+// updateAliases applies actions atomically
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-aliases.html
+func (c *Client) updateAliases(ctx context.Context, actions ...aliasAction) error {
+
+	xa := make([]map[string]interface{}, len(actions))
+	for i, a := range actions {
+		if a.index == "" || a.alias == "" {
+			return errors.New("index and alias must be specified")
+		}
+		xa[i] = map[string]interface{}{a.action: map[string]string{"index": strings.ToLower(a.index), "alias": a.alias}}
+	}
+
+	xb, err := json.Marshal(map[string]interface{}{"actions": xa})
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+	_, err = c.request(ctx, "POST", "/_aliases", bytes.NewReader(xb), standardHeaders)
+	return err
+}
@@ -0,0 +1,92 @@
+package elastic_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestGetAlias(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /_alias/live": []byte(`{
+			"articles_v1": {"aliases": {"live": {}}},
+			"articles_v2": {"aliases": {"live": {"filter": {"term": {"published": true}}, "index_routing": "1", "is_write_index": true}}}
+		}`),
+	})
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	ai, err := e.GetAlias(ctx, "live")
+	is.NoErr(err)
+	is.Equal(len(ai), 2)
+	is.True(ai["articles_v2"].IsWriteIndex)
+	is.Equal(ai["articles_v2"].IndexRouting, "1")
+	is.Equal(string(ai["articles_v2"].Filter), `{"term": {"published": true}}`)
+
+	_, err = e.GetAlias(ctx, "li*")
+	is.Equal(err.Error(), `GetAlias - alias "li*" must not be a pattern or list`)
+	_, err = e.GetAlias(ctx, "live,old")
+	is.True(err != nil)
+}
+
+func TestGetAliasEscaped(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var uri string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri = r.RequestURI
+		w.Write([]byte(`{"articles_v1":{"aliases":{"live?x":{"index_routing":"2"}}}}`))
+	}))
+	defer s.Close()
+
+	ai, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).GetAlias(ctx, "live?x")
+	is.NoErr(err)
+	is.Equal(uri, "/_alias/live%3Fx")
+	is.Equal(ai["articles_v1"].IndexRouting, "2")
+}
+
+func TestAliases(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /_alias":
+			w.Write([]byte(`{"articles_v1":{"aliases":{"live":{},"old":{}}},"articles_v2":{"aliases":{}}}`))
+		case "POST /_aliases":
+			xb, _ := ioutil.ReadAll(r.Body)
+			body = string(xb)
+			w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	aliases, err := e.ListAliases(ctx)
+	is.NoErr(err)
+	is.Equal(len(aliases), 2)
+	_, ok := aliases["live"]["articles_v1"]
+	is.True(ok)
+
+	is.NoErr(e.SwapAlias(ctx, "live", "articles_v1", "articles_v2"))
+	is.Equal(body, `{"actions":[{"remove":{"alias":"live","index":"articles_v1"}},{"add":{"alias":"live","index":"articles_v2"}}]}`)
+
+	is.NoErr(e.AddAlias(ctx, "articles_v2", "all"))
+	is.Equal(body, `{"actions":[{"add":{"alias":"all","index":"articles_v2"}}]}`)
+	is.NoErr(e.RemoveAlias(ctx, "articles_v1", "old"))
+	is.Equal(body, `{"actions":[{"remove":{"alias":"old","index":"articles_v1"}}]}`)
+
+	is.True(e.AddAlias(ctx, "", "all") != nil)
+}
@@ -0,0 +1,48 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Token is a term produced by an analyzer, with the offsets of the characters it came from in the original text
+type Token struct {
+	Token       string `json:"token"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+	Type        string `json:"type"` // eg "<ALPHANUM>" or "word"
+	Position    int    `json:"position"`
+}
+
+// Analyze runs text through an analyzer and returns the tokens it produces, to check how a custom analyzer
+// tokenizes text. The analyzer may be a built-in one, eg "standard", or a custom one defined in the settings of
+// index. Index may be empty for a built-in analyzer.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-analyze.html
+func (c *Client) Analyze(ctx context.Context, index, analyzer, text string) ([]Token, error) {
+
+	xb, err := json.Marshal(map[string]string{"analyzer": analyzer, "text": text})
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	u := "/_analyze"
+	if index != "" {
+		u = "/" + strings.ToLower(index) + u
+	}
+	xb, err = c.request(ctx, "POST", u, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "Analyze")
+	}
+
+	var r struct {
+		Tokens []Token `json:"tokens"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return r.Tokens, nil
+}
@@ -0,0 +1,41 @@
+package elastic_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestAnalyze(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var path, body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		path, body = r.URL.Path, string(xb)
+		w.Write([]byte(`{"tokens":[` +
+			`{"token":"quick","start_offset":4,"end_offset":9,"type":"<ALPHANUM>","position":1},` +
+			`{"token":"fox","start_offset":16,"end_offset":19,"type":"<ALPHANUM>","position":3}]}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	tokens, err := e.Analyze(ctx, "Articles", "title_analyzer", "The quick brown fox")
+	is.NoErr(err)
+	is.Equal(path, "/articles/_analyze")
+	is.Equal(body, `{"analyzer":"title_analyzer","text":"The quick brown fox"}`)
+	is.Equal(tokens, []elastic.Token{
+		{Token: "quick", StartOffset: 4, EndOffset: 9, Type: "<ALPHANUM>", Position: 1},
+		{Token: "fox", StartOffset: 16, EndOffset: 19, Type: "<ALPHANUM>", Position: 3},
+	})
+
+	_, err = e.Analyze(ctx, "", "standard", "text")
+	is.NoErr(err)
+	is.Equal(path, "/_analyze")
+}
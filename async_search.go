@@ -0,0 +1,125 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AsyncSearch is the state of an async search. Response holds the results so far while IsRunning, which are
+// partial if IsPartial, and the final results once it is not.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/async-search.html
+type AsyncSearch struct {
+	ID                     string       `json:"id"` // empty if the search completed before SubmitAsyncSearch returned
+	IsRunning              bool         `json:"is_running"`
+	IsPartial              bool         `json:"is_partial"`
+	StartTimeInMillis      int64        `json:"start_time_in_millis"`
+	ExpirationTimeInMillis int64        `json:"expiration_time_in_millis"`
+	Response               SearchResult `json:"response"`
+}
+
+// SubmitAsyncSearch starts the search described by r in the background, for searches, such as heavy aggregations
+// over cold data, that take too long to wait for. It waits up to waitFor, 1s if zero, and returns the results if
+// the search is done by then, otherwise the id to fetch them with GetAsyncSearch or WaitForAsyncSearch. The results
+// are kept for keepAlive, 5 days if zero, after which they are deleted. Available from version 7.7, and on
+// OpenSearch with the asynchronous search plugin.
+func (c *Client) SubmitAsyncSearch(ctx context.Context, r SearchRequest, waitFor, keepAlive time.Duration) (*AsyncSearch, error) {
+
+	xb, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	u := c.asyncSearchPath(ctx)
+	q := url.Values{}
+	if r.Index != "" && r.PIT == nil {
+		if c.isOpenSearch(ctx) {
+			q.Set("index", strings.ToLower(r.Index))
+		} else {
+			u = "/" + strings.ToLower(r.Index) + u
+		}
+	}
+	if waitFor > 0 {
+		q.Set("wait_for_completion_timeout", timeValue(waitFor))
+	}
+	if keepAlive > 0 {
+		q.Set("keep_alive", timeValue(keepAlive))
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+
+	xb, err = c.request(ctx, "POST", u, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(searchError(err), "SubmitAsyncSearch")
+	}
+
+	return asyncSearch(xb)
+}
+
+// GetAsyncSearch returns the state of an async search and its results so far
+func (c *Client) GetAsyncSearch(ctx context.Context, id string) (*AsyncSearch, error) {
+
+	xb, err := c.request(ctx, "GET", c.asyncSearchPath(ctx)+"/"+id, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(searchError(err), "GetAsyncSearch")
+	}
+
+	return asyncSearch(xb)
+}
+
+// WaitForAsyncSearch polls an async search every interval until it is no longer running, and returns the final
+// results. Use a context with a deadline to give up waiting; the search keeps running.
+func (c *Client) WaitForAsyncSearch(ctx context.Context, id string, interval time.Duration) (*AsyncSearch, error) {
+	for {
+		as, err := c.GetAsyncSearch(ctx, id)
+		if err != nil {
+			return nil, errors.Wrap(err, "WaitForAsyncSearch")
+		}
+		if !as.IsRunning {
+			return as, nil
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, errors.Wrap(err, "WaitForAsyncSearch")
+		}
+	}
+}
+
+// DeleteAsyncSearch cancels an async search if it is still running, and deletes its results
+func (c *Client) DeleteAsyncSearch(ctx context.Context, id string) error {
+	_, err := c.request(ctx, "DELETE", c.asyncSearchPath(ctx)+"/"+id, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "DeleteAsyncSearch")
+	}
+	return nil
+}
+
+// asyncSearchPath returns the path of the async search API for the cluster
+func (c *Client) asyncSearchPath(ctx context.Context) string {
+	if c.isOpenSearch(ctx) {
+		return "/_plugins/_asynchronous_search"
+	}
+	return "/_async_search"
+}
+
+// asyncSearch parses an async search response. OpenSearch reports a state, eg "RUNNING" or "SUCCEEDED", rather than
+// is_running and is_partial.
+func asyncSearch(xb []byte) (*AsyncSearch, error) {
+	var r struct {
+		AsyncSearch
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	if r.State != "" {
+		r.IsRunning = r.State == "INIT" || r.State == "RUNNING"
+		r.IsPartial = r.IsRunning
+	}
+	return &r.AsyncSearch, nil
+}
@@ -0,0 +1,77 @@
+package elastic
+
+import "time"
+
+// The circuit breaker, when turned on by WithCircuitBreaker, stops requests being sent to a node that keeps failing.
+// After threshold consecutive failures, a connection error or 5xx response, the node's circuit opens and it is
+// skipped until the cooldown has passed. The circuit is then half-open: one request is let through as a probe, and
+// closes the circuit if it succeeds or opens it for another cooldown if it fails.
+
+// pick returns the first node in nodes, from index *next and wrapping around, that a request may be sent to, and
+// advances *next past it. It returns nil if the circuit of every node is open.
+func (p *nodePool) pick(nodes []*node, next *int) *node {
+	for i := 0; i < len(nodes); i++ {
+		n := nodes[(*next+i)%len(nodes)]
+		if p.allow(n) {
+			*next = (*next + i + 1) % len(nodes)
+			return n
+		}
+	}
+	return nil
+}
+
+// allow reports whether a request may be sent to n, and claims the probe if n is half-open
+func (p *nodePool) allow(n *node) bool {
+
+	if p.breakerThreshold == 0 {
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if n.failures < p.breakerThreshold {
+		return true
+	}
+	if time.Now().Before(n.openUntil) || n.probing {
+		return false
+	}
+	n.probing = true
+	return true
+}
+
+// succeeded closes the circuit of n
+func (p *nodePool) succeeded(n *node) {
+	if p.breakerThreshold == 0 {
+		return
+	}
+	p.mu.Lock()
+	n.failures = 0
+	n.probing = false
+	p.mu.Unlock()
+}
+
+// failed counts a failure of n, opening its circuit at the threshold
+func (p *nodePool) failed(n *node) {
+	if p.breakerThreshold == 0 {
+		return
+	}
+	p.mu.Lock()
+	n.failures++
+	n.probing = false
+	if n.failures >= p.breakerThreshold {
+		n.openUntil = time.Now().Add(p.breakerCooldown)
+	}
+	p.mu.Unlock()
+}
+
+// abandoned gives up the probe of n, if it was claimed, for a request that ended without a result, eg because its
+// context was done
+func (p *nodePool) abandoned(n *node) {
+	if p.breakerThreshold == 0 {
+		return
+	}
+	p.mu.Lock()
+	n.probing = false
+	p.mu.Unlock()
+}
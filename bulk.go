@@ -0,0 +1,186 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BulkResponse is the parsed response from the bulk API
+type BulkResponse struct {
+	Took   int64              `json:"took"`   // milliseconds Elasticsearch spent processing the request, excluding network time
+	Errors bool               `json:"errors"` // true if any item failed
+	Items  []BulkResponseItem `json:"items"`  // the result of each action, in request order
+}
+
+// BulkResponseItem is the result of one action in a bulk request. Error is nil if the action succeeded. SeqNo and
+// PrimaryTerm identify the write for optimistic concurrency control of later writes to the document.
+type BulkResponseItem struct {
+	Action      string     `json:"-"` // index, create, update or delete
+	Index       string     `json:"_index"`
+	ID          string     `json:"_id"`
+	Version     int64      `json:"_version"`
+	Result      string     `json:"result"`
+	Status      int        `json:"status"`
+	SeqNo       int64      `json:"_seq_no"`
+	PrimaryTerm int64      `json:"_primary_term"`
+	Error       *BulkError `json:"error"`
+}
+
+// BulkError is the reason a bulk action failed
+type BulkError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// ErrBulkItems is the cause of the error returned by BulkResponse.Err. The error is a *BulkItemsError holding the
+// failed items.
+var ErrBulkItems = errors.New("bulk actions failed")
+
+// BulkItemsError reports the items that failed in a bulk request. Check for it with errors.Is(err, ErrBulkItems),
+// and use errors.As to get the items.
+type BulkItemsError struct {
+	Items []BulkResponseItem
+}
+
+func (e *BulkItemsError) Error() string {
+	s := strconv.Itoa(len(e.Items)) + " " + ErrBulkItems.Error()
+	if len(e.Items) > 0 {
+		it := e.Items[0]
+		s += " - first: " + it.Action + " [" + it.Index + "][" + it.ID + "] " + it.Error.Type + ": " + it.Error.Reason
+	}
+	return s
+}
+
+// Is makes errors.Is(err, ErrBulkItems) true for a *BulkItemsError
+func (e *BulkItemsError) Is(target error) bool {
+	return target == ErrBulkItems
+}
+
+// Failed returns the items that failed, in request order
+func (r *BulkResponse) Failed() []BulkResponseItem {
+	var xi []BulkResponseItem
+	for _, it := range r.Items {
+		if it.Error != nil {
+			xi = append(xi, it)
+		}
+	}
+	return xi
+}
+
+// Err returns a *BulkItemsError if any item failed, otherwise nil. The bulk API responds with 200 OK even when
+// actions fail, so Batch and friends don't return an error in that case; use Err to treat any failure as one.
+func (r *BulkResponse) Err() error {
+	xi := r.Failed()
+	if len(xi) == 0 {
+		return nil
+	}
+	return &BulkItemsError{Items: xi}
+}
+
+// UnmarshalJSON unwraps an item from the object keyed by its action type, eg {"update": {...}}
+func (it *BulkResponseItem) UnmarshalJSON(xb []byte) error {
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(xb, &m); err != nil {
+		return err
+	}
+
+	type item BulkResponseItem // without the UnmarshalJSON method
+	for action, v := range m {
+		var i item
+		if err := json.Unmarshal(v, &i); err != nil {
+			return err
+		}
+		*it = BulkResponseItem(i)
+		it.Action = action
+	}
+
+	return nil
+}
+
+// Batch performs a set of actions specified in the document
+// The REST API endpoint /_bulk expects the body to be newline-delimited JSON (NDJSON) and
+// hence the Content-Type header to be application/x-ndjson
+// https://www.elastic.co/guide/en/elasticsearch/reference/6.2/docs-bulk.html
+func (c *Client) Batch(ctx context.Context, index, doc string, opts ...RequestOption) (*BulkResponse, error) {
+	r, err := c.batch(ctx, index, strings.NewReader(doc), opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Batch")
+	}
+	return r, nil
+}
+
+// BatchReader is like Batch but streams the NDJSON actions from r straight into the request body, so a large bulk
+// payload, such as a file, never has to be held in memory. As r can only be read once the request is not failed
+// over to another host.
+func (c *Client) BatchReader(ctx context.Context, index string, r io.Reader, opts ...RequestOption) (*BulkResponse, error) {
+	br, err := c.batch(ctx, index, r, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "BatchReader")
+	}
+	return br, nil
+}
+
+// batch posts the NDJSON body to the bulk endpoint and parses the response
+func (c *Client) batch(ctx context.Context, index string, body io.Reader, opts []RequestOption) (*BulkResponse, error) {
+
+	u := withOptions(c.bulkPath(ctx, index), opts)
+
+	headers := []header{
+		{Key: "Content-Type", Value: "application/x-ndjson"},
+	}
+
+	xb, err := c.request(ctx, "POST", u, body, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var r BulkResponse
+	err = json.Unmarshal(xb, &r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	return &r, nil
+}
+
+// UpdateDocs applies partial updates to many documents in one bulk request. The updates map document ids to partial
+// documents, each of which is marshaled and sent as {"doc": ...}, as with UpdateDoc. Use a json.RawMessage for a
+// partial document that is already JSON. Individual updates can fail, eg if the document does not exist, so check
+// the Error of each item in the response.
+func (c *Client) UpdateDocs(ctx context.Context, index string, updates map[string]interface{}) (*BulkResponse, error) {
+
+	ids := make([]string, 0, len(updates))
+	for id := range updates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, id := range ids {
+		if id == "" {
+			return nil, errors.New("UpdateDocs - id must be specified")
+		}
+		action := map[string]interface{}{"update": map[string]string{"_id": id}}
+		if err := enc.Encode(action); err != nil {
+			return nil, errors.Wrap(err, "Encode")
+		}
+		if err := enc.Encode(map[string]interface{}{"doc": updates[id]}); err != nil {
+			return nil, errors.Wrap(err, "Encode")
+		}
+	}
+
+	r, err := c.batch(ctx, index, bytes.NewReader(buf.Bytes()), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "UpdateDocs")
+	}
+	return r, nil
+}
@@ -0,0 +1,377 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultBulkFlushCount is the number of actions a BulkIndexer buffers before sending them
+const defaultBulkFlushCount = 500
+
+// BulkIndexer buffers bulk actions and sends them to the bulk API in batches, from one or more workers. It is safe
+// for concurrent use. Close must be called to send any actions still buffered and wait for the workers to finish.
+type BulkIndexer struct {
+	c             *Client
+	index         string
+	action        string
+	flushCount    int
+	flushBytes    int
+	flushInterval time.Duration
+	workers       int
+	limiter       *tokenBucket
+	onSuccess     func(BulkIndexerItem, BulkResponseItem)
+	onFailure     func(BulkIndexerItem, BulkResponseItem, error)
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	items  []BulkIndexerItem
+	closed bool
+
+	batches chan bulkBatch
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	ctx     context.Context // for sending batches, which hold the actions of every caller of Add
+	cancel  context.CancelFunc
+
+	errMu  sync.Mutex
+	err    error
+	failed int
+}
+
+// BulkIndexerItem is an action added to a BulkIndexer, as passed to Add
+type BulkIndexerItem struct {
+	Action string
+	Index  string
+	ID     string
+	Doc    string
+}
+
+// bulkBatch is a bulk request body and the items it holds, in order
+type bulkBatch struct {
+	body  []byte
+	items []BulkIndexerItem
+}
+
+// BulkIndexerOption configures a BulkIndexer
+type BulkIndexerOption func(*BulkIndexer)
+
+// BulkDefaultAction sets the action for items added without one, "index" by default. Use "create" to write to a
+// data stream, which accepts only create actions.
+func BulkDefaultAction(action string) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		if action != "" {
+			b.action = action
+		}
+	}
+}
+
+// BulkFlushCount sets how many actions are buffered before they are sent, 500 by default
+func BulkFlushCount(n int) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		if n > 0 {
+			b.flushCount = n
+		}
+	}
+}
+
+// BulkFlushBytes sends the buffered actions once they reach n bytes of NDJSON, whatever their count. There is no
+// size threshold by default.
+func BulkFlushBytes(n int) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		if n > 0 {
+			b.flushBytes = n
+		}
+	}
+}
+
+// BulkFlushInterval sends any buffered actions every d, so that a trickle of actions is not held back waiting for
+// a threshold. Timed flushes are not bound to the context of any Add call.
+func BulkFlushInterval(d time.Duration) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		if d > 0 {
+			b.flushInterval = d
+		}
+	}
+}
+
+// BulkWorkers sets how many batches can be in flight at once, 1 by default
+func BulkWorkers(n int) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		if n > 0 {
+			b.workers = n
+		}
+	}
+}
+
+// BulkRateLimit caps the indexer at docsPerSec actions per second, averaged over time, to limit ingest pressure on
+// a shared cluster. Batches are delayed, so Add blocks, until sending them would not exceed the rate.
+func BulkRateLimit(docsPerSec float64) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		if docsPerSec > 0 {
+			b.limiter = newTokenBucket(docsPerSec)
+		}
+	}
+}
+
+// BulkOnSuccess sets a function called with each action that succeeds and its result. It is called from the
+// workers, so must be safe for concurrent use if there is more than one.
+func BulkOnSuccess(fn func(item BulkIndexerItem, res BulkResponseItem)) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.onSuccess = fn
+	}
+}
+
+// BulkOnFailure sets a function called with each action that fails. Either res holds the error for the action, or
+// err is the reason the whole batch failed, in which case res is empty. It is called from the workers, so must be
+// safe for concurrent use if there is more than one.
+func BulkOnFailure(fn func(item BulkIndexerItem, res BulkResponseItem, err error)) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.onFailure = fn
+	}
+}
+
+// NewBulkIndexer returns a BulkIndexer that sends actions to the bulk endpoint of index, which is the default index
+// for actions that don't specify their own
+func (c *Client) NewBulkIndexer(index string, opts ...BulkIndexerOption) *BulkIndexer {
+
+	b := &BulkIndexer{
+		c:          c,
+		index:      index,
+		action:     "index",
+		flushCount: defaultBulkFlushCount,
+		workers:    1,
+		batches:    make(chan bulkBatch),
+		stop:       make(chan struct{}),
+	}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	for _, o := range opts {
+		o(b)
+	}
+
+	b.wg.Add(b.workers)
+	for i := 0; i < b.workers; i++ {
+		go b.work()
+	}
+	if b.flushInterval > 0 {
+		go b.tick()
+	}
+
+	return b
+}
+
+// Add buffers an action, sending the buffer when it is full. The action is "index", "create", "update" or "delete",
+// or empty for the indexer's default action, see BulkDefaultAction. Index may be empty to use the indexer's index,
+// and id may be empty for index and create to generate an id. The doc is the source for index and create, the update
+// body, eg {"doc": {...}}, for update, and is ignored for delete.
+// Add blocks while all the workers are busy, and returns an error only if the action could not be queued, in which
+// case it is not sent. The results of the actions are reported to the BulkOnSuccess and BulkOnFailure functions, and
+// by Close.
+func (b *BulkIndexer) Add(ctx context.Context, action, index, id, doc string) error {
+
+	if action == "" {
+		action = b.action
+	}
+	meta := map[string]string{}
+	if index != "" {
+		meta["_index"] = index
+	}
+	if id != "" {
+		meta["_id"] = id
+	}
+	line, err := json.Marshal(map[string]interface{}{action: meta})
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return errors.New("BulkIndexer - indexer is closed")
+	}
+
+	n := b.buf.Len()
+	b.buf.Write(line)
+	b.buf.WriteByte('\n')
+	if action != "delete" {
+		b.buf.WriteString(doc)
+		b.buf.WriteByte('\n')
+	}
+	b.items = append(b.items, BulkIndexerItem{Action: action, Index: index, ID: id, Doc: doc})
+
+	if len(b.items) >= b.flushCount || (b.flushBytes > 0 && b.buf.Len() >= b.flushBytes) {
+		if err := b.flush(ctx); err != nil {
+			// Drop only this action; those of other callers stay buffered for the next flush
+			b.buf.Truncate(n)
+			b.items = b.items[:len(b.items)-1]
+			return err
+		}
+	}
+	return nil
+}
+
+// Close sends any buffered actions and waits for every batch to complete. If ctx is done first, actions still
+// buffered are reported as failed and the batches still being sent are abandoned. It returns an error if a batch
+// could not be sent or any action failed.
+func (b *BulkIndexer) Close(ctx context.Context) error {
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return b.result()
+	}
+	err := b.flush(ctx)
+	if err != nil {
+		b.fail(b.items, err)
+		b.buf.Reset()
+		b.items = nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.stop)
+	close(b.batches)
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		b.cancel()
+		<-done
+	}
+	b.cancel()
+
+	if err != nil {
+		return err
+	}
+	return b.result()
+}
+
+// flush hands the buffered actions to a worker, waiting for one to be free until ctx is done, in which case the
+// actions stay buffered. The batch is sent with the indexer's context, as it may hold actions added by other
+// callers. The caller must hold b.mu.
+func (b *BulkIndexer) flush(ctx context.Context) error {
+
+	if len(b.items) == 0 {
+		return nil
+	}
+
+	select {
+	case b.batches <- b.batch():
+		b.buf.Reset()
+		b.items = nil
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "BulkIndexer")
+	}
+}
+
+// batch returns the buffered actions as a batch. The caller must hold b.mu.
+func (b *BulkIndexer) batch() bulkBatch {
+	return bulkBatch{body: append([]byte(nil), b.buf.Bytes()...), items: b.items}
+}
+
+// tick flushes the buffer every flushInterval until the indexer is closed. If every worker is busy the actions are
+// left for the next flush rather than holding b.mu, and so blocking Add and Close, until one is free.
+func (b *BulkIndexer) tick() {
+
+	t := time.NewTicker(b.flushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			b.mu.Lock()
+			if !b.closed && len(b.items) > 0 {
+				select {
+				case b.batches <- b.batch():
+					b.buf.Reset()
+					b.items = nil
+				default:
+				}
+			}
+			b.mu.Unlock()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// work sends batches until the indexer is closed
+func (b *BulkIndexer) work() {
+	defer b.wg.Done()
+	for bt := range b.batches {
+		b.send(bt)
+	}
+}
+
+// send sends a batch, waiting for the rate limit if there is one, and reports the result of each action
+func (b *BulkIndexer) send(bt bulkBatch) {
+
+	var r *BulkResponse
+	var err error
+	if b.limiter != nil {
+		err = b.limiter.wait(b.ctx, len(bt.items))
+	}
+	if err == nil {
+		r, err = b.c.batch(b.ctx, b.index, bytes.NewReader(bt.body), nil)
+	}
+
+	if err != nil {
+		b.fail(bt.items, errors.Wrap(err, "BulkIndexer"))
+		return
+	}
+
+	for i, res := range r.Items {
+		if i >= len(bt.items) {
+			break
+		}
+		if res.Error != nil {
+			b.errMu.Lock()
+			b.failed++
+			b.errMu.Unlock()
+			if b.onFailure != nil {
+				b.onFailure(bt.items[i], res, nil)
+			}
+			continue
+		}
+		if b.onSuccess != nil {
+			b.onSuccess(bt.items[i], res)
+		}
+	}
+}
+
+// fail records err, if it is the first, and reports each of items as failed with it
+func (b *BulkIndexer) fail(items []BulkIndexerItem, err error) {
+	b.errMu.Lock()
+	if b.err == nil {
+		b.err = err
+	}
+	b.errMu.Unlock()
+	if b.onFailure != nil {
+		for _, it := range items {
+			b.onFailure(it, BulkResponseItem{}, err)
+		}
+	}
+}
+
+// result returns the first batch error, or an error counting the failed actions
+func (b *BulkIndexer) result() error {
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	if b.failed > 0 {
+		return errors.Errorf("BulkIndexer - %d actions failed", b.failed)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultLoadChunkBytes is the size of the bulk requests sent by LoadNDJSON
+const defaultLoadChunkBytes = 5 << 20
+
+// LoadStats are the totals for a LoadNDJSON run, over every chunk sent
+type LoadStats struct {
+	Chunks  int           // bulk requests sent
+	Docs    int64         // actions that succeeded
+	Failed  int64         // actions that failed
+	Bytes   int64         // NDJSON bytes sent
+	Elapsed time.Duration // from start to finish of the run
+}
+
+// DocsPerSecond is the rate at which actions succeeded over the run
+func (s LoadStats) DocsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Docs) / s.Elapsed.Seconds()
+}
+
+// BytesPerSecond is the rate at which NDJSON was sent over the run
+func (s LoadStats) BytesPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+// LoadNDJSON bulk loads the NDJSON actions read from r, eg a file of any size, into index, which is the default for
+// actions that don't name their own. The stream is split into chunks of about 5MB, never between an action and its
+// source, which are sent by workers bulk requests at once. Actions that fail are counted in the stats rather than
+// returned as an error. If a bulk request fails, or r cannot be read, loading stops and the error is returned along
+// with the stats so far.
+func (c *Client) LoadNDJSON(ctx context.Context, index string, r io.Reader, workers int) (*LoadStats, error) {
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	stats := &LoadStats{}
+	var firstErr error
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	start := time.Now()
+	chunks := make(chan []byte)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				br, err := c.batch(ctx, index, bytes.NewReader(chunk), nil)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				failed := int64(len(br.Failed()))
+				mu.Lock()
+				stats.Chunks++
+				stats.Docs += int64(len(br.Items)) - failed
+				stats.Failed += failed
+				stats.Bytes += int64(len(chunk))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	err := splitNDJSON(ctx, r, defaultLoadChunkBytes, chunks)
+	close(chunks)
+	wg.Wait()
+	stats.Elapsed = time.Since(start)
+
+	if firstErr != nil {
+		return stats, errors.Wrap(firstErr, "LoadNDJSON")
+	}
+	if err != nil {
+		return stats, errors.Wrap(err, "LoadNDJSON")
+	}
+	return stats, nil
+}
+
+// splitNDJSON reads bulk actions from r and sends them to chunks in chunks of at least size bytes, the last
+// excepted. An action is kept with its source line, which every action but delete has.
+func splitNDJSON(ctx context.Context, r io.Reader, size int, chunks chan<- []byte) error {
+
+	br := bufio.NewReaderSize(r, 64<<10)
+	var buf []byte
+	source := false // the next line is the source of the last action
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			buf = append(buf, line...)
+			if source {
+				source = false
+			} else {
+				source = bulkAction(line) != "delete"
+			}
+			if !source && len(buf) >= size {
+				select {
+				case chunks <- buf:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				buf = nil
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(buf) > 0 {
+		select {
+		case chunks <- buf:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// bulkAction returns the action of a bulk action line, eg "index" for {"index": {"_id": "1"}}, or "" if the line is
+// not an action
+func bulkAction(line []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return ""
+	}
+	t, err := dec.Token()
+	if err != nil {
+		return ""
+	}
+	s, _ := t.(string)
+	return s
+}
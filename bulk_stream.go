@@ -0,0 +1,81 @@
+package elastic
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// defaultBulkStreamFlushBytes bounds the size of the requests sent by BulkStream
+const defaultBulkStreamFlushBytes = 5 << 20
+
+// BulkFailure is an action that failed, as sent on the channel returned by BulkStream. Either Result holds the error
+// for the action, or Err is the reason the whole batch it was in failed.
+type BulkFailure struct {
+	Item   BulkIndexerItem
+	Result BulkResponseItem
+	Err    error
+}
+
+func (f *BulkFailure) Error() string {
+	s := f.Item.Action + " [" + f.Item.Index + "][" + f.Item.ID + "] failed - "
+	if f.Err != nil {
+		return s + f.Err.Error()
+	}
+	if f.Result.Error == nil {
+		return s + "no reason given"
+	}
+	return s + f.Result.Error.Type + ": " + f.Result.Error.Reason
+}
+
+// Unwrap returns the reason the batch failed, if it did
+func (f *BulkFailure) Unwrap() error {
+	return f.Err
+}
+
+// BulkStream indexes the actions received from items, which may be endless, without holding more than a batch or
+// two in memory. Actions are sent in batches of at most 5MB, which can be changed with BulkFlushBytes and the other
+// BulkIndexer options, and an item with no Action is indexed, unless another action is set with BulkDefaultAction.
+// Each action that fails is reported on the returned channel as a *BulkFailure. The channel is closed once items is
+// closed and every action has been sent, or once ctx is done, in which case the context error is sent last. The
+// caller must keep receiving from the channel until it is closed.
+func (c *Client) BulkStream(ctx context.Context, index string, items <-chan BulkIndexerItem, opts ...BulkIndexerOption) <-chan error {
+
+	errs := make(chan error, 64)
+
+	report := func(b *BulkIndexer) {
+		next := b.onFailure
+		b.onFailure = func(it BulkIndexerItem, res BulkResponseItem, err error) {
+			if next != nil {
+				next(it, res, err)
+			}
+			errs <- &BulkFailure{Item: it, Result: res, Err: err}
+		}
+	}
+	opts = append([]BulkIndexerOption{BulkFlushBytes(defaultBulkStreamFlushBytes)}, opts...)
+	b := c.NewBulkIndexer(index, append(opts, report)...)
+
+	go func() {
+		defer close(errs)
+		for {
+			select {
+			case it, ok := <-items:
+				if !ok {
+					b.Close(ctx) // failures have been reported already
+					return
+				}
+				if err := b.Add(ctx, it.Action, it.Index, it.ID, it.Doc); err != nil {
+					b.Close(ctx)
+					errs <- errors.Wrap(err, "BulkStream")
+					return
+				}
+			case <-ctx.Done():
+				b.Close(ctx)
+				errs <- errors.Wrap(ctx.Err(), "BulkStream")
+				return
+			}
+		}
+	}()
+
+	return errs
+}
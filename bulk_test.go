@@ -0,0 +1,397 @@
+package elastic_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestUpdateDocs(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		is.Equal(string(xb), `{"update":{"_id":"1"}}
+{"doc":{"views":10}}
+{"update":{"_id":"2"}}
+{"doc":{"views":20}}
+`)
+		w.Write(fixture("bulk_update.json"))
+	}))
+	defer s.Close()
+
+	r, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(7)).UpdateDocs(ctx, "articles", map[string]interface{}{
+		"1": map[string]int{"views": 10},
+		"2": json.RawMessage(`{"views":20}`),
+	})
+	is.NoErr(err)
+	is.Equal(r.Took, int64(30))
+	is.True(r.Errors)
+	is.Equal(len(r.Items), 2)
+	is.Equal(r.Items[0].Action, "update")
+	is.Equal(r.Items[0].Result, "updated")
+	is.True(r.Items[0].Error == nil)
+	is.Equal(r.Items[0].SeqNo, int64(3))
+	is.Equal(r.Items[0].PrimaryTerm, int64(1))
+	is.Equal(r.Items[1].Status, 404)
+	is.Equal(r.Items[1].Error.Type, "document_missing_exception")
+
+	is.Equal(len(r.Failed()), 1)
+	is.Equal(r.Failed()[0].ID, r.Items[1].ID)
+	err = r.Err()
+	is.True(errors.Is(err, elastic.ErrBulkItems))
+	var be *elastic.BulkItemsError
+	is.True(errors.As(err, &be))
+	is.Equal(len(be.Items), 1)
+}
+
+func TestBulkIndexerRateLimit(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var docs int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		docs += bytes.Count(xb, []byte("\n")) / 2
+		w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer s.Close()
+
+	bi := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).NewBulkIndexer("articles",
+		elastic.BulkFlushCount(100),
+		elastic.BulkRateLimit(1000),
+	)
+
+	// the pacing itself is tested against a fake clock in TestTokenBucketBatches
+	for i := 0; i < 500; i++ {
+		is.NoErr(bi.Add(ctx, "index", "", strconv.Itoa(i), `{"n":1}`))
+	}
+	is.NoErr(bi.Close(ctx))
+	is.Equal(docs, 500)
+}
+
+func TestBulkIndexerCallbacks(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var requests int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		sc := bufio.NewScanner(r.Body)
+		var items []string
+		for sc.Scan() {
+			var meta map[string]struct {
+				ID string `json:"_id"`
+			}
+			json.Unmarshal(sc.Bytes(), &meta)
+			sc.Scan()
+			id := meta["index"].ID
+			if id == "bad" {
+				items = append(items, `{"index":{"_id":"bad","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`)
+				continue
+			}
+			items = append(items, `{"index":{"_id":"`+id+`","status":201,"result":"created"}}`)
+		}
+		w.Write([]byte(`{"took":1,"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	defer s.Close()
+
+	var ok []string
+	var failed []elastic.BulkResponseItem
+	bi := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).NewBulkIndexer("articles",
+		elastic.BulkFlushBytes(64),
+		elastic.BulkWorkers(3),
+		elastic.BulkOnSuccess(func(it elastic.BulkIndexerItem, res elastic.BulkResponseItem) {
+			mu.Lock()
+			ok = append(ok, it.ID)
+			mu.Unlock()
+		}),
+		elastic.BulkOnFailure(func(it elastic.BulkIndexerItem, res elastic.BulkResponseItem, err error) {
+			mu.Lock()
+			failed = append(failed, res)
+			mu.Unlock()
+		}),
+	)
+
+	for i := 0; i < 10; i++ {
+		is.NoErr(bi.Add(ctx, "index", "", strconv.Itoa(i), `{"title":"some title"}`))
+	}
+	is.NoErr(bi.Add(ctx, "index", "", "bad", `{"title":1}`))
+	err := bi.Close(ctx)
+	is.True(err != nil) // one action failed
+
+	is.Equal(len(ok), 10)
+	is.Equal(len(failed), 1)
+	is.Equal(failed[0].Error.Type, "mapper_parsing_exception")
+	is.True(requests > 1) // flushed on size
+	is.True(bi.Add(ctx, "index", "", "late", `{}`) != nil)
+}
+
+func TestBulkIndexerFlushInterval(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	sent := make(chan struct{}, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"took":1,"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`))
+		sent <- struct{}{}
+	}))
+	defer s.Close()
+
+	bi := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).NewBulkIndexer("articles", elastic.BulkFlushInterval(20*time.Millisecond))
+	is.NoErr(bi.Add(ctx, "index", "", "1", `{}`))
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("buffer was not flushed on the interval")
+	}
+	is.NoErr(bi.Close(ctx))
+}
+
+func TestBulkIndexerContext(t *testing.T) {
+	is := is.New(t)
+
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte(`{"took":1,"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`))
+	}))
+	defer s.Close()
+
+	// The batch outlives the context of the Add that filled the buffer
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	bi := e.NewBulkIndexer("articles", elastic.BulkFlushCount(1))
+	actx, cancel := context.WithCancel(context.Background())
+	is.NoErr(bi.Add(actx, "index", "", "1", `{}`))
+	cancel()
+	close(release)
+	is.NoErr(bi.Close(context.Background()))
+
+	// Close abandons the batches still being sent when its context is done
+	s2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer s2.Close()
+	bi = elastic.NewClient(s2.URL, elastic.WithBasicAuth(user, pass)).NewBulkIndexer("articles", elastic.BulkFlushCount(1))
+	is.NoErr(bi.Add(context.Background(), "index", "", "1", `{}`))
+	cctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	is.True(errors.Is(bi.Close(cctx), context.Canceled))
+}
+
+func TestBulkIndexerBusy(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var ids []string
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var meta map[string]struct {
+				ID string `json:"_id"`
+			}
+			json.Unmarshal(sc.Bytes(), &meta)
+			sc.Scan()
+			mu.Lock()
+			ids = append(ids, meta["index"].ID)
+			mu.Unlock()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer s.Close()
+
+	// An Add that gives up waiting for the busy worker drops only its own action
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	bi := e.NewBulkIndexer("articles", elastic.BulkFlushCount(2))
+	is.NoErr(bi.Add(ctx, "index", "", "1", `{}`))
+	is.NoErr(bi.Add(ctx, "index", "", "2", `{}`)) // sent, and held by the server
+	is.NoErr(bi.Add(ctx, "index", "", "3", `{}`))
+	actx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	is.True(errors.Is(bi.Add(actx, "index", "", "4", `{}`), context.DeadlineExceeded))
+	close(release)
+	is.NoErr(bi.Close(ctx))
+	mu.Lock()
+	is.Equal(ids, []string{"1", "2", "3"})
+	mu.Unlock()
+
+	// A Close that gives up waiting reports the actions still buffered as failed, and is not held up by the ticker
+	s2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer s2.Close()
+	var failed []string
+	bi = elastic.NewClient(s2.URL, elastic.WithBasicAuth(user, pass)).NewBulkIndexer("articles",
+		elastic.BulkFlushCount(100),
+		elastic.BulkFlushInterval(time.Millisecond),
+		elastic.BulkOnFailure(func(it elastic.BulkIndexerItem, _ elastic.BulkResponseItem, err error) {
+			mu.Lock()
+			failed = append(failed, it.ID)
+			mu.Unlock()
+		}),
+	)
+	is.NoErr(bi.Add(ctx, "index", "", "1", `{}`))
+	time.Sleep(20 * time.Millisecond) // sent by the ticker, and held by the server
+	is.NoErr(bi.Add(ctx, "index", "", "2", `{}`))
+	time.Sleep(20 * time.Millisecond) // the ticker finds the worker busy
+	cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	closed := make(chan error)
+	go func() { closed <- bi.Close(cctx) }()
+	select {
+	case err := <-closed:
+		is.True(errors.Is(err, context.DeadlineExceeded))
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not give up when its context was done")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	is.Equal(failed, []string{"2", "1"}) // the buffered action, then the abandoned batch
+}
+
+func TestBulkStream(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var requests int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		sc := bufio.NewScanner(r.Body)
+		var items []string
+		for sc.Scan() {
+			var meta map[string]struct {
+				ID string `json:"_id"`
+			}
+			json.Unmarshal(sc.Bytes(), &meta)
+			sc.Scan()
+			id := meta["index"].ID
+			if id == "13" {
+				items = append(items, `{"index":{"_id":"13","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`)
+				continue
+			}
+			items = append(items, `{"index":{"_id":"`+id+`","status":201,"result":"created"}}`)
+		}
+		w.Write([]byte(`{"took":1,"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	defer s.Close()
+
+	items := make(chan elastic.BulkIndexerItem)
+	go func() {
+		for i := 0; i < 50; i++ {
+			items <- elastic.BulkIndexerItem{ID: strconv.Itoa(i), Doc: `{"title":"some title"}`}
+		}
+		close(items)
+	}()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	var failures []error
+	for err := range e.BulkStream(ctx, "articles", items, elastic.BulkFlushBytes(256)) {
+		failures = append(failures, err)
+	}
+
+	is.Equal(len(failures), 1)
+	var f *elastic.BulkFailure
+	is.True(errors.As(failures[0], &f))
+	is.Equal(f.Item.ID, "13")
+	is.Equal(f.Result.Error.Type, "mapper_parsing_exception")
+	is.True(requests > 1) // chunked by size
+}
+
+func TestLoadNDJSON(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var requests int
+	var lines []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		xl := strings.Split(strings.TrimSuffix(string(xb), "\n"), "\n")
+		mu.Lock()
+		requests++
+		lines = append(lines, xl...)
+		mu.Unlock()
+
+		// One item for each action, failing the deletes
+		var items []string
+		for i := 0; i < len(xl); i++ {
+			if strings.HasPrefix(xl[i], `{"delete"`) {
+				items = append(items, `{"delete":{"_id":"x","status":404,"error":{"type":"not_found","reason":"gone"}}}`)
+				continue
+			}
+			items = append(items, `{"index":{"_id":"x","status":201,"result":"created"}}`)
+			i++ // skip the source
+		}
+		w.Write([]byte(`{"took":1,"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	defer s.Close()
+
+	// 12,000 documents of about 1KB, and some deletes, make three chunks
+	var ndjson strings.Builder
+	body := strings.Repeat("x", 1000)
+	for i := 0; i < 12000; i++ {
+		ndjson.WriteString(`{"index":{"_id":"` + strconv.Itoa(i) + `"}}` + "\n")
+		ndjson.WriteString(`{"body":"` + body + `"}` + "\n")
+		if i%1000 == 0 {
+			ndjson.WriteString(`{"delete":{"_id":"old` + strconv.Itoa(i) + `"}}` + "\n\n") // blank lines are skipped
+		}
+	}
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8))
+	stats, err := e.LoadNDJSON(ctx, "articles", strings.NewReader(ndjson.String()), 4)
+	is.NoErr(err)
+	is.Equal(stats.Chunks, 3)
+	is.Equal(requests, 3)
+	is.Equal(stats.Docs, int64(12000))
+	is.Equal(stats.Failed, int64(12))
+	is.Equal(stats.Bytes, int64(ndjson.Len()-12))
+	is.True(stats.DocsPerSecond() > 0)
+	is.Equal(len(lines), 12000*2+12)
+}
+
+func TestLoadNDJSONError(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"type":"illegal_argument_exception","reason":"Malformed action/metadata line [1]"}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8))
+	stats, err := e.LoadNDJSON(ctx, "articles", strings.NewReader(`{"index":{}}`+"\n"+`{"n":1}`+"\n"), 2)
+	is.True(err != nil)
+	is.Equal(stats.Chunks, 0)
+}
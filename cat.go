@@ -0,0 +1,307 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The cat APIs are asked for the columns the typed results hold, with sizes in bytes so they can be parsed as numbers
+const (
+	uriCatShards     = "/_cat/shards?format=json&bytes=b&h=index,shard,prirep,state,docs,store,ip,node,unassigned.reason"
+	uriCatNodes      = "/_cat/nodes?format=json&h=name,ip,node.role,master,heap.percent,ram.percent,cpu,load_1m,load_5m,load_15m,disk.used_percent"
+	uriCatAllocation = "/_cat/allocation?format=json&bytes=b&h=node,host,ip,shards,disk.indices,disk.used,disk.avail,disk.total,disk.percent"
+	uriCatAliases    = "/_cat/aliases?format=json&h=alias,index,filter,routing.index,routing.search,is_write_index"
+	uriCatSegments   = "/_cat/segments?format=json&bytes=b&h=index,shard,prirep,segment,generation,docs.count,docs.deleted,size,committed,searchable,version,compound"
+)
+
+// CatShard is a shard copy, from _cat/shards. Docs and Store are 0 for a shard that is not started.
+type CatShard struct {
+	Index            string
+	Shard            int
+	Primary          bool
+	State            string // "STARTED", "RELOCATING", "INITIALIZING" or "UNASSIGNED"
+	Docs             int64
+	Store            int64 // bytes
+	IP               string
+	Node             string
+	UnassignedReason string // eg "NODE_LEFT", empty for an assigned shard
+}
+
+// CatShards lists the shard copies of index, or of all indices if index is empty, and where they are allocated
+func (c *Client) CatShards(ctx context.Context, index string) ([]CatShard, error) {
+
+	u := uriCatShards
+	if index != "" {
+		u = strings.Replace(u, "?", "/"+strings.ToLower(index)+"?", 1)
+	}
+	rows, err := c.cat(ctx, u)
+	if err != nil {
+		return nil, errors.Wrap(err, "CatShards")
+	}
+
+	xs := make([]CatShard, len(rows))
+	var p catParser
+	for i, r := range rows {
+		xs[i] = CatShard{
+			Index:            r.str("index"),
+			Shard:            p.int(r, "shard"),
+			Primary:          r.str("prirep") == "p",
+			State:            r.str("state"),
+			Docs:             p.int64(r, "docs"),
+			Store:            p.int64(r, "store"),
+			IP:               r.str("ip"),
+			Node:             r.str("node"),
+			UnassignedReason: r.str("unassigned.reason"),
+		}
+	}
+	if p.err != nil {
+		return nil, errors.Wrap(p.err, "CatShards")
+	}
+	return xs, nil
+}
+
+// CatNode is a node of the cluster and its resource usage, from _cat/nodes. The load averages are -1 where the OS
+// does not report them.
+type CatNode struct {
+	Name            string
+	IP              string
+	Roles           string // a letter for each role, eg "dim" for data, ingest and master eligible
+	Master          bool   // the elected master
+	HeapPercent     int
+	RAMPercent      int
+	CPU             int
+	Load1m          float64
+	Load5m          float64
+	Load15m         float64
+	DiskUsedPercent float64
+}
+
+// CatNodes lists the nodes of the cluster
+func (c *Client) CatNodes(ctx context.Context) ([]CatNode, error) {
+
+	rows, err := c.cat(ctx, uriCatNodes)
+	if err != nil {
+		return nil, errors.Wrap(err, "CatNodes")
+	}
+
+	xs := make([]CatNode, len(rows))
+	var p catParser
+	for i, r := range rows {
+		xs[i] = CatNode{
+			Name:            r.str("name"),
+			IP:              r.str("ip"),
+			Roles:           r.str("node.role"),
+			Master:          r.str("master") == "*",
+			HeapPercent:     p.int(r, "heap.percent"),
+			RAMPercent:      p.int(r, "ram.percent"),
+			CPU:             p.int(r, "cpu"),
+			Load1m:          p.float(r, "load_1m"),
+			Load5m:          p.float(r, "load_5m"),
+			Load15m:         p.float(r, "load_15m"),
+			DiskUsedPercent: p.float(r, "disk.used_percent"),
+		}
+	}
+	if p.err != nil {
+		return nil, errors.Wrap(p.err, "CatNodes")
+	}
+	return xs, nil
+}
+
+// CatAllocation is the number of shards on a node and its disk usage, from _cat/allocation. Shards that are not
+// allocated are counted against a Node of "UNASSIGNED", with no disk usage.
+type CatAllocation struct {
+	Node        string
+	Host        string
+	IP          string
+	Shards      int
+	DiskIndices int64 // bytes used by shards
+	DiskUsed    int64 // bytes used in total
+	DiskAvail   int64
+	DiskTotal   int64
+	DiskPercent int
+}
+
+// CatAllocation lists the shard count and disk usage of each data node
+func (c *Client) CatAllocation(ctx context.Context) ([]CatAllocation, error) {
+
+	rows, err := c.cat(ctx, uriCatAllocation)
+	if err != nil {
+		return nil, errors.Wrap(err, "CatAllocation")
+	}
+
+	xs := make([]CatAllocation, len(rows))
+	var p catParser
+	for i, r := range rows {
+		xs[i] = CatAllocation{
+			Node:        r.str("node"),
+			Host:        r.str("host"),
+			IP:          r.str("ip"),
+			Shards:      p.int(r, "shards"),
+			DiskIndices: p.int64(r, "disk.indices"),
+			DiskUsed:    p.int64(r, "disk.used"),
+			DiskAvail:   p.int64(r, "disk.avail"),
+			DiskTotal:   p.int64(r, "disk.total"),
+			DiskPercent: p.int(r, "disk.percent"),
+		}
+	}
+	if p.err != nil {
+		return nil, errors.Wrap(p.err, "CatAllocation")
+	}
+	return xs, nil
+}
+
+// CatAlias is an alias of an index, from _cat/aliases. Filter is "*" if the alias has a filter, and the routing
+// fields are empty if it has no routing.
+type CatAlias struct {
+	Alias         string
+	Index         string
+	Filter        string
+	RoutingIndex  string
+	RoutingSearch string
+	IsWriteIndex  bool
+}
+
+// CatAliases lists every alias and the indices it points to, one entry for each index
+func (c *Client) CatAliases(ctx context.Context) ([]CatAlias, error) {
+
+	rows, err := c.cat(ctx, uriCatAliases)
+	if err != nil {
+		return nil, errors.Wrap(err, "CatAliases")
+	}
+
+	xs := make([]CatAlias, len(rows))
+	for i, r := range rows {
+		xs[i] = CatAlias{
+			Alias:         r.str("alias"),
+			Index:         r.str("index"),
+			Filter:        r.str("filter"),
+			RoutingIndex:  r.str("routing.index"),
+			RoutingSearch: r.str("routing.search"),
+			IsWriteIndex:  r.str("is_write_index") == "true",
+		}
+	}
+	return xs, nil
+}
+
+// CatSegment is a Lucene segment of a shard copy, from _cat/segments. Many small segments in an index that is no
+// longer written to is a sign it would benefit from a force merge.
+type CatSegment struct {
+	Index       string
+	Shard       int
+	Primary     bool
+	Segment     string
+	Generation  int
+	DocsCount   int64
+	DocsDeleted int64
+	Size        int64 // bytes
+	Committed   bool
+	Searchable  bool
+	Version     string // Lucene version
+	Compound    bool
+}
+
+// CatSegments lists the segments of each shard copy of index, or of all indices if index is empty
+func (c *Client) CatSegments(ctx context.Context, index string) ([]CatSegment, error) {
+
+	u := uriCatSegments
+	if index != "" {
+		u = strings.Replace(u, "?", "/"+strings.ToLower(index)+"?", 1)
+	}
+	rows, err := c.cat(ctx, u)
+	if err != nil {
+		return nil, errors.Wrap(err, "CatSegments")
+	}
+
+	xs := make([]CatSegment, len(rows))
+	var p catParser
+	for i, r := range rows {
+		xs[i] = CatSegment{
+			Index:       r.str("index"),
+			Shard:       p.int(r, "shard"),
+			Primary:     r.str("prirep") == "p",
+			Segment:     r.str("segment"),
+			Generation:  p.int(r, "generation"),
+			DocsCount:   p.int64(r, "docs.count"),
+			DocsDeleted: p.int64(r, "docs.deleted"),
+			Size:        p.int64(r, "size"),
+			Committed:   r.str("committed") == "true",
+			Searchable:  r.str("searchable") == "true",
+			Version:     r.str("version"),
+			Compound:    r.str("compound") == "true",
+		}
+	}
+	if p.err != nil {
+		return nil, errors.Wrap(p.err, "CatSegments")
+	}
+	return xs, nil
+}
+
+// catRow is a row of a cat API response. The values are all strings, or null where there is no value, eg the size
+// of an unassigned shard.
+type catRow map[string]*string
+
+// cat fetches the rows of a cat API response
+func (c *Client) cat(ctx context.Context, path string) ([]catRow, error) {
+
+	xb, err := c.request(ctx, "GET", path, nil, standardHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []catRow
+	if err := json.Unmarshal(xb, &rows); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return rows, nil
+}
+
+// str returns the value of column k, or "" if there is none. The cat APIs show "-" for no value in some columns,
+// which is also returned as "".
+func (r catRow) str(k string) string {
+	v := r[k]
+	if v == nil || *v == "-" {
+		return ""
+	}
+	return *v
+}
+
+// catParser parses the numeric columns of cat rows, keeping the first error so that a row can be parsed in one
+// expression. A column with no value parses as 0.
+type catParser struct {
+	err error
+}
+
+// int parses column k as an int
+func (p *catParser) int(r catRow, k string) int {
+	return int(p.int64(r, k))
+}
+
+// int64 parses column k as an int64
+func (p *catParser) int64(r catRow, k string) int64 {
+	s := r.str(k)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil && p.err == nil {
+		p.err = errors.Wrap(err, k)
+	}
+	return n
+}
+
+// float parses column k as a float64
+func (p *catParser) float(r catRow, k string) float64 {
+	s := r.str(k)
+	if s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && p.err == nil {
+		p.err = errors.Wrap(err, k)
+	}
+	return f
+}
@@ -0,0 +1,157 @@
+package elastic_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+// catServer returns a server that responds to each cat API path with the canned rows
+func catServer(t *testing.T, rows map[string]string) *httptest.Server {
+	is := is.New(t)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Query().Get("format"), "json")
+		body, ok := rows[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestIndicesCat(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := catServer(t, map[string]string{
+		"/_cat/indices": `[
+			{"health":"green","status":"open","index":"articles","uuid":"u1","pri":"1","rep":"1",
+			 "docs.count":"1200","docs.deleted":"3","store.size":"2048","pri.store.size":"1024"},
+			{"health":"green","status":"open","index":".security","uuid":"u2","pri":"1","rep":"0",
+			 "docs.count":"7","docs.deleted":"0","store.size":"100","pri.store.size":"100"},
+			{"health":null,"status":"close","index":"archive","uuid":"u3","pri":"1","rep":"1",
+			 "docs.count":null,"docs.deleted":null,"store.size":null,"pri.store.size":null}]`,
+	})
+	defer s.Close()
+
+	xi, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).Indices(ctx)
+	is.NoErr(err)
+	is.Equal(len(xi), 2) // dot index left out
+	is.Equal(xi[0].Docs, 1200)
+	is.Equal(xi[0].DocsDeleted, int64(3))
+	is.Equal(xi[0].StoreSize, int64(2048))
+	is.Equal(xi[0].Replicas, 1)
+	is.Equal(xi[1].Status, "close")
+	is.Equal(xi[1].Docs, 0)
+}
+
+func TestCatShards(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := catServer(t, map[string]string{
+		"/_cat/shards/articles": `[
+			{"index":"articles","shard":"0","prirep":"p","state":"STARTED","docs":"1200","store":"1024",
+			 "ip":"10.0.0.1","node":"es-1","unassigned.reason":null},
+			{"index":"articles","shard":"0","prirep":"r","state":"UNASSIGNED","docs":null,"store":null,
+			 "ip":null,"node":null,"unassigned.reason":"NODE_LEFT"}]`,
+	})
+	defer s.Close()
+
+	xs, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).CatShards(ctx, "articles")
+	is.NoErr(err)
+	is.Equal(xs, []elastic.CatShard{
+		{Index: "articles", Primary: true, State: "STARTED", Docs: 1200, Store: 1024, IP: "10.0.0.1", Node: "es-1"},
+		{Index: "articles", State: "UNASSIGNED", UnassignedReason: "NODE_LEFT"},
+	})
+}
+
+func TestCatNodes(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := catServer(t, map[string]string{
+		"/_cat/nodes": `[{"name":"es-1","ip":"10.0.0.1","node.role":"dim","master":"*","heap.percent":"42",
+			"ram.percent":"91","cpu":"7","load_1m":"0.52","load_5m":"0.40","load_15m":"0.33","disk.used_percent":"61.25"}]`,
+	})
+	defer s.Close()
+
+	xn, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).CatNodes(ctx)
+	is.NoErr(err)
+	is.Equal(len(xn), 1)
+	is.True(xn[0].Master)
+	is.Equal(xn[0].HeapPercent, 42)
+	is.Equal(xn[0].Load1m, 0.52)
+	is.Equal(xn[0].DiskUsedPercent, 61.25)
+}
+
+func TestCatAllocation(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := catServer(t, map[string]string{
+		"/_cat/allocation": `[
+			{"node":"es-1","host":"10.0.0.1","ip":"10.0.0.1","shards":"12","disk.indices":"1024",
+			 "disk.used":"4096","disk.avail":"8192","disk.total":"12288","disk.percent":"33"},
+			{"node":"UNASSIGNED","host":null,"ip":null,"shards":"2","disk.indices":null,
+			 "disk.used":null,"disk.avail":null,"disk.total":null,"disk.percent":null}]`,
+	})
+	defer s.Close()
+
+	xa, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).CatAllocation(ctx)
+	is.NoErr(err)
+	is.Equal(xa[0].Shards, 12)
+	is.Equal(xa[0].DiskTotal, int64(12288))
+	is.Equal(xa[0].DiskPercent, 33)
+	is.Equal(xa[1], elastic.CatAllocation{Node: "UNASSIGNED", Shards: 2})
+}
+
+func TestCatAliases(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := catServer(t, map[string]string{
+		"/_cat/aliases": `[{"alias":"logs","index":"logs-000002","filter":"-","routing.index":"-",
+			"routing.search":"-","is_write_index":"true"}]`,
+	})
+	defer s.Close()
+
+	xa, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).CatAliases(ctx)
+	is.NoErr(err)
+	is.Equal(xa, []elastic.CatAlias{{Alias: "logs", Index: "logs-000002", IsWriteIndex: true}})
+}
+
+func TestCatSegments(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := catServer(t, map[string]string{
+		"/_cat/segments": `[{"index":"articles","shard":"0","prirep":"p","segment":"_0","generation":"0",
+			"docs.count":"1200","docs.deleted":"3","size":"1024","committed":"true","searchable":"true",
+			"version":"8.11.1","compound":"false"}]`,
+	})
+	defer s.Close()
+
+	xs, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).CatSegments(ctx, "")
+	is.NoErr(err)
+	is.Equal(xs, []elastic.CatSegment{{Index: "articles", Primary: true, Segment: "_0", DocsCount: 1200, DocsDeleted: 3,
+		Size: 1024, Committed: true, Searchable: true, Version: "8.11.1"}})
+}
+
+func TestCatBadNumber(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := catServer(t, map[string]string{
+		"/_cat/nodes": `[{"name":"es-1","heap.percent":"lots"}]`,
+	})
+	defer s.Close()
+
+	_, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).CatNodes(ctx)
+	is.True(err != nil) // not silently 0
+}
@@ -0,0 +1,301 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ServerInfo is the basic information about a node returned from the root endpoint
+type ServerInfo struct {
+	Name        string `json:"name"`
+	ClusterName string `json:"cluster_name"`
+	ClusterUUID string `json:"cluster_uuid"`
+	Version     struct {
+		Number        string `json:"number"`
+		Distribution  string `json:"distribution"` // "opensearch" for OpenSearch, empty for Elasticsearch
+		LuceneVersion string `json:"lucene_version"`
+	} `json:"version"`
+	Tagline string `json:"tagline"`
+}
+
+// IsOpenSearch reports whether the node is OpenSearch rather than Elasticsearch
+func (s ServerInfo) IsOpenSearch() bool {
+	return s.Version.Distribution == "opensearch"
+}
+
+// MajorVersion returns the major part of the server version number, eg 7 for "7.10.2", or 0 if it can't be parsed
+func (s ServerInfo) MajorVersion() int {
+	n, _ := strconv.Atoi(strings.SplitN(s.Version.Number, ".", 2)[0])
+	return n
+}
+
+// Info fetches the name, cluster name and version of the node from the root endpoint. It is more informative than
+// CheckOK at startup as the version can be used to decide how to talk to the cluster. The client records the major
+// version, and whether the cluster is OpenSearch, so that the requests it builds suit the cluster without a further
+// request. An error is returned if the response does not look like it came from Elasticsearch or OpenSearch.
+func (c *Client) Info(ctx context.Context) (ServerInfo, error) {
+
+	xb, err := c.request(ctx, "GET", "/", nil, standardHeaders)
+	if err != nil {
+		return ServerInfo{}, errors.Wrap(err, "Info")
+	}
+
+	si, err := parseServerInfo(xb)
+	if err != nil {
+		return si, errors.Wrap(err, "Info")
+	}
+	if si.IsOpenSearch() {
+		atomic.StoreInt32(&c.openSearch, 1)
+	}
+	if v := si.MajorVersion(); v > 0 {
+		atomic.StoreInt32(&c.version, int32(v))
+	}
+
+	return si, nil
+}
+
+// majorVersion returns the major version of the cluster, as set by WithVersion or recorded by Info. If it is not
+// known it is detected with Info the first time it is needed. If that fails it returns 0, and detection is not tried
+// again unless the context was done, so that a client without permission to read the root endpoint does not make
+// the request every time.
+func (c *Client) majorVersion(ctx context.Context) int {
+
+	if v := atomic.LoadInt32(&c.version); v != 0 {
+		return int(max32(v, 0))
+	}
+
+	c.detectMu.Lock()
+	defer c.detectMu.Unlock()
+	if v := atomic.LoadInt32(&c.version); v != 0 {
+		return int(max32(v, 0))
+	}
+
+	if _, err := c.Info(ctx); err != nil {
+		if ctx.Err() == nil {
+			atomic.StoreInt32(&c.version, -1)
+		}
+		return 0
+	}
+	return int(atomic.LoadInt32(&c.version))
+}
+
+// typeless reports whether the cluster uses the typeless document endpoints introduced in version 7, eg
+// /{index}/_update/{id} rather than /{index}/_doc/{id}/_update. If the version is not known they are assumed, as
+// version 8 accepts nothing else. OpenSearch uses them from its first version.
+func (c *Client) typeless(ctx context.Context) bool {
+	if c.serverless || c.isOpenSearch(ctx) {
+		return true
+	}
+	v := c.majorVersion(ctx)
+	return v == 0 || v >= 7
+}
+
+// isOpenSearch reports whether the cluster is OpenSearch, as set by WithOpenSearch or recorded by Info. If the
+// version is not known the cluster is detected as for majorVersion. A cluster whose version was set by WithVersion
+// is taken to be Elasticsearch.
+func (c *Client) isOpenSearch(ctx context.Context) bool {
+	if c.serverless {
+		return false
+	}
+	if atomic.LoadInt32(&c.openSearch) == 1 {
+		return true
+	}
+	c.majorVersion(ctx)
+	return atomic.LoadInt32(&c.openSearch) == 1
+}
+
+// max32 returns the larger of a and b
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// parseServerInfo parses the response from the root endpoint
+func parseServerInfo(xb []byte) (ServerInfo, error) {
+	var si ServerInfo
+	err := json.Unmarshal(xb, &si)
+	if err != nil {
+		return si, errors.Wrap(err, "response is not from Elasticsearch or OpenSearch")
+	}
+	if si.Version.Number == "" || si.ClusterName == "" {
+		return si, errors.New("response is not from Elasticsearch or OpenSearch, no version or cluster name")
+	}
+	return si, nil
+}
+
+// Health is the response from the cluster health API
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/cluster-health.html
+type Health struct {
+	ClusterName                 string  `json:"cluster_name"`
+	Status                      string  `json:"status"`
+	TimedOut                    bool    `json:"timed_out"`
+	NumberOfNodes               int     `json:"number_of_nodes"`
+	NumberOfDataNodes           int     `json:"number_of_data_nodes"`
+	ActivePrimaryShards         int     `json:"active_primary_shards"`
+	ActiveShards                int     `json:"active_shards"`
+	RelocatingShards            int     `json:"relocating_shards"`
+	InitializingShards          int     `json:"initializing_shards"`
+	UnassignedShards            int     `json:"unassigned_shards"`
+	DelayedUnassignedShards     int     `json:"delayed_unassigned_shards"`
+	NumberOfPendingTasks        int     `json:"number_of_pending_tasks"`
+	ActiveShardsPercentAsNumber float64 `json:"active_shards_percent_as_number"`
+}
+
+// ClusterHealth fetches the status, green, yellow or red, of the cluster along with its node and shard counts
+func (c *Client) ClusterHealth(ctx context.Context) (*Health, error) {
+
+	xb, err := c.request(ctx, "GET", uriClusterHealth, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "ClusterHealth")
+	}
+
+	var h Health
+	err = json.Unmarshal(xb, &h)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	return &h, nil
+}
+
+// WaitForStatus blocks until the cluster, or the specified indices, reach status, "yellow" or "green", or until
+// timeout has passed. The wait happens on the server, so timeout should be shorter than any WithTimeout set on the
+// client. The returned error satisfies IsTimeout if the status was not reached in time.
+func (c *Client) WaitForStatus(ctx context.Context, status string, timeout time.Duration, indices ...string) error {
+
+	u := uriClusterHealth
+	if len(indices) > 0 {
+		u += "/" + strings.Join(indices, ",")
+	}
+	u += "?wait_for_status=" + status + "&timeout=" + timeValue(timeout)
+
+	xb, err := c.request(ctx, "GET", u, nil, standardHeaders)
+	var e *Error
+	if errors.As(err, &e) && e.StatusCode == http.StatusRequestTimeout {
+		xb, err = e.Body, nil // the health response, with timed_out set
+	}
+	if err != nil {
+		return errors.Wrap(err, "WaitForStatus")
+	}
+
+	var h Health
+	err = json.Unmarshal(xb, &h)
+	if err != nil {
+		return errors.Wrap(err, "Unmarshal")
+	}
+	if h.TimedOut {
+		return errors.Wrap(&Error{StatusCode: http.StatusRequestTimeout, Reason: "status is " + h.Status, Body: xb},
+			"WaitForStatus - "+status)
+	}
+
+	return nil
+}
+
+// HotThreads returns the plain text hot threads report, the sampled stack traces of the busiest threads, for the
+// specified node, or all nodes if nodeID is empty.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/cluster-nodes-hot-threads.html
+func (c *Client) HotThreads(ctx context.Context, nodeID string) (string, error) {
+
+	u := "/_nodes/hot_threads"
+	if nodeID != "" {
+		u = "/_nodes/" + url.PathEscape(nodeID) + "/hot_threads"
+	}
+
+	xb, err := c.request(ctx, "GET", u, nil, standardHeaders)
+	if err != nil {
+		return "", errors.Wrap(err, "HotThreads")
+	}
+
+	return string(xb), nil
+}
+
+// PendingTask is a cluster-level change, such as creating an index or updating a mapping, that is queued on the
+// master node
+type PendingTask struct {
+	InsertOrder int
+	TimeInQueue time.Duration
+	Priority    string
+	Source      string
+}
+
+// PendingTasksCat returns the cluster state updates waiting to be applied by the master, from _cat/pending_tasks. A
+// long TimeInQueue is a sign of a backed up master.
+func (c *Client) PendingTasksCat(ctx context.Context) ([]PendingTask, error) {
+
+	xb, err := c.request(ctx, "GET", uriPendingTasks, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "PendingTasksCat")
+	}
+
+	var xr []struct {
+		InsertOrder string `json:"insertOrder"`
+		TimeInQueue string `json:"timeInQueue"`
+		Priority    string `json:"priority"`
+		Source      string `json:"source"`
+	}
+	err = json.Unmarshal(xb, &xr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	xt := make([]PendingTask, len(xr))
+	for i, r := range xr {
+		order, err := strconv.Atoi(r.InsertOrder)
+		if err != nil {
+			return nil, errors.Wrap(err, "insertOrder")
+		}
+		ms, err := strconv.ParseInt(r.TimeInQueue, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "timeInQueue")
+		}
+		xt[i] = PendingTask{
+			InsertOrder: order,
+			TimeInQueue: time.Duration(ms) * time.Millisecond,
+			Priority:    r.Priority,
+			Source:      r.Source,
+		}
+	}
+
+	return xt, nil
+}
+
+// WriteQueueDepth returns the number of queued operations in each node's write thread pool, keyed by node name. A
+// growing queue means the cluster is not keeping up with indexing, and is a signal to slow down before requests
+// start to be rejected.
+func (c *Client) WriteQueueDepth(ctx context.Context) (map[string]int, error) {
+
+	xb, err := c.request(ctx, "GET", uriWriteQueue, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "WriteQueueDepth")
+	}
+
+	var xr []struct {
+		NodeName string `json:"node_name"`
+		Queue    string `json:"queue"`
+	}
+	err = json.Unmarshal(xb, &xr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	depth := make(map[string]int, len(xr))
+	for _, r := range xr {
+		n, err := strconv.Atoi(r.Queue)
+		if err != nil {
+			return nil, errors.Wrap(err, "queue")
+		}
+		depth[r.NodeName] = n
+	}
+
+	return depth, nil
+}
@@ -0,0 +1,374 @@
+package elastic_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestInfo(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /": fixture("info.json"),
+	})
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	si, err := e.Info(ctx)
+	is.NoErr(err)
+	is.Equal(si.Name, "instance-0000000001")
+	is.Equal(si.Version.Number, "7.10.2")
+	is.Equal(si.MajorVersion(), 7)
+
+	// Something that isn't Elasticsearch
+	s2 := mockServer(map[string][]byte{
+		"GET /": []byte(`{"status":"ok"}`),
+	})
+	defer s2.Close()
+
+	_, err = elastic.NewClient(s2.URL, elastic.WithBasicAuth(user, pass)).Info(ctx)
+	is.True(err != nil)
+}
+
+func TestVersionDetection(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		info  string
+		paths []string
+	}{
+		{
+			info:  `{"cluster_name":"docs","version":{"number":"6.8.23"}}`,
+			paths: []string{"GET /", "POST /articles/_doc/_bulk", "POST /articles/_doc/1/_update"},
+		},
+		{
+			info:  `{"cluster_name":"docs","version":{"number":"8.11.1"}}`,
+			paths: []string{"GET /", "POST /articles/_bulk", "POST /articles/_update/1"},
+		},
+		{
+			info:  `{"status":"forbidden"}`, // not detected, and not asked again
+			paths: []string{"GET /", "POST /articles/_bulk", "POST /articles/_update/1"},
+		},
+	}
+
+	for _, tt := range tests {
+		var paths []string
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.Method+" "+r.URL.Path)
+			if r.URL.Path == "/" {
+				w.Write([]byte(tt.info))
+				return
+			}
+			w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+		}))
+
+		e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+		_, err := e.Batch(ctx, "articles", "{}\n")
+		is.NoErr(err)
+		_, err = e.UpdateDoc(ctx, "articles", "1", `{"views":1}`)
+		is.NoErr(err)
+		is.Equal(paths, tt.paths)
+		s.Close()
+	}
+
+	// Info and WithVersion set the version without detection
+	var paths []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"cluster_name":"docs","version":{"number":"6.8.23"},"items":[]}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	_, err := e.Info(ctx)
+	is.NoErr(err)
+	_, err = e.Batch(ctx, "articles", "{}\n")
+	is.NoErr(err)
+	_, err = elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(7)).Batch(ctx, "articles", "{}\n")
+	is.NoErr(err)
+	is.Equal(paths, []string{"GET /", "POST /articles/_doc/_bulk", "POST /articles/_bulk"})
+}
+
+func TestOpenSearch(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(xb)))
+		switch {
+		case r.URL.Path == "/":
+			w.Write([]byte(`{"name":"os-1","cluster_name":"docs","version":{"distribution":"opensearch","number":"2.11.0"}}`))
+		case r.URL.Path == "/articles/_search/point_in_time":
+			w.Write([]byte(`{"pit_id":"p1","_shards":{"total":1,"successful":1,"failed":0},"creation_time":1}`))
+		case r.URL.Path == "/_plugins/_sql":
+			w.Write([]byte(`{"schema":[{"name":"author","type":"text"}],"datarows":[["ann"],["bob"]],` +
+				`"total":2,"size":2,"status":200}`))
+		case strings.HasPrefix(r.URL.Path, "/_plugins/_asynchronous_search"):
+			w.Write([]byte(`{"id":"a1","state":"RUNNING","start_time_in_millis":1,"expiration_time_in_millis":2,` +
+				`"response":{"hits":{"total":{"value":10,"relation":"gte"},"hits":[]}}}`))
+		default:
+			w.Write([]byte(`{"took":1,"errors":false,"items":[],"result":"updated"}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	_, err := e.Batch(ctx, "articles", "{}\n")
+	is.NoErr(err)
+	_, err = e.UpdateDoc(ctx, "articles", "1", `{"views":1}`)
+	is.NoErr(err)
+
+	id, err := e.OpenPointInTime(ctx, "articles", time.Minute)
+	is.NoErr(err)
+	is.Equal(id, "p1")
+	is.NoErr(e.ClosePointInTime(ctx, id))
+
+	r, err := e.SQL(ctx, "SELECT author FROM articles")
+	is.NoErr(err)
+	is.Equal(r.Columns, []elastic.SQLColumn{{Name: "author", Type: "text"}})
+	is.Equal(r.Rows, [][]interface{}{{"ann"}, {"bob"}})
+
+	as, err := e.SubmitAsyncSearch(ctx, elastic.SearchRequest{Index: "articles"}, 0, 0)
+	is.NoErr(err)
+	is.True(as.IsRunning)
+	is.True(as.IsPartial)
+
+	is.Equal(requests, []string{
+		"GET /",
+		"POST /articles/_bulk {}",
+		`POST /articles/_update/1 {"doc": {"views":1}}`,
+		"POST /articles/_search/point_in_time?keep_alive=1m",
+		`DELETE /_search/point_in_time {"pit_id":["p1"]}`,
+		`POST /_plugins/_sql {"query":"SELECT author FROM articles"}`,
+		"POST /_plugins/_asynchronous_search?index=articles {}",
+	})
+
+	// WithOpenSearch skips detection
+	requests = nil
+	_, err = elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithOpenSearch()).Batch(ctx, "articles", "{}\n")
+	is.NoErr(err)
+	is.Equal(requests, []string{"POST /articles/_bulk {}"})
+}
+
+func TestPendingTasksCat(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /_cat/pending_tasks": []byte(`[
+			{"insertOrder":"1685","timeInQueue":"855","priority":"HIGH","source":"update-mapping [foo][t]"},
+			{"insertOrder":"1686","timeInQueue":"843","priority":"URGENT","source":"shard-started"}
+		]`),
+	})
+	defer s.Close()
+
+	xt, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).PendingTasksCat(ctx)
+	is.NoErr(err)
+	is.Equal(len(xt), 2)
+	is.Equal(xt[0].InsertOrder, 1685)
+	is.Equal(xt[0].TimeInQueue, 855*time.Millisecond)
+	is.Equal(xt[1].Priority, "URGENT")
+}
+
+func TestHotThreads(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	const report = "::: {node-1}{abc}\n   Hot threads at 2026-10-15T00:00:00Z, interval=500ms, busiestThreads=3:\n"
+	var uri string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri = r.RequestURI
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.Write([]byte(report))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	txt, err := e.HotThreads(ctx, "")
+	is.NoErr(err)
+	is.Equal(uri, "/_nodes/hot_threads")
+	is.Equal(txt, report)
+
+	_, err = e.HotThreads(ctx, "node-1,node/2")
+	is.NoErr(err)
+	is.Equal(uri, "/_nodes/node-1%2Cnode%2F2/hot_threads")
+}
+
+func TestDiagnose(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /": fixture("info.json"),
+	})
+	defer s.Close()
+
+	d, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).Diagnose(ctx)
+	is.NoErr(err)
+	is.Equal(d.Version, "7.10.2")
+	is.Equal(len(d.Steps), 5)
+	is.True(d.Steps[2].Skipped) // no tls for http
+
+	// Nothing listening
+	s.Close()
+	d, err = elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).Diagnose(ctx)
+	is.True(err != nil)
+	is.Equal(d.Failed().Name, elastic.StepTCP)
+	is.True(d.Steps[3].Skipped) // later steps don't run
+
+	// Only a 401 or 403 is an auth failure
+	status := http.StatusUnauthorized
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"error":{"type":"cluster_block_exception","reason":"blocked"},"status":` +
+			strconv.Itoa(status) + `}`))
+	}))
+	defer s.Close()
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8))
+	d, _ = e.Diagnose(ctx)
+	is.Equal(d.Failed().Name, elastic.StepAuth)
+	status = http.StatusForbidden
+	d, _ = e.Diagnose(ctx)
+	is.Equal(d.Failed().Name, elastic.StepAuth)
+	status = http.StatusServiceUnavailable
+	d, err = e.Diagnose(ctx)
+	is.Equal(d.Failed().Name, elastic.StepVersion)
+	is.True(d.Steps[3].Err == nil) // auth passed
+	is.Equal(err.Error(), "Diagnose - version: Service Unavailable - blocked")
+}
+
+func TestHostHealth(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	up := mockServer(map[string][]byte{
+		"GET /_cat/health": mockResponseJSON["health"],
+	})
+	defer up.Close()
+	down := mockServer(nil)
+	down.Close()
+
+	health := elastic.NewClientWithHosts([]string{up.URL, down.URL}, elastic.WithBasicAuth(user, pass)).HostHealth(ctx)
+	is.Equal(len(health), 2)
+	is.NoErr(health[up.URL])
+	is.True(health[down.URL] != nil)
+}
+
+func TestWriteQueueDepth(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /_cat/thread_pool/write": []byte(`[{"node_name":"node-1","queue":"0"},{"node_name":"node-2","queue":"17"}]`),
+	})
+	defer s.Close()
+
+	depth, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).WriteQueueDepth(ctx)
+	is.NoErr(err)
+	is.Equal(depth, map[string]int{"node-1": 0, "node-2": 17})
+}
+
+func TestSniff(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var other string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_nodes/http":
+			w.Write([]byte(`{"nodes":{
+				"a":{"http":{"publish_address":"` + strings.TrimPrefix(other, "http://") + `"}},
+				"b":{"http":{"publish_address":"localhost/127.0.0.1:1"}},
+				"c":{}
+			}}`))
+		default:
+			w.Write(mockResponseJSON["health"])
+		}
+	}))
+	defer s.Close()
+	o := mockServer(map[string][]byte{
+		"GET /_cat/health": mockResponseJSON["health"],
+	})
+	defer o.Close()
+	other = o.URL
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	defer e.Close()
+	is.NoErr(e.Sniff(ctx))
+
+	health := e.HostHealth(ctx)
+	is.Equal(len(health), 2)
+	is.NoErr(health[o.URL])                      // discovered
+	is.True(health["http://localhost:1"] != nil) // hostname preferred over ip
+	_, ok := health[s.URL]
+	is.True(!ok) // not a node in the cluster
+}
+
+func TestClusterHealth(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /_cluster/health": []byte(`{
+			"cluster_name": "docs",
+			"status": "yellow",
+			"timed_out": false,
+			"number_of_nodes": 3,
+			"number_of_data_nodes": 2,
+			"active_primary_shards": 14,
+			"active_shards": 26,
+			"relocating_shards": 1,
+			"initializing_shards": 0,
+			"unassigned_shards": 2,
+			"active_shards_percent_as_number": 92.8
+		}`),
+	})
+	defer s.Close()
+
+	h, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).ClusterHealth(ctx)
+	is.NoErr(err)
+	is.Equal(h.Status, "yellow")
+	is.Equal(h.NumberOfDataNodes, 2)
+	is.Equal(h.RelocatingShards, 1)
+	is.Equal(h.UnassignedShards, 2)
+	is.Equal(h.ActiveShardsPercentAsNumber, 92.8)
+}
+
+func TestWaitForStatus(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var query string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Path + "?" + r.URL.RawQuery
+		if r.URL.Query().Get("wait_for_status") == "green" {
+			w.WriteHeader(http.StatusRequestTimeout)
+			w.Write([]byte(`{"cluster_name":"docs","status":"yellow","timed_out":true}`))
+			return
+		}
+		w.Write([]byte(`{"cluster_name":"docs","status":"yellow","timed_out":false}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.WaitForStatus(ctx, "yellow", 30*time.Second, "articles"))
+	is.Equal(query, "/_cluster/health/articles?wait_for_status=yellow&timeout=30s")
+
+	err := e.WaitForStatus(ctx, "green", 500*time.Millisecond)
+	is.True(err != nil)
+	is.True(elastic.IsTimeout(err))
+	is.True(strings.Contains(err.Error(), "status is yellow"))
+}
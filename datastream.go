@@ -0,0 +1,73 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DataStream is an append-only stream of time series documents, such as logs, stored in hidden backing indices
+// that are rolled over as they grow. A data stream is created for a composable template with DataStream set, either
+// by CreateDataStream or by the first write to a name matching the template. Writes to a data stream must create
+// documents, see OpTypeCreate and BulkDefaultAction, and each document must have a @timestamp field.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/data-streams.html
+type DataStream struct {
+	Name           string `json:"name"`
+	TimestampField struct {
+		Name string `json:"name"`
+	} `json:"timestamp_field"`
+	Indices    []DataStreamIndex `json:"indices"` // oldest first, the last is the write index
+	Generation int               `json:"generation"`
+	Status     string            `json:"status"` // health of the backing indices, "GREEN", "YELLOW" or "RED"
+	Template   string            `json:"template"`
+	ILMPolicy  string            `json:"ilm_policy"`
+}
+
+// DataStreamIndex is a backing index of a data stream
+type DataStreamIndex struct {
+	Name string `json:"index_name"`
+	UUID string `json:"index_uuid"`
+}
+
+// CreateDataStream creates a data stream, which requires Elasticsearch 7.9 or later and a composable index template
+// matching name with DataStream set
+func (c *Client) CreateDataStream(ctx context.Context, name string) error {
+	_, err := c.request(ctx, "PUT", "/_data_stream/"+strings.ToLower(name), nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "CreateDataStream")
+	}
+	return nil
+}
+
+// GetDataStreams returns the data streams matching name, which may be a wildcard pattern such as "logs-*", or all
+// data streams if name is empty
+func (c *Client) GetDataStreams(ctx context.Context, name string) ([]DataStream, error) {
+
+	u := "/_data_stream"
+	if name != "" {
+		u += "/" + strings.ToLower(name)
+	}
+	xb, err := c.request(ctx, "GET", u, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetDataStreams")
+	}
+
+	var r struct {
+		DataStreams []DataStream `json:"data_streams"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return r.DataStreams, nil
+}
+
+// DeleteDataStream deletes a data stream and its backing indices, and so all of its documents
+func (c *Client) DeleteDataStream(ctx context.Context, name string) error {
+	_, err := c.request(ctx, "DELETE", "/_data_stream/"+strings.ToLower(name), nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "DeleteDataStream")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package elastic_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/elastictest"
+)
+
+func TestDataStreams(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	tr := elastictest.NewTransport()
+	tr.Handle("PUT", "/_index_template/logs", http.StatusOK, `{"acknowledged":true}`)
+	tr.Handle("PUT", "/_data_stream/logs-app", http.StatusOK, `{"acknowledged":true}`)
+	tr.Handle("GET", "/_data_stream/logs-*", http.StatusOK, `{"data_streams":[{"name":"logs-app",`+
+		`"timestamp_field":{"name":"@timestamp"},"indices":[{"index_name":".ds-logs-app-2026.10.15-000001",`+
+		`"index_uuid":"u1"}],"generation":1,"status":"GREEN","template":"logs","ilm_policy":"logs"}]}`)
+	tr.Handle("DELETE", "/_data_stream/logs-app", http.StatusOK, `{"acknowledged":true}`)
+	tr.Handle("PUT", "/logs-app/_doc/1", http.StatusCreated, `{"_index":".ds-logs-app-2026.10.15-000001",`+
+		`"_id":"1","_version":1,"result":"created"}`)
+	tr.Handle("POST", "/logs-app/_bulk", http.StatusOK, `{"errors":false,"items":[`+
+		`{"create":{"_id":"a","status":201,"result":"created"}}]}`)
+	e := elastictest.NewClient(tr, elastic.WithVersion(8))
+
+	is.NoErr(e.PutIndexTemplate(ctx, "logs", elastic.Template{IndexPatterns: []string{"logs-*"}, DataStream: true}))
+	r, _ := tr.LastRequest("PUT", "/_index_template/logs")
+	is.Equal(string(r.Body), `{"index_patterns":["logs-*"],"data_stream":{},"template":{}}`)
+
+	is.NoErr(e.CreateDataStream(ctx, "logs-app"))
+	xs, err := e.GetDataStreams(ctx, "logs-*")
+	is.NoErr(err)
+	is.Equal(len(xs), 1)
+	is.Equal(xs[0].TimestampField.Name, "@timestamp")
+	is.Equal(xs[0].Indices[0].Name, ".ds-logs-app-2026.10.15-000001")
+	is.Equal(xs[0].Status, "GREEN")
+
+	// a write to a data stream must create the document
+	_, err = e.IndexDoc(ctx, "logs-app", "1", `{"@timestamp":"2026-10-15T00:00:00Z"}`, elastic.OpTypeCreate())
+	is.NoErr(err)
+	r, _ = tr.LastRequest("PUT", "/logs-app/_doc/1")
+	is.Equal(r.Query.Get("op_type"), "create")
+
+	b := e.NewBulkIndexer("logs-app", elastic.BulkDefaultAction("create"))
+	is.NoErr(b.Add(ctx, "", "", "a", `{"@timestamp":"2026-10-15T00:00:00Z"}`))
+	is.NoErr(b.Close(ctx))
+	r, _ = tr.LastRequest("POST", "/logs-app/_bulk")
+	is.Equal(string(r.Body), "{\"create\":{\"_id\":\"a\"}}\n{\"@timestamp\":\"2026-10-15T00:00:00Z\"}\n")
+
+	is.NoErr(e.DeleteDataStream(ctx, "logs-app"))
+}
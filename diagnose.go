@@ -0,0 +1,183 @@
+package elastic
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// diagnoseTimeout limits each network step of Diagnose
+const diagnoseTimeout = 5 * time.Second
+
+// Diagnosis steps, in the order they are run
+const (
+	StepDNS     = "dns"
+	StepTCP     = "tcp"
+	StepTLS     = "tls"
+	StepAuth    = "auth"
+	StepVersion = "version"
+)
+
+// DiagnosisStep is the outcome of one step of Diagnose. A step is skipped if an earlier step failed, or, for the tls
+// step, if the host does not use https.
+type DiagnosisStep struct {
+	Name    string
+	Err     error
+	Skipped bool
+}
+
+// Diagnosis breaks a connection check down into the layers that have to work for the client to talk to a host
+type Diagnosis struct {
+	Host    string
+	Addrs   []string // addresses the host name resolved to
+	Steps   []DiagnosisStep
+	Version string // server version number, if the version step succeeded
+}
+
+// Failed returns the first step that failed, or nil if every step that ran succeeded
+func (d *Diagnosis) Failed() *DiagnosisStep {
+	for i := range d.Steps {
+		if d.Steps[i].Err != nil {
+			return &d.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Diagnose checks the connection to the first configured host one layer at a time: that the host name resolves,
+// a TCP connection can be made, the TLS handshake succeeds, the credentials are accepted and the server reports an
+// Elasticsearch version. Where CheckOK only says that something is wrong, the Diagnosis says which layer failed.
+// Only a 401 or 403 response fails the auth step; any other failed request, eg a 503 or a cluster block, fails the
+// version step. The returned error is that of the first failed step, and the Diagnosis is always returned.
+func (c *Client) Diagnose(ctx context.Context) (*Diagnosis, error) {
+
+	hosts := c.pool.urls()
+	if len(hosts) == 0 {
+		return nil, errors.New("Diagnose - no hosts configured")
+	}
+	d := &Diagnosis{Host: hosts[0]}
+
+	u, err := url.Parse(d.Host)
+	if err != nil {
+		return d, errors.Wrap(err, "Diagnose")
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	failed := false
+	step := func(name string, fn func() error) {
+		s := DiagnosisStep{Name: name, Skipped: failed}
+		if !failed {
+			s.Err = fn()
+			failed = s.Err != nil
+		}
+		d.Steps = append(d.Steps, s)
+	}
+
+	step(StepDNS, func() error {
+		ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout)
+		defer cancel()
+		d.Addrs, err = net.DefaultResolver.LookupHost(ctx, u.Hostname())
+		return err
+	})
+
+	var conn net.Conn
+	step(StepTCP, func() error {
+		dialer := net.Dialer{Timeout: diagnoseTimeout}
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+		return err
+	})
+	if conn != nil {
+		defer conn.Close()
+	}
+
+	if u.Scheme == "https" {
+		step(StepTLS, func() error {
+			ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout)
+			defer cancel()
+			cfg := &tls.Config{}
+			if c.tlsConfig != nil {
+				cfg = c.tlsConfig.Clone()
+			}
+			cfg.ServerName = u.Hostname()
+			return tls.Client(conn, cfg).HandshakeContext(ctx)
+		})
+	} else {
+		d.Steps = append(d.Steps, DiagnosisStep{Name: StepTLS, Skipped: true})
+	}
+
+	var xb []byte
+	var reqErr error // from a request that got past auth
+	step(StepAuth, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", d.Host+"/", nil)
+		if err != nil {
+			return err
+		}
+		_, res, _, err := c.send(req, standardHeaders, false)
+		if isStatus(err, http.StatusUnauthorized) || isStatus(err, http.StatusForbidden) {
+			return err
+		}
+		if err != nil {
+			reqErr = err
+			return nil
+		}
+		xb = res.body
+		return nil
+	})
+
+	step(StepVersion, func() error {
+		if reqErr != nil {
+			return reqErr
+		}
+		si, err := parseServerInfo(xb)
+		d.Version = si.Version.Number
+		return err
+	})
+
+	if s := d.Failed(); s != nil {
+		return d, errors.Wrap(s.Err, "Diagnose - "+s.Name)
+	}
+	return d, nil
+}
+
+// HostHealth probes the cluster health endpoint of each configured host in parallel, bypassing failover, and returns
+// the outcome keyed by host url. A nil error means the host responded. Each probe times out after 5 seconds.
+func (c *Client) HostHealth(ctx context.Context) map[string]error {
+
+	type result struct {
+		host string
+		err  error
+	}
+	hosts := c.pool.urls()
+	ch := make(chan result, len(hosts))
+
+	for _, h := range hosts {
+		go func(host string) {
+			ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, "GET", host+uriHealth, nil)
+			if err == nil {
+				_, _, _, err = c.send(req, standardHeaders, false)
+			}
+			ch <- result{host, err}
+		}(h)
+	}
+
+	health := make(map[string]error, len(hosts))
+	for range hosts {
+		r := <-ch
+		health[r.host] = r.err
+	}
+
+	return health
+}
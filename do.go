@@ -0,0 +1,67 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Response is the response to a request made with Do
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// JSON unmarshals the response body into v
+func (r *Response) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Path joins segments into a request path for Do, escaping each one so that a segment containing "/", "?" or "#",
+// such as a document id, is sent as a single segment, eg Path("articles", "_doc", "a/b") is "/articles/_doc/a%2Fb"
+func Path(segments ...string) string {
+	var b strings.Builder
+	for _, s := range segments {
+		b.WriteByte('/')
+		b.WriteString(url.PathEscape(s))
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
+
+// Do sends a request for an API the client has no method for, eg
+//
+//	r, err := c.Do(ctx, "GET", elastic.Path("_nodes", "stats"), url.Values{"level": {"shards"}}, nil)
+//
+// Path is sent as it is, so should be built with Path where a segment may need escaping, and params are added to
+// the query string, after any already in path. The body, which may be nil, is sent as JSON. The request goes through
+// the same failover and retries as any other. A response with a non-2xx status is returned as an *Error, which holds
+// the status code and body. The body of a successful response is read in full, subject to WithMaxResponseSize; use
+// RawRequest to read a large response incrementally.
+func (c *Client) Do(ctx context.Context, method, path string, params url.Values, body io.Reader) (*Response, error) {
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if len(params) > 0 {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		path += sep + params.Encode()
+	}
+
+	res, err := c.roundTrip(ctx, method, path, body, standardHeaders, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "Do")
+	}
+	return &Response{StatusCode: res.status, Header: res.header, Body: res.body}, nil
+}
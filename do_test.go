@@ -0,0 +1,65 @@
+package elastic_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestDo(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var uri string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri = r.RequestURI
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"type":"resource_not_found_exception","reason":"no such thing"},"status":404}`))
+			return
+		}
+		w.Header().Set("Warning", `299 Elasticsearch "deprecated"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"_id":"a/b?c","result":"created"}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8))
+
+	is.Equal(elastic.Path("articles", "_doc", "a/b?c"), "/articles/_doc/a%2Fb%3Fc")
+	r, err := e.Do(ctx, "PUT", elastic.Path("articles", "_doc", "a/b?c"), map[string][]string{"refresh": {"true"}},
+		strings.NewReader(`{"title":"one"}`))
+	is.NoErr(err)
+	is.Equal(uri, "/articles/_doc/a%2Fb%3Fc?refresh=true")
+	is.Equal(r.StatusCode, http.StatusCreated)
+	is.Equal(r.Header.Get("Warning"), `299 Elasticsearch "deprecated"`)
+	var doc struct {
+		ID string `json:"_id"`
+	}
+	is.NoErr(r.JSON(&doc))
+	is.Equal(doc.ID, "a/b?c")
+
+	_, err = e.Do(ctx, "GET", "/_cat/indices?v", map[string][]string{"format": {"json"}}, nil)
+	is.NoErr(err)
+	is.Equal(uri, "/_cat/indices?v&format=json")
+
+	_, err = e.Do(ctx, "GET", "missing", nil, nil)
+	var ee *elastic.Error
+	is.True(errors.As(err, &ee))
+	is.Equal(ee.StatusCode, http.StatusNotFound)
+	is.Equal(ee.Reason, "no such thing")
+
+	// document ids are escaped by the document methods too
+	_, err = e.IndexDoc(ctx, "articles", "a/b?c", `{"title":"one"}`)
+	is.NoErr(err)
+	is.Equal(uri, "/articles/_doc/a%2Fb%3Fc")
+	_, err = e.UpdateDoc(ctx, "articles", "a/b?c", `{"title":"two"}`)
+	is.NoErr(err)
+	is.Equal(uri, "/articles/_update/a%2Fb%3Fc")
+}
@@ -1,25 +1,81 @@
 package elastic
 
 import (
-	"net/http"
-	"github.com/pkg/errors"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"encoding/json"
-	"strconv"
-	"io/ioutil"
 	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
-	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
 	uriHealth  = "/_cat/health?format=json"
-	uriIndices = "/_cat/indices?format=json"
+	uriIndices = "/_cat/indices?format=json&bytes=b&h=health,status,index,uuid,pri,rep,docs.count,docs.deleted,store.size,pri.store.size"
+
+	uriPendingTasks = "/_cat/pending_tasks?format=json&time=ms"
+	uriWriteQueue   = "/_cat/thread_pool/write?format=json&h=node_name,queue"
+
+	uriClusterHealth = "/_cluster/health"
+	uriClusterStats  = "/_cluster/stats"
 )
 
+// serverlessAPIVersion is the Elastic-Api-Version sent to Elastic serverless projects
+const serverlessAPIVersion = "2023-10-31"
+
+// defaultErrorBodyLimit is the most bytes of an error response read when looking for the error reason
+const defaultErrorBodyLimit = 64 << 10
+
 type Client struct {
-	url  string
+	pool *nodePool
 	user string
 	pass string
+	auth string // Authorization header value, for API key or bearer token authentication
+
+	gzip        bool
+	gzipMinSize int
+
+	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+	userAgent  string
+	tlsConfig  *tls.Config
+
+	sniffInterval time.Duration
+	done          chan struct{} // closed by Close
+	closeOnce     sync.Once
+
+	serverless      bool
+	errorBodyLimit  int64
+	maxResponseSize int64 // 0 for no limit
+
+	version    int32      // major version of the cluster, 0 until known and -1 if it could not be detected
+	openSearch int32      // 1 if the cluster is OpenSearch
+	detectMu   sync.Mutex // held while detecting the version
+
+	maxRetries      int
+	maxRetryTime    time.Duration
+	retryBackoff    time.Duration
+	retryBackoffMax time.Duration
+	conflictRetries int // retry_on_conflict for UpsertDoc and UpdateUpsert
+
+	hooks  []Hook
+	logger Logger
+
+	inFlight chan struct{} // holds a value for each request being sent, if limited by WithMaxInFlight
+	limiter  *tokenBucket  // limits the request rate, if set by WithRateLimit
 }
 
 type header struct {
@@ -27,202 +83,1060 @@ type header struct {
 	Value string
 }
 
+// Index is an index and its size, from _cat/indices. The counts and sizes are 0 for a closed index.
 type Index struct {
-	UUID   string `json:"uuid"`
-	Name   string `json:"index"`
-	Health string `json:"health"`
-	Status string `json:"status"`
-	Count  string `json:"docs.Count"`
-	Docs   int
+	UUID         string `json:"uuid"`
+	Name         string `json:"index"`
+	Health       string `json:"health"`
+	Status       string `json:"status"`
+	Count        string `json:"docs.count"` // Deprecated: use Docs
+	Docs         int
+	DocsDeleted  int64
+	Primaries    int
+	Replicas     int
+	StoreSize    int64 // bytes, including replicas
+	PriStoreSize int64 // bytes, primaries only
+}
+
+// DocResponse is the response from writing a single document. SeqNo and PrimaryTerm identify the write for
+// optimistic concurrency control of later writes to the document.
+type DocResponse struct {
+	Index       string `json:"_index"`
+	ID          string `json:"_id"`
+	Version     int64  `json:"_version"`
+	Result      string `json:"result"` // "created", "updated", "deleted" or "noop"
+	SeqNo       int64  `json:"_seq_no"`
+	PrimaryTerm int64  `json:"_primary_term"`
 }
 
 var standardHeaders = []header{
 	{Key: "Content-Type", Value: "application/json"},
 }
 
-// NewClient returns a pointer to a new client initialised with user and pass
-func NewClient(url, user, pass string) *Client {
-	return &Client{
-		url:  url,
-		user: user,
-		pass: pass,
+// NewClient returns a pointer to a new client for the cluster at url, configured by opts, eg
+//
+//	c := elastic.NewClient("https://localhost:9200", elastic.WithBasicAuth(user, pass), elastic.WithTimeout(time.Minute))
+func NewClient(url string, opts ...Option) *Client {
+	return NewClientWithHosts([]string{url}, opts...)
+}
+
+// NewClientWithHosts returns a pointer to a new client that spreads requests across several node urls. Each request
+// starts at the next live host in round-robin order and fails over to the following host when a node cannot be
+// reached or responds with a 5xx status. A node that cannot be reached is passed over until its cooldown has passed.
+func NewClientWithHosts(urls []string, opts ...Option) *Client {
+	c := &Client{
+		pool:           newNodePool(urls),
+		done:           make(chan struct{}),
+		errorBodyLimit: defaultErrorBodyLimit,
+
+		maxRetries:      defaultMaxRetries,
+		maxRetryTime:    defaultMaxRetryTime,
+		retryBackoff:    defaultRetryBackoff,
+		retryBackoffMax: defaultRetryBackoffMax,
+		conflictRetries: defaultConflictRetries,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+
+	// Copy the client so that options never modify one passed to WithHTTPClient
+	hc := http.Client{}
+	if c.httpClient != nil {
+		hc = *c.httpClient
+	}
+	if c.transport != nil {
+		hc.Transport = c.transport
 	}
+	if c.tlsConfig != nil {
+		rt := hc.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		// A custom RoundTripper is left to manage its own TLS
+		if t, ok := rt.(*http.Transport); ok {
+			t = t.Clone()
+			t.TLSClientConfig = c.tlsConfig
+			hc.Transport = t
+		}
+	}
+	if c.timeout > 0 {
+		hc.Timeout = c.timeout
+	}
+	c.httpClient = &hc
+
+	if c.sniffInterval > 0 {
+		go c.sniff(c.sniffInterval)
+	}
+
+	return c
 }
 
 // CheckOK tests the connection
-func (c *Client) CheckOK() error {
-	_, err := c.request("GET", c.url+uriHealth, nil, standardHeaders)
+func (c *Client) CheckOK(ctx context.Context) error {
+	u := uriHealth
+	if c.serverless {
+		u = "/" // serverless projects have no cluster health
+	}
+	_, err := c.request(ctx, "GET", u, nil, standardHeaders)
 	return err
 }
 
 // Indices returns a list of user-created elastic indices - all those that don't have a name starting with a dot.
-func (c *Client) Indices() ([]Index, error) {
+func (c *Client) Indices(ctx context.Context) ([]Index, error) {
 
-	xb, err := c.request("GET", c.url+uriIndices, nil, standardHeaders)
+	rows, err := c.cat(ctx, uriIndices)
 	if err != nil {
-		return nil, errors.Wrap(err, "NewRequest")
+		return nil, errors.Wrap(err, "Indices")
 	}
 
 	var xi []Index
-	err = json.Unmarshal(xb, &xi)
-	if err != nil {
-		return nil, errors.Wrap(err, "Unmarshal")
-	}
-
-	// Remove indices with . and set Docs int from Count string
-	var xi2 []Index
-	for _, v := range xi {
-		if v.Name[0] != '.' {
-			v.Docs, _ = strconv.Atoi(v.Count) // naughty!
-			xi2 = append(xi2, v)
+	var p catParser
+	for _, r := range rows {
+		name := r.str("index")
+		if strings.HasPrefix(name, ".") {
+			continue
 		}
+		xi = append(xi, Index{
+			UUID:         r.str("uuid"),
+			Name:         name,
+			Health:       r.str("health"),
+			Status:       r.str("status"),
+			Count:        r.str("docs.count"),
+			Docs:         p.int(r, "docs.count"),
+			DocsDeleted:  p.int64(r, "docs.deleted"),
+			Primaries:    p.int(r, "pri"),
+			Replicas:     p.int(r, "rep"),
+			StoreSize:    p.int64(r, "store.size"),
+			PriStoreSize: p.int64(r, "pri.store.size"),
+		})
+	}
+	if p.err != nil {
+		return nil, errors.Wrap(p.err, "Indices")
 	}
 
-	return xi2, nil
+	return xi, nil
 }
 
 // CreateIndex adds a new index, name must be lowercase
-func (c *Client) CreateIndex(name string) error {
+func (c *Client) CreateIndex(ctx context.Context, name string) error {
 	n := strings.ToLower(name)
-	_, err := c.request("PUT", c.url+"/"+n, nil, standardHeaders)
+	_, err := c.request(ctx, "PUT", "/"+n, nil, standardHeaders)
 	if err != nil {
 		return errors.Wrap(err, "CreateIndex")
 	}
 	return nil
 }
 
+// CreateIndexWithBody adds a new index with the settings, mappings and aliases in body. For example, to store
+// documents pre-sorted by timestamp, which speeds up range queries and lets sorted searches terminate early, set
+// index.sort.field and index.sort.order and map the sort field:
+//
+//	{
+//	  "settings": {"index": {"sort.field": ["timestamp"], "sort.order": ["desc"]}},
+//	  "mappings": {"properties": {"timestamp": {"type": "date"}}}
+//	}
+//
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-create-index.html
+func (c *Client) CreateIndexWithBody(ctx context.Context, name, body string) error {
+	n := strings.ToLower(name)
+	_, err := c.request(ctx, "PUT", "/"+n, strings.NewReader(body), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "CreateIndexWithBody")
+	}
+	return nil
+}
+
+// IndexDefinition is the settings and mapping of a new index, for CreateIndexWith
+type IndexDefinition struct {
+	Settings *IndexSettings `json:"settings,omitempty"`
+	Mappings *Mapping       `json:"mappings,omitempty"`
+}
+
+// CreateIndexWith adds a new index with the settings and mapping in def. Use CreateIndexWithBody for settings that
+// IndexSettings does not cover.
+func (c *Client) CreateIndexWith(ctx context.Context, name string, def IndexDefinition) error {
+	xb, err := json.Marshal(def)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+	if err := c.CreateIndexWithBody(ctx, name, string(xb)); err != nil {
+		return errors.Wrap(err, "CreateIndexWith")
+	}
+	return nil
+}
+
+// SortField is a field that an index is sorted by. Index sorting can only be set when an index is created and the
+// field must be mapped at the same time, so Type is the field's mapping type, eg "date" or "keyword". Order is "asc"
+// or "desc", and defaults to "asc".
+type SortField struct {
+	Field string
+	Type  string
+	Order string
+}
+
+// CreateSortedIndex adds a new index whose segments are sorted by the fields, in order, and maps the fields.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/index-modules-index-sorting.html
+func (c *Client) CreateSortedIndex(ctx context.Context, name string, sortFields []SortField) error {
+
+	if len(sortFields) == 0 {
+		return errors.New("CreateSortedIndex - at least one sort field must be specified")
+	}
+
+	var fields, orders []string
+	props := map[string]interface{}{}
+	for _, f := range sortFields {
+		if f.Field == "" || f.Type == "" {
+			return errors.New("CreateSortedIndex - sort fields need a field and type")
+		}
+		order := f.Order
+		if order == "" {
+			order = "asc"
+		}
+		fields = append(fields, f.Field)
+		orders = append(orders, order)
+		props[f.Field] = map[string]string{"type": f.Type}
+	}
+
+	body := map[string]interface{}{
+		"settings": map[string]interface{}{
+			"index": map[string]interface{}{"sort.field": fields, "sort.order": orders},
+		},
+		"mappings": map[string]interface{}{"properties": props},
+	}
+	xb, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	if err := c.CreateIndexWithBody(ctx, name, string(xb)); err != nil {
+		return errors.Wrap(err, "CreateSortedIndex")
+	}
+	return nil
+}
+
 // DeleteIndex deletes an index
-func (c *Client) DeleteIndex(name string) error {
+func (c *Client) DeleteIndex(ctx context.Context, name string) error {
 	n := strings.ToLower(name)
-	_, err := c.request("DELETE", c.url+"/"+n, nil, standardHeaders)
+	_, err := c.request(ctx, "DELETE", "/"+n, nil, standardHeaders)
 	if err != nil {
 		return errors.Wrap(err, "DeleteIndex")
 	}
 	return nil
 }
 
-// IndexDoc adds or updates a document in the specified index. If id is nil then a new record is created with an
-// automatically generated uuid, otherwise the doc is added with the specified id, or updated if the id exists.
-func (c *Client) IndexDoc(index, id, doc string) error {
-	u := c.url + "/" + strings.ToLower(index) + "/_doc/" + id
-	b := strings.NewReader(doc)
-	_, err := c.request("POST", u, b, standardHeaders)
+// IndexDoc adds or updates a document in the specified index. If id is empty then a new record is created with an
+// automatically generated id (POST /index/_doc), otherwise the doc is added with the specified id, or replaced if
+// the id exists (PUT /index/_doc/id). The response holds the id, which is the only way to learn a generated id, and
+// whether the document was "created" or "updated". If the index does not exist and auto-creation is disabled for it
+// the error is ErrIndexNotFound, so the caller knows to create the index first.
+func (c *Client) IndexDoc(ctx context.Context, index, id, doc string, opts ...RequestOption) (*DocResponse, error) {
+
+	method, u := "POST", "/"+strings.ToLower(index)+"/_doc"
+	if id != "" {
+		method, u = "PUT", u+"/"+url.PathEscape(id)
+	}
+	u = withOptions(u, opts)
+
+	xb, err := c.request(ctx, method, u, strings.NewReader(doc), standardHeaders)
+	if errors.Is(err, ErrIndexNotFound) {
+		return nil, errors.Wrap(ErrIndexNotFound, "IndexDoc - "+strings.ToLower(index))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "IndexDoc")
+	}
+
+	return docResponse(xb)
+}
+
+// docResponse parses the response from writing a single document
+func docResponse(xb []byte) (*DocResponse, error) {
+	var r DocResponse
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &r, nil
+}
+
+// IndexDocStruct is like IndexDoc but marshals v, eg a struct with json tags, as the document
+func (c *Client) IndexDocStruct(ctx context.Context, index, id string, v interface{}, opts ...RequestOption) (*DocResponse, error) {
+	doc, err := marshalDoc(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "IndexDocStruct")
+	}
+	return c.IndexDoc(ctx, index, id, doc, opts...)
+}
+
+// marshalDoc marshals v as a document, which must be a JSON object. A json.RawMessage is checked for validity.
+func marshalDoc(v interface{}) (string, error) {
+	xb, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.Wrap(err, "Marshal")
+	}
+	if len(xb) == 0 || xb[0] != '{' {
+		return "", errors.New("document must be a JSON object, got " + string(xb))
+	}
+	return string(xb), nil
+}
+
+// CloseIndex closes an index, blocking reads and writes and releasing most of the resources it holds. From
+// Elasticsearch 7.2 the request waits for the primary shards of the closed index to be ready, or for as many copies
+// as are set with WaitForActiveShards.
+func (c *Client) CloseIndex(ctx context.Context, name string, opts ...RequestOption) error {
+	u := withOptions("/"+strings.ToLower(name)+"/_close", opts)
+	_, err := c.request(ctx, "POST", u, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "CloseIndex")
+	}
+	return nil
+}
+
+// OpenIndex re-opens a closed index. The request waits for the primary shards to start, or for as many copies as
+// are set with WaitForActiveShards.
+func (c *Client) OpenIndex(ctx context.Context, name string, opts ...RequestOption) error {
+	u := withOptions("/"+strings.ToLower(name)+"/_open", opts)
+	_, err := c.request(ctx, "POST", u, nil, standardHeaders)
 	if err != nil {
-		return errors.Wrap(err, "IndexDoc")
+		return errors.Wrap(err, "OpenIndex")
+	}
+	return nil
+}
+
+// DeleteIndexIfExists deletes an index, treating an index that does not exist as already deleted, so can be used
+// for idempotent cleanup
+func (c *Client) DeleteIndexIfExists(ctx context.Context, name string) error {
+	n := strings.ToLower(name)
+	_, err := c.request(ctx, "DELETE", "/"+n+"?ignore_unavailable=true", nil, standardHeaders)
+	if err != nil && !isStatus(err, http.StatusNotFound) {
+		return errors.Wrap(err, "DeleteIndexIfExists")
 	}
 	return nil
 }
 
-// UpdateDoc updates one or more fields in an existing document.
+// IndexExists reports whether an index, alias or data stream called name exists
+func (c *Client) IndexExists(ctx context.Context, name string) (bool, error) {
+	ok, err := c.exists(ctx, "/"+strings.ToLower(name))
+	if err != nil {
+		return false, errors.Wrap(err, "IndexExists")
+	}
+	return ok, nil
+}
+
+// DocExists reports whether a document exists, without fetching it
+func (c *Client) DocExists(ctx context.Context, index, id string) (bool, error) {
+	if id == "" {
+		return false, errors.New("DocExists - id must be specified")
+	}
+	ok, err := c.exists(ctx, "/"+strings.ToLower(index)+"/_doc/"+url.PathEscape(id))
+	if err != nil {
+		return false, errors.Wrap(err, "DocExists")
+	}
+	return ok, nil
+}
+
+// exists makes a HEAD request to path, treating a 404 as false rather than an error
+func (c *Client) exists(ctx context.Context, path string) (bool, error) {
+	_, err := c.request(ctx, "HEAD", path, nil, standardHeaders)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateDoc updates one or more fields in an existing document. By default an update that would not change the
+// document is skipped, with a Result of "noop", use DetectNoop(false) to force a new version.
 // See: https://www.elastic.co/guide/en/elasticsearch/reference/current/_updating_documents.html
-func (c *Client) UpdateDoc(index, id, doc string) error {
+func (c *Client) UpdateDoc(ctx context.Context, index, id, doc string, opts ...RequestOption) (*DocResponse, error) {
 
 	if id == "" {
-		return errors.New("UpdateDoc - id must be specified")
+		return nil, errors.New("UpdateDoc - id must be specified")
 	}
 
-	body := `{"doc": ` + doc + `}`
+	o := newRequestOptions(opts)
+	body := `{"doc": ` + doc
+	if o.detectNoop != nil {
+		body += `, "detect_noop": ` + strconv.FormatBool(*o.detectNoop)
+	}
+	body += `}`
 
-	u := c.url + "/" + strings.ToLower(index) + "/_doc/" + id + "/_update"
+	u := o.path(c.updatePath(ctx, index, id))
 	b := strings.NewReader(body)
-	_, err := c.request("POST", u, b, standardHeaders)
+	xb, err := c.request(ctx, "POST", u, b, standardHeaders)
 	if err != nil {
-		return errors.Wrap(err, "UpdateDoc")
+		return nil, errors.Wrap(err, "UpdateDoc")
 	}
 
-	return nil
+	return docResponse(xb)
+}
+
+// UpdateDocStruct is like UpdateDoc but marshals v, eg a struct with json tags and omitempty on fields that should
+// be left unchanged, as the partial document
+func (c *Client) UpdateDocStruct(ctx context.Context, index, id string, v interface{}, opts ...RequestOption) (*DocResponse, error) {
+	doc, err := marshalDoc(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "UpdateDocStruct")
+	}
+	return c.UpdateDoc(ctx, index, id, doc, opts...)
+}
+
+// UpdateRequest is the body of an update for UpdateWith. Either Doc is merged into the document, or Script, eg
+// `ctx._source.views += params.n`, modifies it. If the document does not exist Upsert is indexed as a new document,
+// or with DocAsUpsert, Doc is; with ScriptedUpsert the script runs on an empty document, or on Upsert if set,
+// instead. RetryOnConflict sets how many times the update is re-applied on the shard when a concurrent change
+// conflicts with it.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-update.html
+type UpdateRequest struct {
+	Doc             interface{} `json:"doc,omitempty"`
+	Script          *Script     `json:"script,omitempty"`
+	Upsert          interface{} `json:"upsert,omitempty"`
+	DocAsUpsert     bool        `json:"doc_as_upsert,omitempty"`
+	ScriptedUpsert  bool        `json:"scripted_upsert,omitempty"`
+	DetectNoop      *bool       `json:"detect_noop,omitempty"`
+	RetryOnConflict int         `json:"-"`
+}
+
+// UpdateWith updates a document as described by r. As with UpdateDoc the Result in the response is "noop" if the
+// document was left unchanged, and "created" if it was upserted.
+func (c *Client) UpdateWith(ctx context.Context, index, id string, r UpdateRequest, opts ...RequestOption) (*DocResponse, error) {
+
+	if id == "" {
+		return nil, errors.New("UpdateWith - id must be specified")
+	}
+	if r.Doc == nil && r.Script == nil {
+		return nil, errors.New("UpdateWith - a doc or script must be specified")
+	}
+
+	xb, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	if r.RetryOnConflict > 0 {
+		opts = append(opts[:len(opts):len(opts)], func(o *requestOptions) {
+			o.params.Set("retry_on_conflict", strconv.Itoa(r.RetryOnConflict))
+		})
+	}
+	u := withOptions(c.updatePath(ctx, index, id), opts)
+	xb, err = c.request(ctx, "POST", u, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "UpdateWith")
+	}
+
+	return docResponse(xb)
+}
+
+// UpdateUpsert updates one or more fields in a document, creating the document from doc if it does not exist.
+// Concurrent updates to the same document can conflict, so retryOnConflict sets how many times Elasticsearch
+// re-applies the update on the shard when that happens, or 0 for the client's WithConflictRetries. If the conflict
+// persists through every retry the error satisfies errors.Is(err, ErrConflict). The Result in the response is
+// "created" if the document was created.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-update.html#doc_as_upsert
+func (c *Client) UpdateUpsert(ctx context.Context, index, id, doc string, retryOnConflict int, opts ...RequestOption) (*DocResponse, error) {
+
+	if id == "" {
+		return nil, errors.New("UpdateUpsert - id must be specified")
+	}
+
+	if retryOnConflict > 0 {
+		opts = append(opts[:len(opts):len(opts)], RetryOnConflict(retryOnConflict))
+	}
+	r, err := c.upsert(ctx, index, id, doc, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "UpdateUpsert")
+	}
+	return r, nil
+}
+
+// UpsertDoc merges doc, which is marshalled, eg a struct with json tags, into the document with id, creating the
+// document from doc if it does not exist. A concurrent change to the document is a version conflict, on which
+// Elasticsearch re-reads the document and applies the update again, 3 times by default, which can be changed for the
+// client with WithConflictRetries or for the call with RetryOnConflict. If the conflict persists through every retry
+// the error satisfies errors.Is(err, ErrConflict). The Result in the response is "created" if the document was
+// created.
+func (c *Client) UpsertDoc(ctx context.Context, index, id string, doc interface{}, opts ...RequestOption) (*DocResponse, error) {
+
+	if id == "" {
+		return nil, errors.New("UpsertDoc - id must be specified")
+	}
+	d, err := marshalDoc(doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "UpsertDoc")
+	}
+
+	r, err := c.upsert(ctx, index, id, d, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "UpsertDoc")
+	}
+	return r, nil
+}
+
+// upsert merges doc into the document with id, or creates it, retrying conflicts as set by RetryOnConflict or, if
+// not set, WithConflictRetries
+func (c *Client) upsert(ctx context.Context, index, id, doc string, opts []RequestOption) (*DocResponse, error) {
+
+	o := newRequestOptions(opts)
+	body := `{"doc": ` + doc + `, "doc_as_upsert": true`
+	if o.detectNoop != nil {
+		body += `, "detect_noop": ` + strconv.FormatBool(*o.detectNoop)
+	}
+	body += `}`
+	if o.params.Get("retry_on_conflict") == "" && c.conflictRetries > 0 {
+		o.params.Set("retry_on_conflict", strconv.Itoa(c.conflictRetries))
+	}
+
+	u := o.path(c.updatePath(ctx, index, id))
+	xb, err := c.request(ctx, "POST", u, strings.NewReader(body), standardHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	return docResponse(xb)
 }
 
 // DeleteDoc deletes a document from the specified index
-func (c *Client) DeleteDoc(index, id string) error {
+func (c *Client) DeleteDoc(ctx context.Context, index, id string, opts ...RequestOption) (*DocResponse, error) {
 
 	if id == "" {
-		return errors.New("UpdateDoc - id must be specified")
+		return nil, errors.New("DeleteDoc - id must be specified")
 	}
 
-	u := c.url + "/" + strings.ToLower(index) + "/_doc/" + id
-	_, err := c.request("DELETE", u, nil, standardHeaders)
+	u := withOptions("/"+strings.ToLower(index)+"/_doc/"+url.PathEscape(id), opts)
+	xb, err := c.request(ctx, "DELETE", u, nil, standardHeaders)
 	if err != nil {
-		return errors.Wrap(err, "DeleteDoc")
+		return nil, errors.Wrap(err, "DeleteDoc")
 	}
 
-	return nil
+	return docResponse(xb)
 }
 
 // QueryDoc looks up a doc in the specified index, by id
-func (c *Client) QueryDoc(index, id string) ([]byte, error) {
-	u := c.url + "/" + strings.ToLower(index) + "/_doc/" + id
-	xb, err := c.request("GET", u, nil, standardHeaders)
+func (c *Client) QueryDoc(ctx context.Context, index, id string, opts ...RequestOption) ([]byte, error) {
+	u := withOptions("/"+strings.ToLower(index)+"/_doc/"+url.PathEscape(id), opts)
+	xb, err := c.request(ctx, "GET", u, nil, standardHeaders)
 	if err != nil {
 		return nil, errors.Wrap(err, "QueryDoc")
 	}
 	return xb, nil
 }
 
-// Batch performs a set of actions specified in the document
-// The REST API endpoint /_bulk expects the body to be newline-delimited JSON (NDJSON) and
-// hence the Content-Type header to be application/x-ndjson
-// https://www.elastic.co/guide/en/elasticsearch/reference/6.2/docs-bulk.html
-func (c *Client) Batch(index, doc string) ([]byte, error) {
+// GetResult is the metadata of a document fetched by GetDoc
+type GetResult struct {
+	Index       string                   `json:"_index"`
+	ID          string                   `json:"_id"`
+	Version     int64                    `json:"_version"`
+	SeqNo       int64                    `json:"_seq_no"`
+	PrimaryTerm int64                    `json:"_primary_term"`
+	Found       bool                     `json:"found"`
+	Source      json.RawMessage          `json:"_source"`
+	Fields      map[string][]interface{} `json:"fields"` // stored fields, see StoredFields
+}
 
-	u := c.url + "/" + strings.ToLower(index) + "/_doc/_bulk"
+// GetDoc fetches a document by id and unmarshals its source into dest, which may be nil to fetch only the metadata.
+// If the document or index does not exist the error satisfies errors.Is(err, ErrNotFound).
+func (c *Client) GetDoc(ctx context.Context, index, id string, dest interface{}, opts ...RequestOption) (*GetResult, error) {
 
-	headers := []header{
-		{Key: "Content-Type", Value: "application/x-ndjson"},
+	if id == "" {
+		return nil, errors.New("GetDoc - id must be specified")
 	}
 
-	b := strings.NewReader(doc)
+	xb, err := c.QueryDoc(ctx, index, id, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetDoc")
+	}
 
-	xb, err := c.request("POST", u, b, headers)
+	var r GetResult
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	if dest != nil && len(r.Source) > 0 {
+		if err := json.Unmarshal(r.Source, dest); err != nil {
+			return nil, errors.Wrap(err, "Unmarshal")
+		}
+	}
+
+	return &r, nil
+}
+
+// MultiGet fetches many documents from index in one request. The results are in the order of ids, with Found false
+// for a document that does not exist; unmarshal each Source as required.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-multi-get.html
+func (c *Client) MultiGet(ctx context.Context, index string, ids []string, opts ...RequestOption) ([]GetResult, error) {
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(map[string][]string{"ids": ids})
 	if err != nil {
-		return nil, errors.Wrap(err, "Batch")
+		return nil, errors.Wrap(err, "Marshal")
 	}
 
-	return xb, nil
+	u := withOptions("/"+strings.ToLower(index)+"/_mget", opts)
+	xb, err := c.request(ctx, "POST", u, bytes.NewReader(body), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "MultiGet")
+	}
+
+	var r struct {
+		Docs []GetResult `json:"docs"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	return r.Docs, nil
+}
+
+// bulkPath returns the path of the bulk endpoint for index. Version 6 needs the document type in the path, which
+// later versions reject.
+func (c *Client) bulkPath(ctx context.Context, index string) string {
+	if c.typeless(ctx) {
+		return "/" + strings.ToLower(index) + "/_bulk"
+	}
+	return "/" + strings.ToLower(index) + "/_doc/_bulk"
+}
+
+// updatePath returns the path of the partial update endpoint for a document
+func (c *Client) updatePath(ctx context.Context, index, id string) string {
+	if c.typeless(ctx) {
+		return "/" + strings.ToLower(index) + "/_update/" + url.PathEscape(id)
+	}
+	return "/" + strings.ToLower(index) + "/_doc/" + url.PathEscape(id) + "/_update"
+}
+
+// Retry defaults, see WithMaxRetries, WithMaxRetryTime, WithRetryBackoff and WithConflictRetries
+const (
+	defaultMaxRetries      = 3
+	defaultMaxRetryTime    = 30 * time.Second
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryBackoffMax = 5 * time.Second
+	defaultConflictRetries = 3
+)
+
+// RawRequest sends a request with a JSON body, which may be nil, to path, eg "/_nodes/stats?level=shards", for APIs
+// the client has no method for, and returns the response body unread for the caller to decode and close. The
+// request goes through the same failover and retries as any other, and a response with a non-2xx status is returned
+// as an *Error.
+func (c *Client) RawRequest(ctx context.Context, method, path string, body io.Reader) (io.ReadCloser, error) {
+	rc, err := c.requestStream(ctx, method, path, body, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "RawRequest")
+	}
+	return rc, nil
+}
+
+// request makes a request against the configured hosts and returns the response body as a []byte. Hosts are tried
+// in turn, starting from the next live one in round-robin order, until one responds without a connection error or
+// 5xx status. A host that cannot be connected to is marked dead, see WithDeadNodeCooldown. Once every host has been
+// tried, requests that are likely to succeed later, eg a 429 or a connection reset, are retried with exponential
+// backoff, or after the delay in a Retry-After header, up to the retry limits.
+// A body that cannot be rewound is only ever sent once. A response body larger than the WithMaxResponseSize limit
+// fails with ErrResponseTooLarge.
+func (c *Client) request(ctx context.Context, method, path string, body io.Reader, headers []header) ([]byte, error) {
+	res, err := c.roundTrip(ctx, method, path, body, headers, false)
+	if err != nil {
+		return nil, err
+	}
+	return res.body, nil
+}
+
+// requestStream is like request but returns the response body unread, for the caller to decode incrementally and
+// close. Failover and retries apply until a node responds with a 2xx status.
+func (c *Client) requestStream(ctx context.Context, method, path string, body io.Reader, headers []header) (io.ReadCloser, error) {
+	res, err := c.roundTrip(ctx, method, path, body, headers, true)
+	if err != nil {
+		return nil, err
+	}
+	return res.stream, nil
+}
+
+// response is a successful response. Either body holds the body, read in full, or, for a streamed request, stream
+// is the unread body.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+	stream io.ReadCloser
+}
+
+// roundTrip sends a request as described for request, reading the response body unless stream is set
+func (c *Client) roundTrip(ctx context.Context, method, path string, body io.Reader, headers []header, stream bool) (*response, error) {
+
+	nodes := c.pool.order()
+	if len(nodes) == 0 {
+		return nil, errors.New("request - no hosts configured")
+	}
+
+	rewind := rewinder(body)
+	failovers, retries := 0, 0
+	start := time.Now()
+	next := 0 // index in nodes to look for the node of the next attempt from
+	var lastErr error
+
+	compress := c.gzip && body != nil
+	if n, ok := bodyLen(body); compress && ok && n < c.gzipMinSize {
+		compress = false
+	}
+	if compress {
+		headers = append(headers[:len(headers):len(headers)], header{Key: "Content-Encoding", Value: "gzip"})
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		if attempt > 0 && body != nil {
+			body = rewind()
+		}
+
+		release, err := c.throttle(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "request")
+		}
+		n := c.pool.pick(nodes, &next)
+		if n == nil {
+			release()
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, errors.Wrap(ErrCircuitOpen, "request")
+		}
+		rb := body
+		if compress {
+			rb = gzipReader(body) // its goroutine runs until the reader is read or closed
+		}
+		res, failover, err := c.attempt(ctx, method, n.url, path, rb, headers, attempt+1, stream)
+		release()
+		if err == nil {
+			c.pool.succeeded(n)
+			c.pool.markLive(n)
+			return res, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			c.pool.abandoned(n)
+			return nil, err
+		}
+		if failover {
+			c.pool.failed(n)
+		} else {
+			c.pool.succeeded(n) // the node responded, if with an error
+		}
+		if isTransport(err) {
+			c.pool.markDead(n)
+		}
+		if body != nil && rewind == nil {
+			return nil, err
+		}
+		if failover && failovers < len(nodes)-1 && resendable(method, err) {
+			failovers++
+			continue
+		}
+		if !retryable(method, err) || retries >= c.maxRetries {
+			return nil, err
+		}
+		d := c.backoff(retries, err)
+		if time.Since(start)+d > c.maxRetryTime {
+			return nil, err
+		}
+		retries++
+		if err := sleep(ctx, d); err != nil {
+			return nil, errors.Wrap(err, "request")
+		}
+	}
+}
+
+// retryable reports whether a failed request is worth repeating: the cluster is overloaded or a node unavailable,
+// or the connection failed. A connection that failed after the request may have been processed is only retried for
+// an idempotent method.
+func retryable(method string, err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		switch e.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var oe *net.OpError
+	if errors.As(err, &oe) && oe.Op == "dial" {
+		return true
+	}
+	return idempotent(method) && (isTransient(err) || isTransport(err))
+}
+
+// resendable reports whether a request that failed on one node can be sent to another. A request with a method that
+// is not idempotent, eg a POST that indexes a document with a generated id, may already have been carried out if
+// the connection failed after it was sent, or the node failed part way, so is only resent if it cannot have reached
+// the node, because the connection could not be made, or was turned away with a 502, 503 or 504.
+func resendable(method string, err error) bool {
+	if idempotent(method) {
+		return true
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		switch e.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+// isTransport reports whether err came from the transport rather than a response
+func isTransport(err error) bool {
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// backoff returns how long to wait before retry n (from 0) of a request that failed with err. A Retry-After on the
+// response is honoured, otherwise the delay doubles with each retry up to the maximum, with jitter so that clients
+// retrying together spread out.
+func (c *Client) backoff(n int, err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) && e.retryAfter > 0 {
+		return e.retryAfter
+	}
+	d := c.retryBackoff << uint(n)
+	if d <= 0 || d > c.retryBackoffMax {
+		d = c.retryBackoffMax
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter returns the delay in a Retry-After header, which is either a number of seconds or a date, or 0
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(v); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// idempotent reports whether a request with the method can safely be repeated
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// isTransient reports whether err is a transport error that is likely to succeed on a retry, such as a connection
+// reset by a busy node or a response cut short
+func isTransient(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		strings.Contains(err.Error(), "connection reset by peer")
 }
 
-// request makes a request and returns the response body as a []byte
-func (c *Client) request(method, url string, body io.Reader, headers []header) ([]byte, error) {
+// attempt sends a request to a single host, calling the hooks around it. For a streamed request the hooks are
+// called once the response headers are received.
+func (c *Client) attempt(ctx context.Context, method, host, path string, body io.Reader, headers []header, n int, stream bool) (*response, bool, error) {
 
-	req, err := http.NewRequest(method, url, body)
+	info := RequestInfo{Method: method, URL: host + path, Path: path, Attempt: n}
+	if len(c.hooks) > 0 {
+		info.Endpoint = Endpoint(path)
+		ctx = c.beforeRequest(ctx, info)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, info.URL, body)
 	if err != nil {
-		return nil, errors.Wrap(err, "request")
+		return nil, false, errors.Wrap(err, "request")
+	}
+	if len(c.hooks) == 0 {
+		_, res, failover, err := c.send(req, headers, stream)
+		return res, failover, err
+	}
+
+	var cb *countingBody
+	if req.Body != nil && req.ContentLength <= 0 {
+		cb = &countingBody{ReadCloser: req.Body}
+		req.Body = cb
+	}
+
+	start := time.Now()
+	status, res, failover, err := c.send(req, headers, stream)
+	info.Duration = time.Since(start)
+	info.StatusCode = status
+	info.Err = err
+	info.RequestBytes = req.ContentLength
+	if cb != nil {
+		info.RequestBytes = cb.n
+	}
+	if res != nil {
+		info.ResponseBytes = int64(len(res.body))
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		info.ResponseBytes = int64(len(e.Body))
+	}
+	c.afterRequest(ctx, info)
+
+	return res, failover, err
+}
+
+// send performs a single request and returns the response status and, if it was successful, the response. The bool
+// result reports whether the failure was a connection error or 5xx status, and so worth retrying against another
+// host. The body of a streamed response is left for the caller to read and close.
+func (c *Client) send(req *http.Request, headers []header, stream bool) (int, *response, bool, error) {
+
+	switch {
+	case c.auth != "":
+		req.Header.Set("Authorization", c.auth)
+	case c.user != "" || c.pass != "":
+		req.SetBasicAuth(c.user, c.pass)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
 	}
-	req.SetBasicAuth(c.user, c.pass)
 
 	for _, h := range headers {
 		req.Header.Add(h.Key, h.Value)
 	}
-	fmt.Println(req.Header)
+	if c.serverless {
+		req.Header.Set("Elastic-Api-Version", serverlessAPIVersion)
+	}
+	if c.gzip {
+		// Setting Accept-Encoding ourselves turns off the transport's own decompression, so responses are
+		// decompressed below
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	c.debugf("%s %s %v", req.Method, req.URL, redactedHeader(req.Header))
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, errors.Wrap(err, "request")
+		return 0, nil, true, errors.Wrap(err, "request")
 	}
-	if res.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(res.StatusCode) + " - " + errReason(res.Body))
+
+	rb := res.Body
+	if res.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			res.Body.Close()
+			return res.StatusCode, nil, false, errors.Wrap(err, "gzip")
+		}
+		rb = &gzipBody{Reader: zr, body: res.Body}
 	}
-	defer res.Body.Close()
 
-	return ioutil.ReadAll(res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer rb.Close()
+		e := readError(res.StatusCode, rb, c.errorBodyLimit)
+		e.retryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+		c.debugf("%s %s: %d %s", req.Method, req.URL, res.StatusCode, e.Body)
+		return res.StatusCode, nil, res.StatusCode >= 500, e
+	}
+
+	r := &response{status: res.StatusCode, header: res.Header}
+	if stream {
+		r.stream = rb
+		return res.StatusCode, r, false, nil
+	}
+	defer rb.Close()
+
+	max := c.maxResponseSize
+	if max > 0 && res.ContentLength > max && res.Header.Get("Content-Encoding") != "gzip" {
+		return res.StatusCode, nil, false, errors.Wrapf(ErrResponseTooLarge, "request - %d bytes", res.ContentLength)
+	}
+	var lr io.Reader = rb
+	if max > 0 {
+		lr = io.LimitReader(rb, max+1)
+	}
+	xb, err := ioutil.ReadAll(lr)
+	if err != nil {
+		return res.StatusCode, nil, false, errors.Wrap(err, "request")
+	}
+	if max > 0 && int64(len(xb)) > max {
+		return res.StatusCode, nil, false, errors.Wrapf(ErrResponseTooLarge, "request - over %d bytes", max)
+	}
+	r.body = xb
+	return res.StatusCode, r, false, nil
 }
 
-// errReason extracts the error reason message from a response body
-func errReason(body io.Reader) string {
+// gzipBody is a decompressed response body. Closing it closes the response body too.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
 
-	xb, _ := ioutil.ReadAll(body)
-	fmt.Println(string(xb))
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
 
-	var r = struct {
-		Error struct {
-			Reason string `json:"reason"`
-		} `json:"error"`
-	}{}
+// rewinder returns a function that yields a fresh copy of body, so it can be sent again, or nil if body is of a
+// type that can only be read once
+func rewinder(body io.Reader) func() io.Reader {
+	switch v := body.(type) {
+	case *strings.Reader:
+		snapshot := *v
+		return func() io.Reader {
+			r := snapshot
+			return &r
+		}
+	case *bytes.Reader:
+		snapshot := *v
+		return func() io.Reader {
+			r := snapshot
+			return &r
+		}
+	case *bytes.Buffer:
+		xb := v.Bytes()
+		return func() io.Reader {
+			return bytes.NewReader(xb)
+		}
+	}
+	return nil
+}
 
-	err := json.NewDecoder(body).Decode(&r)
-	if err != nil {
-		return err.Error()
+// bodyLen returns the length of body if it is of a type whose length is known up front
+func bodyLen(body io.Reader) (int, bool) {
+	switch v := body.(type) {
+	case *strings.Reader:
+		return v.Len(), true
+	case *bytes.Reader:
+		return v.Len(), true
+	case *bytes.Buffer:
+		return v.Len(), true
 	}
+	return 0, false
+}
 
-	return r.Error.Reason
+// gzipReader returns a reader of the gzip-compressed contents of r. Compression happens as the returned reader is
+// consumed so the body is never held in memory in full.
+func gzipReader(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, r)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
 }
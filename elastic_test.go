@@ -1,26 +1,41 @@
 package elastic_test
 
 import (
-	"testing"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
 
 	"github.com/matryer/is"
 	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/elastictest"
 )
 
 const (
-	url = "http://dummy.host.com"
+	url  = "http://dummy.host.com"
 	user = "dummyUser"
 	pass = "dummyPass"
 )
 
 var mockResponseJSON = map[string][]byte{
-	"health":   {},
+	"health":  {},
 	"indices": {},
 }
 
-
 func init() {
 
 	for i := range mockResponseJSON {
@@ -35,10 +50,1345 @@ func init() {
 
 func TestIndices(t *testing.T) {
 	is := is.New(t)
-	e := elastic.NewClient(url, user, pass)
-	e.Indices()
+	ctx := context.Background()
+
+	tr := elastictest.NewTransport()
+	tr.Handle("GET", "/_cat/indices", http.StatusOK, string(mockResponseJSON["indices"]))
+	e := elastictest.NewClient(tr, elastic.WithBasicAuth(user, pass))
+
+	xi, err := e.Indices(ctx)
+	is.NoErr(err)
 	// Expect 2 indices, named articles and resources
-	is.Equal(1,1) // Not equal
+	is.Equal(len(xi), 2)
+	is.Equal(xi[0].Name, "resources")
+	is.Equal(xi[1].Name, "articles")
+	is.Equal(xi[1].Docs, 3)
+	is.Equal(xi[1].StoreSize, int64(31027))
+}
+
+func TestFailover(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	var hits int
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write(mockResponseJSON["health"])
+	}))
+	defer up.Close()
+
+	e := elastic.NewClientWithHosts([]string{down.URL, up.URL}, elastic.WithBasicAuth(user, pass))
+	for i := 0; i < 4; i++ {
+		is.NoErr(e.CheckOK(ctx)) // every call should reach the healthy host
+	}
+	is.Equal(hits, 4)
+}
+
+func TestFailoverNotIdempotent(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	// the first node reads the request then drops the connection, so it may have indexed the document
+	var dropped, hits int32
+	drop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&dropped, 1)
+		ioutil.ReadAll(r.Body)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		is.NoErr(err)
+		conn.Close()
+	}))
+	defer drop.Close()
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(`{"_index":"a","_id":"x1","result":"created"}`))
+	}))
+	defer up.Close()
+
+	e := elastic.NewClientWithHosts([]string{drop.URL, up.URL}, elastic.WithBasicAuth(user, pass))
+	_, err := e.IndexDoc(ctx, "a", "", `{"title":"one"}`)
+	is.True(err != nil)
+	is.Equal(atomic.LoadInt32(&dropped), int32(1))
+	is.Equal(atomic.LoadInt32(&hits), int32(0)) // not sent again, which would index a duplicate
+
+	// an idempotent request fails over
+	_, err = e.IndexDoc(ctx, "a", "1", `{"title":"one"}`)
+	is.NoErr(err)
+	is.Equal(atomic.LoadInt32(&hits), int32(1))
+}
+
+// mockServer returns a test server that responds to "METHOD /path" with the matching body, or 404 if there is none
+func mockServer(routes map[string][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(xb)
+	}))
+}
+
+// fixture returns the contents of a file in testdata
+func fixture(name string) []byte {
+	xb, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		log.Fatalf("Cannot read %s\n", name)
+	}
+	return xb
 }
 
+func TestGzip(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	const doc = `{"index":{"_id":"1"}}
+{"title":"one"}
+`
+	const resp = `{"took":3,"errors":false,"items":[]}`
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.Header.Get("Content-Encoding"), "gzip")
+		is.Equal(r.Header.Get("Accept-Encoding"), "gzip")
+
+		zr, err := gzip.NewReader(r.Body) // body must be gzip-framed
+		is.NoErr(err)
+		xb, err := ioutil.ReadAll(zr)
+		is.NoErr(err)
+		is.Equal(string(xb), doc)
+
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(resp))
+		zw.Close()
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(6), elastic.WithGzip())
+	r, err := e.Batch(ctx, "articles", doc)
+	is.NoErr(err)
+	is.Equal(r.Took, int64(3)) // response was decompressed and parsed
+}
+
+func TestGzipMinSize(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	encodings := map[string]string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings[r.URL.Path] = r.Header.Get("Content-Encoding")
+		is.Equal(r.Header.Get("Accept-Encoding"), "gzip") // responses are always accepted compressed
+		w.Write([]byte(`{"took":1,"errors":false,"items":[],"hits":{"hits":[]}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(6), elastic.WithGzipMinSize(1024))
+	_, err := e.Search(ctx, "articles", `{"query":{"match_all":{}}}`)
+	is.NoErr(err)
+	_, err = e.Batch(ctx, "articles", strings.Repeat(`{"index":{}}`+"\n"+`{"title":"one"}`+"\n", 100))
+	is.NoErr(err)
+
+	is.Equal(encodings["/articles/_search"], "")        // too small to be worth it
+	is.Equal(encodings["/articles/_doc/_bulk"], "gzip") // large
+}
+
+func TestErrorBodyLimit(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	// A huge error body whose reason appears before the bulk of it
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"type":"illegal_argument_exception","reason":"bad things","detail":"`))
+		w.Write(bytes.Repeat([]byte("x"), 1<<20))
+		w.Write([]byte(`"}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithErrorBodyLimit(1024))
+	err := e.CheckOK(ctx)
+	is.True(err != nil)
+	is.Equal(err.Error(), "Bad Request - bad things")
+}
+
+func TestBatchReader(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	const doc = `{"index":{"_id":"1"}}
+{"title":"one"}
+`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Path, "/articles/_doc/_bulk")
+		is.Equal(r.Header.Get("Content-Type"), "application/x-ndjson")
+		xb, _ := ioutil.ReadAll(r.Body)
+		is.Equal(string(xb), doc)
+		w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(6))
+	pr, pw := io.Pipe() // a reader that can only be consumed once, as with a file
+	go func() {
+		io.WriteString(pw, doc)
+		pw.Close()
+	}()
+	_, err := e.BatchReader(ctx, "articles", pr)
+	is.NoErr(err)
+}
+
+func TestIndexDoc(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var method, path string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		switch {
+		case r.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"_index":"articles","_id":"Xc3mZ2QBxhgdcL5KNcvS","result":"created"}`))
+		case r.URL.Path == "/articles/_doc/42":
+			w.Write([]byte(`{"_index":"articles","_id":"42","result":"updated"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"mapper_parsing_exception","reason":"failed to parse"},"status":400}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+
+	// Explicit id
+	r, err := e.IndexDoc(ctx, "Articles", "42", `{"title":"one"}`)
+	is.NoErr(err)
+	is.Equal(method, "PUT")
+	is.Equal(path, "/articles/_doc/42")
+	is.Equal(*r, elastic.DocResponse{Index: "articles", ID: "42", Result: "updated"})
+
+	// Auto-generated id, no trailing slash
+	r, err = e.IndexDoc(ctx, "articles", "", `{"title":"two"}`)
+	is.NoErr(err)
+	is.Equal(method, "POST")
+	is.Equal(path, "/articles/_doc")
+	is.Equal(*r, elastic.DocResponse{Index: "articles", ID: "Xc3mZ2QBxhgdcL5KNcvS", Result: "created"})
+
+	// The error carries the reason from Elasticsearch
+	_, err = e.IndexDoc(ctx, "articles", "bad", `{"title":`)
+	is.True(err != nil)
+	is.True(strings.HasSuffix(err.Error(), "Bad Request - failed to parse"))
+}
+
+func TestIndexDocStruct(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		body = string(xb)
+		w.Write([]byte(`{"_index":"articles","_id":"42","_version":3,"result":"updated"}`))
+	}))
+	defer s.Close()
+
+	type article struct {
+		Title string `json:"title"`
+		Views int    `json:"views,omitempty"`
+	}
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.IndexDocStruct(ctx, "articles", "42", article{Title: "one"})
+	is.NoErr(err)
+	is.Equal(body, `{"title":"one"}`)
+	is.Equal(r.Version, int64(3))
+
+	_, err = e.UpdateDocStruct(ctx, "articles", "42", article{Views: 7})
+	is.NoErr(err)
+	is.Equal(body, `{"doc": {"title":"","views":7}}`)
+
+	_, err = e.IndexDocStruct(ctx, "articles", "42", json.RawMessage(`{"title":`))
+	is.True(err != nil) // invalid JSON
+	_, err = e.IndexDocStruct(ctx, "articles", "42", []string{"one"})
+	is.True(err != nil) // not an object
+}
+
+func TestDeleteIndexIfExists(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"type":"index_not_found_exception","reason":"no such index [gone]"},"status":404}`))
+		case "/locked":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"type":"cluster_block_exception","reason":"blocked"},"status":403}`))
+		default:
+			w.Write([]byte(`{"acknowledged":true}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.DeleteIndexIfExists(ctx, "articles"))
+	is.NoErr(e.DeleteIndexIfExists(ctx, "gone"))         // missing is fine
+	is.True(e.DeleteIndexIfExists(ctx, "locked") != nil) // other errors are not
+	is.True(e.DeleteIndex(ctx, "gone") != nil)           // DeleteIndex is unchanged
+}
+
+func TestRetryTransient(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var hits int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			// Promise a body then drop the connection part way through
+			conn, buf, _ := w.(http.Hijacker).Hijack()
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 1000\r\n\r\n[{\"status\":")
+			buf.Flush()
+			conn.Close()
+			return
+		}
+		w.Write(mockResponseJSON["health"])
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.CheckOK(ctx)) // GET is retried
+	is.Equal(hits, 2)
+}
+
+func TestServerless(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var paths []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.Header.Get("Elastic-Api-Version"), "2023-10-31")
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithServerless())
+	is.NoErr(e.CheckOK(ctx))
+	_, err := e.Batch(ctx, "articles", "{}\n")
+	is.NoErr(err)
+	_, err = e.UpdateDoc(ctx, "articles", "1", `{"title":"one"}`)
+	is.NoErr(err)
+	is.Equal(paths, []string{"GET /", "POST /articles/_bulk", "POST /articles/_update/1"})
+}
+
+func TestUpdateUpsert(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var conflict bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Path, "/articles/_doc/1/_update")
+		is.Equal(r.URL.Query().Get("retry_on_conflict"), "3")
+		is.Equal(r.URL.Query().Get("refresh"), "wait_for")
+		xb, _ := ioutil.ReadAll(r.Body)
+		is.Equal(string(xb), `{"doc": {"views":1}, "doc_as_upsert": true}`)
+		if conflict {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":{"type":"version_conflict_engine_exception","reason":"[1]: version conflict"},"status":409}`))
+			return
+		}
+		w.Write([]byte(`{"_index":"articles","_id":"1","result":"created"}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(6))
+	r, err := e.UpdateUpsert(ctx, "articles", "1", `{"views":1}`, 3, elastic.Refresh("wait_for"))
+	is.NoErr(err)
+	is.Equal(r.Result, "created")
+
+	conflict = true
+	_, err = e.UpdateUpsert(ctx, "articles", "1", `{"views":1}`, 3, elastic.Refresh("wait_for"))
+	is.True(errors.Is(err, elastic.ErrConflict))
+
+	// without retryOnConflict the client's WithConflictRetries applies, 3 by default
+	conflict = false
+	_, err = e.UpdateUpsert(ctx, "articles", "1", `{"views":1}`, 0, elastic.Refresh("wait_for"))
+	is.NoErr(err)
+}
+
+func TestUpdateUpsertConflictRetries(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var query, body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		query, body = r.URL.RawQuery, string(xb)
+		w.Write([]byte(`{"_index":"articles","_id":"1","result":"updated"}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8),
+		elastic.WithConflictRetries(5))
+	r, err := e.UpdateUpsert(ctx, "articles", "1", `{"views":1}`, 0, elastic.DetectNoop(false))
+	is.NoErr(err)
+	is.Equal(r.Result, "updated")
+	is.Equal(query, "retry_on_conflict=5")
+	is.Equal(body, `{"doc": {"views":1}, "doc_as_upsert": true, "detect_noop": false}`)
+	_, err = e.UpdateUpsert(ctx, "articles", "1", `{"views":1}`, 2)
+	is.NoErr(err)
+	is.Equal(query, "retry_on_conflict=2")
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var hits int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/chunked/") {
+			w.(http.Flusher).Flush() // no Content-Length, so the limit is found by reading
+		}
+		w.Write([]byte(`{"_index":"articles","_id":"1","found":true,"_source":{"title":"a long title"}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithMaxResponseSize(32))
+	_, err := e.QueryDoc(ctx, "articles", "1")
+	is.True(errors.Is(err, elastic.ErrResponseTooLarge))
+	is.Equal(hits, 1) // not retried
+	_, err = e.QueryDoc(ctx, "chunked", "1")
+	is.True(errors.Is(err, elastic.ErrResponseTooLarge))
+
+	rc, err := e.RawRequest(ctx, "GET", "/chunked/_doc/1", nil)
+	is.NoErr(err)
+	xb, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.NoErr(rc.Close())
+	is.True(len(xb) > 32) // streamed responses are not limited
+
+	e = elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithMaxResponseSize(1024))
+	_, err = e.QueryDoc(ctx, "chunked", "1")
+	is.NoErr(err)
+}
+
+func TestUpsertDoc(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var query, body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Path, "/articles/_update/1")
+		xb, _ := ioutil.ReadAll(r.Body)
+		query, body = r.URL.RawQuery, string(xb)
+		if r.URL.Query().Get("retry_on_conflict") == "0" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":{"type":"version_conflict_engine_exception","reason":"[1]: version conflict"},"status":409}`))
+			return
+		}
+		w.Write([]byte(`{"_index":"articles","_id":"1","_version":1,"result":"created"}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(7))
+	doc := struct {
+		Views int `json:"views"`
+	}{1}
+	r, err := e.UpsertDoc(ctx, "articles", "1", doc)
+	is.NoErr(err)
+	is.Equal(r.Result, "created")
+	is.Equal(query, "retry_on_conflict=3") // the default
+	is.Equal(body, `{"doc": {"views":1}, "doc_as_upsert": true}`)
+
+	e = elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(7), elastic.WithConflictRetries(5))
+	_, err = e.UpsertDoc(ctx, "articles", "1", doc)
+	is.NoErr(err)
+	is.Equal(query, "retry_on_conflict=5")
+
+	_, err = e.UpsertDoc(ctx, "articles", "1", doc, elastic.RetryOnConflict(0))
+	is.True(errors.Is(err, elastic.ErrConflict))
+
+	_, err = e.UpsertDoc(ctx, "articles", "1", "not an object")
+	is.True(err != nil)
+}
+
+func TestUpdateWith(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var query, body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		query, body = r.URL.RawQuery, string(xb)
+		w.Write([]byte(`{"_index":"articles","_id":"1","_version":3,"result":"updated"}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.UpdateWith(ctx, "articles", "1", elastic.UpdateRequest{
+		Script: &elastic.Script{
+			Source: "ctx._source.views += params.n",
+			Lang:   "painless",
+			Params: map[string]interface{}{"n": 1},
+		},
+		Upsert:          map[string]int{"views": 1},
+		RetryOnConflict: 3,
+	}, elastic.Refresh("wait_for"))
+	is.NoErr(err)
+	is.Equal(r.Result, "updated")
+	is.Equal(query, "refresh=wait_for&retry_on_conflict=3")
+	is.Equal(body, `{"script":{"source":"ctx._source.views += params.n","lang":"painless","params":{"n":1}},`+
+		`"upsert":{"views":1}}`)
+
+	_, err = e.UpdateWith(ctx, "articles", "1", elastic.UpdateRequest{Doc: map[string]string{"title": "one"}, DocAsUpsert: true})
+	is.NoErr(err)
+	is.Equal(query, "")
+	is.Equal(body, `{"doc":{"title":"one"},"doc_as_upsert":true}`)
+
+	_, err = e.UpdateWith(ctx, "articles", "1", elastic.UpdateRequest{Upsert: map[string]int{}})
+	is.True(err != nil) // nothing to update with
+}
+
+func TestRequireAlias(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(7))
+	_, err := e.IndexDoc(ctx, "live", "1", `{}`, elastic.RequireAlias())
+	is.NoErr(err)
+	_, err = e.Batch(ctx, "live", "{}\n", elastic.RequireAlias())
+	is.NoErr(err)
+	_, err = e.IndexDoc(ctx, "live", "1", `{}`)
+	is.NoErr(err)
+	is.Equal(queries, []string{"require_alias=true", "require_alias=true", ""})
+}
+
+func TestWriteOptions(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(7))
+	_, err := e.IndexDoc(ctx, "articles", "1", `{}`, elastic.Refresh("wait_for"), elastic.Routing("user1"))
+	is.NoErr(err)
+	_, err = e.UpdateDoc(ctx, "articles", "1", `{}`, elastic.Routing("user1"), elastic.WaitForActiveShards("all"))
+	is.NoErr(err)
+	_, err = e.DeleteDoc(ctx, "articles", "1", elastic.Refresh("true"), elastic.Routing("user1"))
+	is.NoErr(err)
+	_, err = e.Batch(ctx, "articles", "{}\n", elastic.Refresh("wait_for"), elastic.WaitForActiveShards("2"))
+	is.NoErr(err)
+	is.Equal(queries, []string{
+		"refresh=wait_for&routing=user1",
+		"routing=user1&wait_for_active_shards=all",
+		"refresh=true&routing=user1",
+		"refresh=wait_for&wait_for_active_shards=2",
+	})
+}
+
+func TestPreference(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`{"_index":"articles","_id":"1","found":true,"_source":{}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8))
+	_, err := e.QueryDoc(ctx, "articles", "1", elastic.Preference("_local"))
+	is.NoErr(err)
+	var doc map[string]interface{}
+	_, err = e.GetDoc(ctx, "articles", "1", &doc, elastic.Preference("session-42"))
+	is.NoErr(err)
+	is.Equal(queries, []string{"preference=_local", "preference=session-42"})
+}
+
+func TestIndexDocMissingIndex(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"type":"index_not_found_exception",` +
+			`"reason":"no such index [articles] and [action.auto_create_index] is [false]"},"status":404}`))
+	}))
+	defer s.Close()
+
+	_, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).IndexDoc(ctx, "articles", "1", `{}`)
+	is.True(errors.Is(err, elastic.ErrIndexNotFound))
+	is.Equal(err.Error(), "IndexDoc - articles: index does not exist")
+}
+
+func TestCreateSortedIndex(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.Method+" "+r.URL.Path, "PUT /events")
+		xb, _ := ioutil.ReadAll(r.Body)
+		is.Equal(string(xb), `{"mappings":{"properties":{"host":{"type":"keyword"},"timestamp":{"type":"date"}}},`+
+			`"settings":{"index":{"sort.field":["timestamp","host"],"sort.order":["desc","asc"]}}}`)
+		w.Write([]byte(`{"acknowledged":true}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.CreateSortedIndex(ctx, "events", []elastic.SortField{
+		{Field: "timestamp", Type: "date", Order: "desc"},
+		{Field: "host", Type: "keyword"},
+	}))
+}
+
+func TestUpdateDocDetectNoop(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		body = string(xb)
+		w.Write([]byte(`{"result":"updated"}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	_, err := e.UpdateDoc(ctx, "articles", "1", `{"views":1}`)
+	is.NoErr(err)
+	is.Equal(body, `{"doc": {"views":1}}`)
+	_, err = e.UpdateDoc(ctx, "articles", "1", `{"views":1}`, elastic.DetectNoop(false))
+	is.NoErr(err)
+	is.Equal(body, `{"doc": {"views":1}, "detect_noop": false}`)
+}
+
+func TestDocResponses(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"POST /articles/_doc/1/_update": []byte(`{"_index":"articles","_id":"1","_version":4,"result":"updated",` +
+			`"_seq_no":12,"_primary_term":2}`),
+		"DELETE /articles/_doc/1": []byte(`{"_index":"articles","_id":"1","_version":5,"result":"deleted",` +
+			`"_seq_no":13,"_primary_term":2}`),
+	})
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(6))
+	r, err := e.UpdateDoc(ctx, "articles", "1", `{"views":1}`)
+	is.NoErr(err)
+	is.Equal(*r, elastic.DocResponse{Index: "articles", ID: "1", Version: 4, Result: "updated", SeqNo: 12, PrimaryTerm: 2})
+
+	r, err = e.DeleteDoc(ctx, "articles", "1")
+	is.NoErr(err)
+	is.Equal(*r, elastic.DocResponse{Index: "articles", ID: "1", Version: 5, Result: "deleted", SeqNo: 13, PrimaryTerm: 2})
+}
+
+func TestContextCancel(t *testing.T) {
+	is := is.New(t)
+
+	hits := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e := elastic.NewClientWithHosts([]string{s.URL, s.URL}, elastic.WithBasicAuth(user, pass))
+	err := e.CheckOK(ctx)
+	is.True(errors.Is(err, context.Canceled)) // cancellation is surfaced
+	is.Equal(hits, 0)                         // and nothing is retried
+}
+
+func TestRetryBackoff(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var hits int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch hits {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}`))
+			return
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer s.Close()
 
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(7), elastic.WithRetryBackoff(time.Millisecond, 10*time.Millisecond))
+	_, err := e.Batch(ctx, "articles", "{}\n")
+	is.NoErr(err) // POST is retried on 429 and 503
+	is.Equal(hits, 3)
+
+	hits = 0
+	e = elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(7), elastic.WithMaxRetries(0))
+	_, err = e.Batch(ctx, "articles", "{}\n")
+	is.True(err != nil) // retries turned off
+	is.Equal(hits, 1)
+}
+
+func TestRetryAfter(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var times []time.Time
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		if len(times) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write(mockResponseJSON["health"])
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithRetryBackoff(time.Millisecond, time.Millisecond))
+	is.NoErr(e.CheckOK(ctx))
+	is.Equal(len(times), 2)
+	is.True(times[1].Sub(times[0]) >= time.Second) // waited as long as the server asked
+
+	times = nil
+	e = elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithMaxRetryTime(100*time.Millisecond))
+	is.True(e.CheckOK(ctx) != nil) // Retry-After exceeds the time allowed
+	is.Equal(len(times), 1)
+}
+
+type countingTransport struct {
+	n int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.n++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestClientOptions(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		is.True(ok)
+		is.Equal(u+":"+p, "elastic:secret")
+		is.Equal(r.UserAgent(), "indexer/1.0")
+		if r.URL.Path == "/slow/_doc/1" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write(mockResponseJSON["health"])
+	}))
+	defer s.Close()
+
+	hc := &http.Client{}
+	rt := &countingTransport{}
+	e := elastic.NewClient(s.URL,
+		elastic.WithBasicAuth("elastic", "secret"),
+		elastic.WithHTTPClient(hc),
+		elastic.WithTransport(rt),
+		elastic.WithUserAgent("indexer/1.0"),
+		elastic.WithTimeout(50*time.Millisecond),
+		elastic.WithMaxRetries(0),
+	)
+	is.NoErr(e.CheckOK(ctx))
+	is.Equal(rt.n, 1)            // sent with the transport
+	is.True(hc.Transport == nil) // without modifying the client passed in
+
+	_, err := e.QueryDoc(ctx, "slow", "1")
+	is.True(err != nil) // timed out
+}
+
+func TestTokenAuth(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var auth string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write(mockResponseJSON["health"])
+	}))
+	defer s.Close()
+
+	is.NoErr(elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithAPIKey("aWQ6a2V5")).CheckOK(ctx))
+	is.Equal(auth, "ApiKey aWQ6a2V5") // replaces basic auth
+
+	is.NoErr(elastic.NewClient(s.URL, elastic.WithBearerToken("dGVzdA==")).CheckOK(ctx))
+	is.Equal(auth, "Bearer dGVzdA==")
+
+	is.NoErr(elastic.NewClient(s.URL).CheckOK(ctx))
+	is.Equal(auth, "") // no credentials
+}
+
+func TestTLS(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(mockResponseJSON["health"])
+	}))
+	defer s.Close()
+
+	f, err := ioutil.TempFile("", "ca*.pem")
+	is.NoErr(err)
+	defer os.Remove(f.Name())
+	is.NoErr(pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}))
+	f.Close()
+
+	is.True(elastic.NewClient(s.URL, elastic.WithMaxRetries(0)).CheckOK(ctx) != nil) // unknown authority
+
+	pool, err := elastic.LoadCACerts(f.Name())
+	is.NoErr(err)
+	is.NoErr(elastic.NewClient(s.URL, elastic.WithRootCAs(pool)).CheckOK(ctx))
+	is.NoErr(elastic.NewClient(s.URL, elastic.WithInsecureSkipVerify()).CheckOK(ctx))
+
+	_, err = elastic.LoadCACerts("testdata/health.json")
+	is.True(err != nil) // not PEM
+}
+
+func TestDeadNode(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var badHits int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&badHits, 1)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer bad.Close()
+	good := mockServer(map[string][]byte{
+		"GET /_cat/health": mockResponseJSON["health"],
+	})
+	defer good.Close()
+
+	e := elastic.NewClient(bad.URL, elastic.WithHosts(good.URL), elastic.WithDeadNodeCooldown(100*time.Millisecond))
+	for i := 0; i < 4; i++ {
+		is.NoErr(e.CheckOK(ctx))
+	}
+	is.Equal(atomic.LoadInt32(&badHits), int32(1)) // passed over once dead
+
+	time.Sleep(150 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		is.NoErr(e.CheckOK(ctx))
+	}
+	is.Equal(atomic.LoadInt32(&badHits), int32(2)) // tried again after the cooldown
+}
+
+func TestError(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/articles/_doc/1":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"root_cause":[],"type":"index_not_found_exception","reason":"no such index [articles]",` +
+				`"index_uuid":"_na_","index":"articles"},"status":404}`))
+		default:
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithMaxRetries(0))
+	_, err := e.QueryDoc(ctx, "articles", "1")
+	var ee *elastic.Error
+	is.True(errors.As(err, &ee))
+	is.Equal(ee.StatusCode, http.StatusNotFound)
+	is.Equal(ee.Type, "index_not_found_exception")
+	is.Equal(ee.Reason, "no such index [articles]")
+	is.Equal(ee.Index, "articles")
+	is.True(elastic.IsNotFound(err))
+	is.True(!elastic.IsConflict(err))
+	is.True(!elastic.IsTimeout(err))
+
+	err = e.CheckOK(ctx)
+	is.True(elastic.IsTimeout(err)) // 504
+
+	dctx, cancel := context.WithTimeout(ctx, time.Nanosecond)
+	defer cancel()
+	<-dctx.Done()
+	is.True(elastic.IsTimeout(e.CheckOK(dctx))) // client side
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var query string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		if r.URL.Query().Get("if_seq_no") == "12" || r.URL.Query().Get("version") == "5" {
+			w.Write([]byte(`{"_id":"1","result":"updated"}`))
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":{"type":"version_conflict_engine_exception","reason":"version conflict"},"status":409}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	_, err := e.IndexDoc(ctx, "articles", "1", `{}`, elastic.IfSeqNo(12), elastic.IfPrimaryTerm(2))
+	is.NoErr(err)
+	is.Equal(query, "if_primary_term=2&if_seq_no=12")
+
+	_, err = e.UpdateDoc(ctx, "articles", "1", `{}`, elastic.IfSeqNo(11), elastic.IfPrimaryTerm(2))
+	is.True(errors.Is(err, elastic.ErrConflict)) // stale seq_no
+
+	_, err = e.DeleteDoc(ctx, "articles", "1", elastic.Version(5), elastic.VersionType("external"))
+	is.NoErr(err)
+	is.Equal(query, "version=5&version_type=external")
+}
+
+func TestGetDoc(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/articles/_doc/1" {
+			w.Write([]byte(`{"_index":"articles","_id":"1","_version":2,"_seq_no":5,"_primary_term":1,"found":true,` +
+				`"_source":{"title":"one"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"_index":"articles","_id":"2","found":false}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	var doc struct {
+		Title string `json:"title"`
+	}
+	r, err := e.GetDoc(ctx, "articles", "1", &doc)
+	is.NoErr(err)
+	is.True(r.Found)
+	is.Equal(r.Version, int64(2))
+	is.Equal(r.SeqNo, int64(5))
+	is.Equal(doc.Title, "one")
+
+	_, err = e.GetDoc(ctx, "articles", "2", &doc)
+	is.True(errors.Is(err, elastic.ErrNotFound))
+}
+
+func TestMultiGet(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Path, "/articles/_mget")
+		xb, _ := ioutil.ReadAll(r.Body)
+		is.Equal(string(xb), `{"ids":["1","2"]}`)
+		w.Write([]byte(`{"docs":[{"_index":"articles","_id":"1","_version":1,"found":true,"_source":{"title":"one"}},` +
+			`{"_index":"articles","_id":"2","found":false}]}`))
+	}))
+	defer s.Close()
+
+	xr, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).MultiGet(ctx, "articles", []string{"1", "2"})
+	is.NoErr(err)
+	is.Equal(len(xr), 2)
+	is.True(xr[0].Found)
+	is.Equal(string(xr[0].Source), `{"title":"one"}`)
+	is.Equal(xr[1].ID, "2")
+	is.True(!xr[1].Found)
+}
+
+func TestSourceFiltering(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+		switch r.URL.Path {
+		case "/articles/_doc/1":
+			w.Write([]byte(`{"_index":"articles","_id":"1","found":true,"fields":{"tags":["go","search"]}}`))
+		case "/articles/_mget":
+			w.Write([]byte(`{"docs":[{"_index":"articles","_id":"1","found":true,"_source":{"title":"one"}}]}`))
+		default:
+			w.Write([]byte(`{"hits":{"hits":[{"_id":"1","_source":{"title":"one"}}]}}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.GetDoc(ctx, "articles", "1", nil, elastic.StoredFields("tags"))
+	is.NoErr(err)
+	is.Equal(r.Fields["tags"], []interface{}{"go", "search"})
+
+	_, err = e.MultiGet(ctx, "articles", []string{"1"}, elastic.SourceIncludes("title", "author.*"))
+	is.NoErr(err)
+
+	_, err = e.SearchWith(ctx, elastic.SearchRequest{Index: "articles"}, elastic.SourceExcludes("body", "attachments"))
+	is.NoErr(err)
+
+	is.Equal(queries, []string{
+		"/articles/_doc/1?stored_fields=tags",
+		"/articles/_mget?_source_includes=title%2Cauthor.%2A",
+		"/articles/_search?_source_excludes=body%2Cattachments",
+	})
+}
+
+func TestExists(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.Method, "HEAD")
+		switch r.URL.Path {
+		case "/articles", "/articles/_doc/1":
+		case "/locked":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	ok, err := e.IndexExists(ctx, "Articles")
+	is.NoErr(err)
+	is.True(ok)
+	ok, err = e.IndexExists(ctx, "gone")
+	is.NoErr(err) // 404 is not an error
+	is.True(!ok)
+	_, err = e.IndexExists(ctx, "locked")
+	is.True(err != nil) // other statuses are
+
+	ok, err = e.DocExists(ctx, "articles", "1")
+	is.NoErr(err)
+	is.True(ok)
+	ok, err = e.DocExists(ctx, "articles", "2")
+	is.NoErr(err)
+	is.True(!ok)
+}
+
+func TestHooks(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"type":"unavailable","reason":"busy"}}`))
+			return
+		}
+		w.Write([]byte(`{"_id":"1","result":"created"}`))
+	}))
+	defer s.Close()
+
+	type key struct{}
+	var before, after []elastic.RequestInfo
+	hook := &recordingHook{
+		before: func(ctx context.Context, info elastic.RequestInfo) context.Context {
+			before = append(before, info)
+			return context.WithValue(ctx, key{}, info.Attempt)
+		},
+		after: func(ctx context.Context, info elastic.RequestInfo) {
+			is.Equal(ctx.Value(key{}), info.Attempt) // the context from BeforeRequest
+			after = append(after, info)
+		},
+	}
+	var logs bytes.Buffer
+	e := elastic.NewClient(s.URL,
+		elastic.WithBasicAuth(user, pass),
+		elastic.WithRetryBackoff(time.Millisecond, time.Millisecond),
+		elastic.WithHook(hook),
+		elastic.WithLogger(log.New(&logs, "", 0)),
+	)
+
+	_, err := e.IndexDoc(ctx, "articles", "1", `{"title":"one"}`)
+	is.NoErr(err)
+
+	is.Equal(len(before), 2)
+	is.Equal(before[1].Attempt, 2)
+	is.Equal(len(after), 2)
+	is.Equal(after[0].StatusCode, http.StatusServiceUnavailable)
+	is.True(after[0].Err != nil)
+	is.Equal(after[0].ResponseBytes, int64(len(`{"error":{"type":"unavailable","reason":"busy"}}`)))
+	is.Equal(after[1].Method, "PUT")
+	is.Equal(after[1].URL, s.URL+"/articles/_doc/1")
+	is.Equal(after[1].Path, "/articles/_doc/1")
+	is.Equal(after[1].Endpoint, "_doc")
+	is.Equal(after[1].StatusCode, http.StatusOK)
+	is.NoErr(after[1].Err)
+	is.Equal(after[1].RequestBytes, int64(len(`{"title":"one"}`)))
+	is.Equal(after[1].ResponseBytes, int64(len(`{"_id":"1","result":"created"}`)))
+
+	// Debug output goes to the logger, without credentials
+	is.True(strings.Contains(logs.String(), "PUT "+s.URL+"/articles/_doc/1"))
+	is.True(strings.Contains(logs.String(), `"reason":"busy"`))
+	is.True(!strings.Contains(logs.String(), "Basic "))
+}
+
+// recordingHook is a Hook made of functions
+type recordingHook struct {
+	before func(context.Context, elastic.RequestInfo) context.Context
+	after  func(context.Context, elastic.RequestInfo)
+}
+
+func (h *recordingHook) BeforeRequest(ctx context.Context, info elastic.RequestInfo) context.Context {
+	return h.before(ctx, info)
+}
+
+func (h *recordingHook) AfterRequest(ctx context.Context, info elastic.RequestInfo) {
+	h.after(ctx, info)
+}
+
+func TestEndpoint(t *testing.T) {
+	is := is.New(t)
+
+	for path, want := range map[string]string{
+		"/":                                   "/",
+		"/articles":                           "_index",
+		"/articles/_doc/1?refresh=true":       "_doc",
+		"/articles/_search":                   "_search",
+		"/_search/scroll":                     "_search",
+		"/_cat/indices?format=json":           "_cat/indices",
+		"/_cluster/health/articles":           "_cluster/health",
+		"/_nodes/stats/jvm":                   "_nodes/stats",
+		"/_snapshot/backups/nightly/_restore": "_snapshot/_restore",
+		"/articles/_update/1":                 "_update",
+	} {
+		is.Equal(elastic.Endpoint(path), want)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var current, peak int
+	unblock := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		current++
+		if current > peak {
+			peak = current
+		}
+		mu.Unlock()
+		<-unblock
+		mu.Lock()
+		current--
+		mu.Unlock()
+		w.Write([]byte(`{"found":true,"_source":{}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithMaxInFlight(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := e.GetDoc(ctx, "articles", "1", nil)
+			is.NoErr(err)
+		}()
+	}
+
+	// A caller waiting for a slot gives up when its context is done
+	time.Sleep(50 * time.Millisecond)
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err := e.GetDoc(tctx, "articles", "1", nil)
+	is.True(elastic.IsTimeout(err))
+
+	close(unblock)
+	wg.Wait()
+	is.Equal(peak, 2)
+}
+
+func TestRateLimit(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"found":true,"_source":{}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithRateLimit(100))
+
+	// the pacing itself is tested against a fake clock in TestClientLimiter
+	for i := 0; i < 20; i++ {
+		_, err := e.GetDoc(ctx, "articles", "1", nil)
+		is.NoErr(err)
+	}
+
+	// A caller waiting for the rate gives up when its context is done
+	slow := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithRateLimit(1))
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err := slow.GetDoc(tctx, "articles", "1", nil)
+	is.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestGzipThrottleLeak(t *testing.T) {
+	is := is.New(t)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"_id":"1","result":"created"}`))
+	}))
+	defer s.Close()
+
+	// the bucket starts empty, so each call waits for the rate and gives up at once on a done context
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithGzip(), elastic.WithRateLimit(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		_, err := e.IndexDoc(ctx, "articles", "1", `{"title":"one"}`)
+		is.True(errors.Is(err, context.Canceled))
+	}
+	is.True(settledGoroutines(before) <= before) // no gzip goroutine is left behind
+}
+
+func TestGzipCircuitOpenLeak(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithGzip(), elastic.WithMaxRetries(0),
+		elastic.WithCircuitBreaker(1, time.Hour))
+	_, err := e.IndexDoc(ctx, "articles", "1", `{"title":"one"}`) // opens the circuit
+	is.True(err != nil)
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		_, err := e.IndexDoc(ctx, "articles", "1", `{"title":"one"}`)
+		is.True(errors.Is(err, elastic.ErrCircuitOpen))
+	}
+	is.True(settledGoroutines(before) <= before) // no gzip goroutine is left behind
+}
+
+// settledGoroutines waits briefly for the number of goroutines to fall to n, and returns the number running
+func settledGoroutines(n int) int {
+	for i := 0; i < 50 && runtime.NumGoroutine() > n; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return runtime.NumGoroutine()
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	status, hits := http.StatusServiceUnavailable, 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits++
+		w.WriteHeader(status)
+		w.Write([]byte(`{"found":true,"_source":{}}`))
+	}))
+	defer s.Close()
+
+	setStatus := func(code int) {
+		mu.Lock()
+		status = code
+		mu.Unlock()
+	}
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithMaxRetries(0),
+		elastic.WithCircuitBreaker(2, 50*time.Millisecond))
+
+	// Two failures open the circuit, after which requests fail without being sent
+	for i := 0; i < 2; i++ {
+		_, err := e.GetDoc(ctx, "articles", "1", nil)
+		is.True(err != nil)
+		is.True(!errors.Is(err, elastic.ErrCircuitOpen))
+	}
+	_, err := e.GetDoc(ctx, "articles", "1", nil)
+	is.True(errors.Is(err, elastic.ErrCircuitOpen))
+	is.Equal(hits, 2)
+
+	// A failed probe opens it again
+	time.Sleep(60 * time.Millisecond)
+	_, err = e.GetDoc(ctx, "articles", "1", nil)
+	is.True(!errors.Is(err, elastic.ErrCircuitOpen))
+	_, err = e.GetDoc(ctx, "articles", "1", nil)
+	is.True(errors.Is(err, elastic.ErrCircuitOpen))
+	is.Equal(hits, 3)
+
+	// A successful probe closes it
+	setStatus(http.StatusOK)
+	time.Sleep(60 * time.Millisecond)
+	_, err = e.GetDoc(ctx, "articles", "1", nil)
+	is.NoErr(err)
+	_, err = e.GetDoc(ctx, "articles", "1", nil)
+	is.NoErr(err)
+	is.Equal(hits, 5)
+
+	// Error responses that are not the node's fault don't count
+	setStatus(http.StatusNotFound)
+	for i := 0; i < 3; i++ {
+		_, err = e.GetDoc(ctx, "articles", "1", nil)
+		is.True(errors.Is(err, elastic.ErrNotFound))
+	}
+	is.Equal(hits, 8)
+}
+
+func TestCircuitBreakerFailover(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var downHits, upHits int
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downHits++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upHits++
+		w.Write([]byte(`{"found":true,"_source":{}}`))
+	}))
+	defer up.Close()
+
+	e := elastic.NewClientWithHosts([]string{down.URL, up.URL}, elastic.WithBasicAuth(user, pass),
+		elastic.WithCircuitBreaker(1, time.Minute))
+
+	// Once the circuit of the down node is open, every request goes straight to the other
+	for i := 0; i < 4; i++ {
+		_, err := e.GetDoc(ctx, "articles", "1", nil)
+		is.NoErr(err)
+	}
+	is.Equal(upHits, 4)
+	is.True(downHits <= 1)
+}
@@ -0,0 +1,63 @@
+// Package elasticotel traces the requests made by an elastic.Client with OpenTelemetry. Each attempt at a request is
+// a client span carrying the database semantic convention attributes, eg db.system=elasticsearch.
+//
+//	c := elastic.NewClient(url, elastic.WithHook(elasticotel.NewHook(nil)))
+package elasticotel
+
+import (
+	"context"
+
+	"github.com/mikedonnici/elastic"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer
+const instrumentationName = "github.com/mikedonnici/elastic/elasticotel"
+
+// Hook is an elastic.Hook that records a span for each attempt at a request
+type Hook struct {
+	tracer trace.Tracer
+}
+
+// NewHook returns a Hook that creates spans with a tracer from tp, or from the global tracer provider if tp is nil
+func NewHook(tp trace.TracerProvider) *Hook {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Hook{tracer: tp.Tracer(instrumentationName)}
+}
+
+// BeforeRequest starts a span named for the method and endpoint, eg "POST _search"
+func (h *Hook) BeforeRequest(ctx context.Context, info elastic.RequestInfo) context.Context {
+	ctx, _ = h.tracer.Start(ctx, info.Method+" "+info.Endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "elasticsearch"),
+			attribute.String("db.operation", info.Endpoint),
+			attribute.String("http.request.method", info.Method),
+			attribute.String("url.full", info.URL),
+			attribute.Int("http.request.resend_count", info.Attempt-1),
+		),
+	)
+	return ctx
+}
+
+// AfterRequest records the response and any error, and ends the span
+func (h *Hook) AfterRequest(ctx context.Context, info elastic.RequestInfo) {
+	span := trace.SpanFromContext(ctx)
+	if info.StatusCode > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", info.StatusCode))
+	}
+	span.SetAttributes(
+		attribute.Int64("http.request.body.size", info.RequestBytes),
+		attribute.Int64("http.response.body.size", info.ResponseBytes),
+	)
+	if info.Err != nil {
+		span.RecordError(info.Err)
+		span.SetStatus(codes.Error, info.Err.Error())
+	}
+	span.End()
+}
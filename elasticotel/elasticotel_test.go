@@ -0,0 +1,53 @@
+package elasticotel_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/elasticotel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHook(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing/_search" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"type":"index_not_found_exception","reason":"no such index [missing]"}}`))
+			return
+		}
+		w.Write([]byte(`{"hits":{"hits":[]}}`))
+	}))
+	defer s.Close()
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	c := elastic.NewClient(s.URL, elastic.WithHook(elasticotel.NewHook(tp)))
+
+	_, err := c.Search(ctx, "articles", `{}`)
+	is.NoErr(err)
+	_, err = c.Search(ctx, "missing", `{}`)
+	is.True(err != nil)
+
+	spans := sr.Ended()
+	is.Equal(len(spans), 2)
+	is.Equal(spans[0].Name(), "POST _search")
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range spans[0].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	is.Equal(attrs["db.system"].AsString(), "elasticsearch")
+	is.Equal(attrs["url.full"].AsString(), s.URL+"/articles/_search")
+	is.Equal(attrs["http.response.status_code"].AsInt64(), int64(200))
+	is.Equal(spans[0].Status().Code, codes.Unset)
+	is.Equal(spans[1].Status().Code, codes.Error)
+}
@@ -0,0 +1,81 @@
+// Package elasticprom exposes Prometheus metrics for the requests made by an elastic.Client: request and error
+// counts, and a latency histogram, by method and endpoint. A Collector is both the elastic.Hook that records the
+// metrics and the prometheus.Collector that exposes them:
+//
+//	m := elasticprom.NewCollector("myapp")
+//	prometheus.MustRegister(m)
+//	c := elastic.NewClient(url, elastic.WithHook(m))
+package elasticprom
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/mikedonnici/elastic"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector records metrics for each attempt at a request. Endpoints are as returned by elastic.Endpoint, so index
+// names and ids do not add to the number of series.
+type Collector struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewCollector returns a Collector whose metrics are prefixed with namespace, if it is not empty:
+//
+//   - elasticsearch_client_requests_total, by method, endpoint and status code, which is "0" if there was no response
+//   - elasticsearch_client_request_errors_total, by method and endpoint
+//   - elasticsearch_client_request_duration_seconds, by method and endpoint
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "elasticsearch_client",
+			Name:      "requests_total",
+			Help:      "Requests sent to Elasticsearch, counting each retry.",
+		}, []string{"method", "endpoint", "code"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "elasticsearch_client",
+			Name:      "request_errors_total",
+			Help:      "Requests to Elasticsearch that failed, with an error response or no response.",
+		}, []string{"method", "endpoint"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "elasticsearch_client",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken by requests to Elasticsearch, including reading the response.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "endpoint"}),
+	}
+}
+
+// BeforeRequest returns ctx unchanged
+func (c *Collector) BeforeRequest(ctx context.Context, info elastic.RequestInfo) context.Context {
+	return ctx
+}
+
+// AfterRequest records the attempt
+func (c *Collector) AfterRequest(ctx context.Context, info elastic.RequestInfo) {
+	c.requests.WithLabelValues(info.Method, info.Endpoint, strconv.Itoa(info.StatusCode)).Inc()
+	if info.Err != nil {
+		c.errors.WithLabelValues(info.Method, info.Endpoint).Inc()
+	}
+	c.duration.WithLabelValues(info.Method, info.Endpoint).Observe(info.Duration.Seconds())
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.errors.Describe(ch)
+	c.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.errors.Collect(ch)
+	c.duration.Collect(ch)
+}
@@ -0,0 +1,52 @@
+package elasticprom_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/elasticprom"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/articles/_doc/2" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"found":false}`))
+			return
+		}
+		w.Write([]byte(`{"_id":"1","found":true,"_source":{}}`))
+	}))
+	defer s.Close()
+
+	m := elasticprom.NewCollector("test")
+	reg := prometheus.NewPedanticRegistry()
+	is.NoErr(reg.Register(m))
+
+	c := elastic.NewClient(s.URL, elastic.WithHook(m))
+	var doc map[string]interface{}
+	_, err := c.GetDoc(ctx, "articles", "1", &doc)
+	is.NoErr(err)
+	_, err = c.GetDoc(ctx, "articles", "2", &doc)
+	is.True(err != nil)
+
+	is.NoErr(testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP test_elasticsearch_client_requests_total Requests sent to Elasticsearch, counting each retry.
+# TYPE test_elasticsearch_client_requests_total counter
+test_elasticsearch_client_requests_total{code="200",endpoint="_doc",method="GET"} 1
+test_elasticsearch_client_requests_total{code="404",endpoint="_doc",method="GET"} 1
+# HELP test_elasticsearch_client_request_errors_total Requests to Elasticsearch that failed, with an error response or no response.
+# TYPE test_elasticsearch_client_request_errors_total counter
+test_elasticsearch_client_request_errors_total{endpoint="_doc",method="GET"} 1
+`), "test_elasticsearch_client_requests_total", "test_elasticsearch_client_request_errors_total"))
+	is.Equal(testutil.CollectAndCount(m, "test_elasticsearch_client_request_duration_seconds"), 1)
+}
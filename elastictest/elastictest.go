@@ -0,0 +1,165 @@
+// Package elastictest tests code that uses an elastic.Client without a cluster. A Transport serves canned responses
+// registered by method and path, and records each request so that tests can check what was sent:
+//
+//	tr := elastictest.NewTransport()
+//	tr.Handle("POST", "/articles/_search", 200, `{"hits":{"hits":[]}}`)
+//	c := elastictest.NewClient(tr)
+//	... code under test that uses c ...
+//	r, ok := tr.LastRequest("POST", "/articles/_search")
+//
+// The root endpoint answers as Elasticsearch 8 unless a response is registered for "GET /", so version detection
+// works as it would against a cluster.
+package elastictest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/mikedonnici/elastic"
+)
+
+// URL is the address of the cluster that clients from NewClient talk to. Requests never leave the Transport.
+const URL = "http://elastictest.invalid:9200"
+
+// defaultInfo is the response from the root endpoint when none is registered
+const defaultInfo = `{"name":"elastictest","cluster_name":"elastictest","cluster_uuid":"elastictest",` +
+	`"version":{"number":"8.11.1","lucene_version":"9.8.0"},"tagline":"You Know, for Search"}`
+
+// Request is a request received by a Transport. Body is decompressed if the client sent it gzipped.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// JSON unmarshals the request body into v
+func (r Request) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// HandlerFunc returns the status code and body of the response to a request
+type HandlerFunc func(r Request) (status int, body string)
+
+// Transport is an http.RoundTripper that serves registered responses rather than making requests. It is safe for
+// concurrent use. A request with no registered response gets a 404 with an Elasticsearch error body naming it.
+type Transport struct {
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	requests []Request
+}
+
+// NewTransport returns a Transport with no responses registered
+func NewTransport() *Transport {
+	return &Transport{handlers: map[string]HandlerFunc{}}
+}
+
+// NewClient returns a client that sends its requests to t. Options are applied after WithTransport, and should not
+// replace the transport.
+func NewClient(t *Transport, opts ...elastic.Option) *elastic.Client {
+	return elastic.NewClient(URL, append([]elastic.Option{elastic.WithTransport(t)}, opts...)...)
+}
+
+// Handle registers the response to requests with method and path, which excludes the query string, eg
+// Handle("GET", "/articles/_doc/1", 200, `{"found":true,"_source":{}}`). It replaces any earlier response.
+func (t *Transport) Handle(method, path string, status int, body string) {
+	t.HandleFunc(method, path, func(Request) (int, string) {
+		return status, body
+	})
+}
+
+// HandleFunc registers a function that builds the response to requests with method and path, eg to respond
+// differently to successive requests or according to the body
+func (t *Transport) HandleFunc(method, path string, fn HandlerFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers[method+" "+path] = fn
+}
+
+// Requests returns every request received, in order
+func (t *Transport) Requests() []Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Request(nil), t.requests...)
+}
+
+// LastRequest returns the most recent request with method and path, and false if there was none
+func (t *Transport) LastRequest(method, path string) (Request, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.requests) - 1; i >= 0; i-- {
+		if r := t.requests[i]; r.Method == method && r.Path == path {
+			return r, true
+		}
+	}
+	return Request{}, false
+}
+
+// Reset forgets the requests received. Registered responses are kept.
+func (t *Transport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests = nil
+}
+
+// RoundTrip records req and returns the registered response
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	r := Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query(),
+		Header: req.Header.Clone(),
+	}
+	if req.Body != nil {
+		xb, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(bytes.NewReader(xb))
+			if err != nil {
+				return nil, err
+			}
+			if xb, err = ioutil.ReadAll(zr); err != nil {
+				return nil, err
+			}
+		}
+		r.Body = xb
+	}
+
+	t.mu.Lock()
+	t.requests = append(t.requests, r)
+	fn, ok := t.handlers[r.Method+" "+r.Path]
+	t.mu.Unlock()
+
+	status, body := http.StatusOK, defaultInfo
+	switch {
+	case ok:
+		status, body = fn(r)
+	case r.Method != "GET" || r.Path != "/":
+		reason, _ := json.Marshal(fmt.Sprintf("elastictest: no response registered for %s %s", r.Method, r.Path))
+		status = http.StatusNotFound
+		body = `{"error":{"type":"resource_not_found_exception","reason":` + string(reason) + `},"status":404}`
+	}
+
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
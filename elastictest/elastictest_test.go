@@ -0,0 +1,71 @@
+package elastictest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/elastictest"
+)
+
+func TestTransport(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	tr := elastictest.NewTransport()
+	tr.Handle("GET", "/articles/_doc/1", http.StatusOK, `{"_index":"articles","_id":"1","found":true,"_source":{"title":"one"}}`)
+	tr.HandleFunc("POST", "/articles/_bulk", func(r elastictest.Request) (int, string) {
+		return http.StatusOK, `{"took":1,"errors":false,"items":[]}`
+	})
+	c := elastictest.NewClient(tr, elastic.WithGzip())
+
+	var doc struct {
+		Title string `json:"title"`
+	}
+	_, err := c.GetDoc(ctx, "articles", "1", &doc)
+	is.NoErr(err)
+	is.Equal(doc.Title, "one")
+
+	_, err = c.Batch(ctx, "articles", `{"index":{"_id":"2"}}`+"\n"+`{"title":"two"}`+"\n", elastic.Refresh("true"))
+	is.NoErr(err)
+
+	// The root endpoint answers as Elasticsearch 8, so the bulk request used the typeless path
+	xr := tr.Requests()
+	is.Equal(len(xr), 3)
+	is.Equal(xr[1].Method+" "+xr[1].Path, "GET /")
+
+	r, ok := tr.LastRequest("POST", "/articles/_bulk")
+	is.True(ok)
+	is.Equal(r.Query.Get("refresh"), "true")
+	is.Equal(r.Header.Get("Content-Type"), "application/x-ndjson")
+	is.Equal(string(r.Body), `{"index":{"_id":"2"}}`+"\n"+`{"title":"two"}`+"\n") // decompressed
+
+	// No registered response
+	_, err = c.GetDoc(ctx, "articles", "2", nil)
+	is.True(errors.Is(err, elastic.ErrNotFound))
+	var e *elastic.Error
+	is.True(errors.As(err, &e))
+	is.Equal(e.Reason, "elastictest: no response registered for GET /articles/_doc/2")
+
+	tr.Reset()
+	is.Equal(len(tr.Requests()), 0)
+}
+
+func TestRequestJSON(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	tr := elastictest.NewTransport()
+	tr.Handle("PUT", "/_ingest/pipeline/stamp", http.StatusOK, `{"acknowledged":true}`)
+	c := elastictest.NewClient(tr)
+
+	is.NoErr(c.PutPipeline(ctx, "stamp", elastic.IngestPipeline{Description: "adds a timestamp"}))
+
+	r, _ := tr.LastRequest("PUT", "/_ingest/pipeline/stamp")
+	var body map[string]interface{}
+	is.NoErr(r.JSON(&body))
+	is.Equal(body["description"], "adds a timestamp")
+}
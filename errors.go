@@ -0,0 +1,176 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned, wrapped, when the circuit breaker is open for every node, so a request is failed
+// without being sent. See WithCircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// ErrConflict is matched, using errors.Is, by a version conflict (409) response, eg when a document was changed by
+// another writer
+var ErrConflict = errors.New("version conflict")
+
+// ErrIndexNotFound is matched, using errors.Is, by an index_not_found_exception response. IndexDoc returns it when
+// the index does not exist and cannot be created automatically because action.auto_create_index restricts it.
+var ErrIndexNotFound = errors.New("index does not exist")
+
+// ErrResponseTooLarge is returned, wrapped, when a response body is larger than the limit set by
+// WithMaxResponseSize. The request is not retried.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// ErrNotFound is matched, using errors.Is, by a 404 response, eg from GetDoc for a document that does not exist
+var ErrNotFound = errors.New("not found")
+
+// Error is an error response from Elasticsearch. Client methods wrap it, so use errors.As to get at it, or the
+// IsNotFound, IsConflict and IsTimeout helpers to branch on common conditions.
+type Error struct {
+	StatusCode int
+	Type       string // eg "index_not_found_exception", empty if the body was not a JSON error
+	Reason     string // the reason given in the body, or the body itself if there was none
+	Index      string // the index the error relates to, if any
+	Body       []byte // as much of the response body as was read, see WithErrorBodyLimit
+
+	retryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *Error) Error() string {
+	return http.StatusText(e.StatusCode) + " - " + e.Reason
+}
+
+// Is makes errors.Is(err, ErrNotFound) true for a 404 response, errors.Is(err, ErrConflict) true for a 409 response,
+// and errors.Is(err, ErrIndexNotFound) true for an index_not_found_exception
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrIndexNotFound:
+		return e.Type == "index_not_found_exception"
+	}
+	return false
+}
+
+// IsNotFound reports whether err was caused by a 404 response, eg for a missing document or index
+func IsNotFound(err error) bool {
+	return isStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err was caused by a 409 response, eg a version conflict
+func IsConflict(err error) bool {
+	return isStatus(err, http.StatusConflict)
+}
+
+// IsTimeout reports whether err was caused by a timeout, either on the server, eg a 408 or 504 response or a
+// timeout_exception, or on the client, eg a context deadline or WithTimeout
+func IsTimeout(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode == http.StatusRequestTimeout ||
+			e.StatusCode == http.StatusGatewayTimeout ||
+			strings.HasSuffix(e.Type, "timeout_exception")
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// isStatus reports whether err was caused by an error response with the specified status code
+func isStatus(err error, status int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == status
+}
+
+// readError builds an Error from an error response body. At most limit bytes are read so a huge error response
+// can't cause a memory spike, and the reason is picked out of the JSON as it is decoded so it can still be found
+// when the body has been cut short. If there is no reason the raw body is used.
+func readError(status int, body io.Reader, limit int64) *Error {
+
+	xb, _ := ioutil.ReadAll(io.LimitReader(body, limit))
+
+	e := &Error{StatusCode: status, Body: xb}
+	e.Type, e.Reason, e.Index = jsonError(xb)
+	if e.Reason == "" {
+		e.Reason = strings.TrimSpace(string(xb))
+	}
+	return e
+}
+
+// jsonError returns error.type, error.reason and error.index, or error if it is a string, from a JSON error body
+// that may be truncated
+func jsonError(xb []byte) (typ, reason, index string) {
+
+	dec := json.NewDecoder(bytes.NewReader(xb))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return "", "", ""
+	}
+
+	for dec.More() {
+		k, err := dec.Token()
+		if err != nil {
+			return "", "", ""
+		}
+		if k != "error" {
+			if skipJSONValue(dec) != nil {
+				return "", "", ""
+			}
+			continue
+		}
+
+		t, err := dec.Token()
+		if err != nil {
+			return "", "", ""
+		}
+		if s, ok := t.(string); ok {
+			return "", s, ""
+		}
+		if t != json.Delim('{') {
+			return "", "", ""
+		}
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return typ, reason, index
+			}
+			if k == "type" || k == "reason" || k == "index" {
+				t, _ := dec.Token()
+				s, _ := t.(string)
+				switch k {
+				case "type":
+					typ = s
+				case "reason":
+					reason = s
+				default:
+					index = s
+				}
+				continue
+			}
+			if skipJSONValue(dec) != nil {
+				return typ, reason, index
+			}
+		}
+		return typ, reason, index
+	}
+
+	return "", "", ""
+}
+
+// skipJSONValue reads past the next value in dec
+func skipJSONValue(dec *json.Decoder) error {
+	var v json.RawMessage
+	return dec.Decode(&v)
+}
@@ -0,0 +1,86 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Explanation is how a score, Value, was computed, as a tree of the parts that went into it
+type Explanation struct {
+	Value       float64       `json:"value"`
+	Description string        `json:"description"`
+	Details     []Explanation `json:"details"`
+}
+
+// ExplainResult reports whether a document matched a query and why
+type ExplainResult struct {
+	Index       string       `json:"_index"`
+	ID          string       `json:"_id"`
+	Matched     bool         `json:"matched"`
+	Explanation *Explanation `json:"explanation"`
+}
+
+// Explain shows why a document does, or does not, match a query, and how its score is computed. The query is a
+// search request body, as for Search. If the document does not exist the error satisfies errors.Is(err, ErrNotFound).
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-explain.html
+func (c *Client) Explain(ctx context.Context, index, id, query string) (*ExplainResult, error) {
+
+	if id == "" {
+		return nil, errors.New("Explain - id must be specified")
+	}
+
+	u := "/" + strings.ToLower(index) + "/_explain/" + url.PathEscape(id)
+	if !c.typeless(ctx) {
+		u = "/" + strings.ToLower(index) + "/_doc/" + url.PathEscape(id) + "/_explain"
+	}
+	xb, err := c.request(ctx, "POST", u, strings.NewReader(query), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(searchError(err), "Explain")
+	}
+
+	var r ExplainResult
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &r, nil
+}
+
+// QueryValidation is the result of ValidateQuery. If the query is not valid Explanations holds the error for each
+// index; if it is, each holds the query as rewritten by that index, eg with analyzed terms.
+type QueryValidation struct {
+	Valid        bool               `json:"valid"`
+	Explanations []QueryExplanation `json:"explanations"`
+}
+
+// QueryExplanation is the validation of a query against one index
+type QueryExplanation struct {
+	Index       string `json:"index"`
+	Valid       bool   `json:"valid"`
+	Explanation string `json:"explanation"`
+	Error       string `json:"error"`
+}
+
+// ValidateQuery checks a query, a search request body as for Search, against the mappings of index, or all indices
+// if index is empty, without running it. An invalid query is not an error; check Valid and the Explanations.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-validate.html
+func (c *Client) ValidateQuery(ctx context.Context, index, query string) (*QueryValidation, error) {
+
+	u := "/_validate/query?explain=true"
+	if index != "" {
+		u = "/" + strings.ToLower(index) + u
+	}
+	xb, err := c.request(ctx, "POST", u, strings.NewReader(query), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "ValidateQuery")
+	}
+
+	var r QueryValidation
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &r, nil
+}
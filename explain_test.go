@@ -0,0 +1,54 @@
+package elastic_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestExplain(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Path, "/articles/_explain/1")
+		w.Write([]byte(`{"_index":"articles","_id":"1","matched":true,"explanation":{"value":1.6943,` +
+			`"description":"weight(title:fox in 0) [PerFieldSimilarity], result of:","details":[` +
+			`{"value":1.6943,"description":"score(freq=1.0), computed as boost * idf * tf from:","details":[]}]}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(7))
+	r, err := e.Explain(ctx, "articles", "1", `{"query":{"match":{"title":"fox"}}}`)
+	is.NoErr(err)
+	is.True(r.Matched)
+	is.Equal(r.Explanation.Value, 1.6943)
+	is.Equal(len(r.Explanation.Details), 1)
+
+	_, err = e.Explain(ctx, "articles", "", `{}`)
+	is.True(err != nil)
+}
+
+func TestValidateQuery(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Path+"?"+r.URL.RawQuery, "/articles/_validate/query?explain=true")
+		w.Write([]byte(`{"valid":false,"_shards":{"total":1,"successful":1,"failed":0},"explanations":[` +
+			`{"index":"articles","valid":false,"error":"[articles/abc] QueryShardException[failed to create query: ` +
+			`For input string: \"ten\"]"}]}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	v, err := e.ValidateQuery(ctx, "articles", `{"query":{"term":{"views":"ten"}}}`)
+	is.NoErr(err)
+	is.True(!v.Valid)
+	is.Equal(v.Explanations[0].Index, "articles")
+	is.True(v.Explanations[0].Error != "")
+}
@@ -0,0 +1,128 @@
+package elastic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestInfo describes one attempt at a request, as passed to a Hook. A request that is retried or fails over to
+// another host is made up of several attempts.
+type RequestInfo struct {
+	Method        string
+	URL           string // the full url, including the host
+	Path          string // the path and query, without the host
+	Endpoint      string // the API called, without index names or ids, eg "_search" or "_cat/indices", see Endpoint
+	Attempt       int    // from 1
+	StatusCode    int    // 0 if there was no response
+	Duration      time.Duration
+	RequestBytes  int64 // as sent, so compressed if WithGzip is set
+	ResponseBytes int64 // after decompression
+	Err           error
+}
+
+// Hook observes every attempt a Client makes, eg to log, trace or count requests. BeforeRequest is called before
+// each attempt with the method, url, path and attempt number set, and the context it returns is the one the attempt
+// is sent with and that AfterRequest is called with, so a tracer can carry a span from one to the other.
+// AfterRequest is called once the response body has been read. Hooks are called from the goroutine making the
+// request, so must be safe for concurrent use.
+type Hook interface {
+	BeforeRequest(ctx context.Context, info RequestInfo) context.Context
+	AfterRequest(ctx context.Context, info RequestInfo)
+}
+
+// AfterRequestFunc is a Hook that only needs to know about completed attempts, eg for logging
+type AfterRequestFunc func(ctx context.Context, info RequestInfo)
+
+// BeforeRequest returns ctx unchanged
+func (f AfterRequestFunc) BeforeRequest(ctx context.Context, info RequestInfo) context.Context {
+	return ctx
+}
+
+// AfterRequest calls f
+func (f AfterRequestFunc) AfterRequest(ctx context.Context, info RequestInfo) {
+	f(ctx, info)
+}
+
+// Logger receives debug output, such as the headers of each request and the body of each error response. A
+// *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Endpoint returns the API that a request path calls, eg "_doc" for "/articles/_doc/1?refresh=true", without the
+// index names, ids and parameters, so that it can be used to group requests, eg as a metric label. The cat, cluster
+// and nodes APIs include the name of the API, eg "_cat/indices", and the root endpoint is "/".
+func Endpoint(path string) string {
+
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	var xs []string
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segments {
+		if !strings.HasPrefix(seg, "_") {
+			continue
+		}
+		xs = append(xs, seg)
+		switch seg {
+		case "_cat", "_cluster", "_nodes":
+			if i+1 < len(segments) && segments[i+1] != "" && !strings.HasPrefix(segments[i+1], "_") {
+				xs = append(xs, segments[i+1])
+			}
+		}
+	}
+	if len(xs) == 0 {
+		if path == "" || path == "/" {
+			return "/"
+		}
+		return "_index" // eg creating, deleting or checking an index
+	}
+	return strings.Join(xs, "/")
+}
+
+// beforeRequest calls the BeforeRequest of each hook in turn
+func (c *Client) beforeRequest(ctx context.Context, info RequestInfo) context.Context {
+	for _, h := range c.hooks {
+		ctx = h.BeforeRequest(ctx, info)
+	}
+	return ctx
+}
+
+// afterRequest calls the AfterRequest of each hook, in reverse order so that hooks nest
+func (c *Client) afterRequest(ctx context.Context, info RequestInfo) {
+	for i := len(c.hooks) - 1; i >= 0; i-- {
+		c.hooks[i].AfterRequest(ctx, info)
+	}
+}
+
+// debugf writes to the logger, if there is one
+func (c *Client) debugf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	}
+}
+
+// redactedHeader returns a copy of h that is safe to log
+func redactedHeader(h http.Header) http.Header {
+	h = h.Clone()
+	if h.Get("Authorization") != "" {
+		h.Set("Authorization", "[redacted]")
+	}
+	return h
+}
+
+// countingBody counts the bytes read from a request body
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,131 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LifecyclePolicy is an index lifecycle management (ILM) policy, the phases, eg "hot", "warm", "cold" and "delete",
+// an index moves through as it ages
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/index-lifecycle-management.html
+type LifecyclePolicy struct {
+	Phases map[string]LifecyclePhase `json:"phases"`
+}
+
+// LifecyclePhase is a phase of a lifecycle policy. MinAge is how old an index must be, eg "30d", before it enters
+// the phase, and Actions are the actions run in the phase keyed by name, eg {"rollover": {"max_age": "7d"}} or
+// {"delete": {}}.
+type LifecyclePhase struct {
+	MinAge  string                 `json:"min_age,omitempty"`
+	Actions map[string]interface{} `json:"actions"`
+}
+
+// LifecycleExplain is where an index is in its lifecycle. FailedStep and StepInfo say why an index is in the ERROR
+// step; after fixing the cause, RetryLifecycle resumes it.
+type LifecycleExplain struct {
+	Index      string          `json:"index"`
+	Managed    bool            `json:"managed"`
+	Policy     string          `json:"policy"`
+	Phase      string          `json:"phase"`
+	Action     string          `json:"action"`
+	Step       string          `json:"step"`
+	FailedStep string          `json:"failed_step"`
+	Age        string          `json:"age"`
+	StepInfo   json.RawMessage `json:"step_info"`
+}
+
+// PutLifecyclePolicy creates or replaces a lifecycle policy
+func (c *Client) PutLifecyclePolicy(ctx context.Context, name string, p LifecyclePolicy) error {
+
+	xb, err := json.Marshal(map[string]LifecyclePolicy{"policy": p})
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	_, err = c.request(ctx, "PUT", "/_ilm/policy/"+name, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "PutLifecyclePolicy")
+	}
+	return nil
+}
+
+// GetLifecyclePolicy returns a lifecycle policy
+func (c *Client) GetLifecyclePolicy(ctx context.Context, name string) (*LifecyclePolicy, error) {
+
+	xb, err := c.request(ctx, "GET", "/_ilm/policy/"+name, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetLifecyclePolicy")
+	}
+
+	var r map[string]struct {
+		Policy LifecyclePolicy `json:"policy"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	v, ok := r[name]
+	if !ok {
+		return nil, errors.Wrap(ErrNotFound, "GetLifecyclePolicy - "+name)
+	}
+	return &v.Policy, nil
+}
+
+// DeleteLifecyclePolicy deletes a lifecycle policy, which must not be in use by any index
+func (c *Client) DeleteLifecyclePolicy(ctx context.Context, name string) error {
+	_, err := c.request(ctx, "DELETE", "/_ilm/policy/"+name, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "DeleteLifecyclePolicy")
+	}
+	return nil
+}
+
+// SetIndexLifecycle attaches a lifecycle policy to an existing index. To have new indices managed from the start,
+// set IndexSettings.Lifecycle in their template instead. rolloverAlias may be empty if the policy does not roll
+// over.
+func (c *Client) SetIndexLifecycle(ctx context.Context, index, policy, rolloverAlias string) error {
+
+	xb, err := json.Marshal(map[string]interface{}{
+		"index": map[string]interface{}{
+			"lifecycle": LifecycleSettings{Name: policy, RolloverAlias: rolloverAlias},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	if err := c.PutIndexSettings(ctx, index, string(xb)); err != nil {
+		return errors.Wrap(err, "SetIndexLifecycle")
+	}
+	return nil
+}
+
+// ExplainLifecycle returns where each index matching index, which may be a pattern, is in its lifecycle, keyed by
+// index name
+func (c *Client) ExplainLifecycle(ctx context.Context, index string) (map[string]LifecycleExplain, error) {
+
+	xb, err := c.request(ctx, "GET", "/"+strings.ToLower(index)+"/_ilm/explain", nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "ExplainLifecycle")
+	}
+
+	var r struct {
+		Indices map[string]LifecycleExplain `json:"indices"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return r.Indices, nil
+}
+
+// RetryLifecycle retries the failed step of the indices matching index, which are in the ERROR step
+func (c *Client) RetryLifecycle(ctx context.Context, index string) error {
+	_, err := c.request(ctx, "POST", "/"+strings.ToLower(index)+"/_ilm/retry", nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "RetryLifecycle")
+	}
+	return nil
+}
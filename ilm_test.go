@@ -0,0 +1,69 @@
+package elastic_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestLifecycle(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	requests := map[string]string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		requests[r.Method+" "+r.URL.Path] = string(xb)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /_ilm/policy/logs":
+			w.Write([]byte(`{"logs":{"version":1,"modified_date":"2021-01-01T00:00:00.000Z","policy":{"phases":{
+				"hot":{"min_age":"0ms","actions":{"rollover":{"max_age":"7d"}}},
+				"delete":{"min_age":"30d","actions":{"delete":{}}}
+			}}}}`))
+		case "GET /logs-*/_ilm/explain":
+			w.Write([]byte(`{"indices":{"logs-000001":{"index":"logs-000001","managed":true,"policy":"logs",
+				"phase":"hot","action":"rollover","step":"ERROR","failed_step":"check-rollover-ready",
+				"step_info":{"type":"illegal_argument_exception","reason":"rollover alias is empty"}}}}`))
+		default:
+			w.Write([]byte(`{"acknowledged":true}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.PutLifecyclePolicy(ctx, "logs", elastic.LifecyclePolicy{Phases: map[string]elastic.LifecyclePhase{
+		"hot":    {Actions: map[string]interface{}{"rollover": map[string]string{"max_age": "7d"}}},
+		"delete": {MinAge: "30d", Actions: map[string]interface{}{"delete": struct{}{}}},
+	}}))
+	is.Equal(requests["PUT /_ilm/policy/logs"], `{"policy":{"phases":{"delete":{"min_age":"30d","actions":{"delete":{}}},`+
+		`"hot":{"actions":{"rollover":{"max_age":"7d"}}}}}}`)
+
+	p, err := e.GetLifecyclePolicy(ctx, "logs")
+	is.NoErr(err)
+	is.Equal(p.Phases["delete"].MinAge, "30d")
+
+	is.NoErr(e.SetIndexLifecycle(ctx, "logs-000001", "logs", "logs"))
+	is.Equal(requests["PUT /logs-000001/_settings"], `{"index":{"lifecycle":{"name":"logs","rollover_alias":"logs"}}}`)
+
+	xe, err := e.ExplainLifecycle(ctx, "logs-*")
+	is.NoErr(err)
+	is.Equal(xe["logs-000001"].Step, "ERROR")
+	is.Equal(xe["logs-000001"].FailedStep, "check-rollover-ready")
+
+	is.NoErr(e.RetryLifecycle(ctx, "logs-000001"))
+	_, ok := requests["POST /logs-000001/_ilm/retry"]
+	is.True(ok)
+
+	is.NoErr(e.DeleteLifecyclePolicy(ctx, "logs"))
+
+	// Attached through template settings
+	var ts elastic.IndexSettings
+	is.NoErr(json.Unmarshal([]byte(`{"index":{"lifecycle":{"name":"logs","rollover_alias":"logs"},"number_of_shards":"1"}}`), &ts))
+	is.Equal(*ts.Lifecycle, elastic.LifecycleSettings{Name: "logs", RolloverAlias: "logs"})
+}
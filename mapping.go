@@ -0,0 +1,372 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MappingDiff describes a field whose mapping differs between two indices. An empty type means the field is not
+// present in that index.
+type MappingDiff struct {
+	Field string
+	TypeA string
+	TypeB string
+}
+
+// Mapping is the mapping of an index, for CreateIndexWith and from GetMapping
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/mapping.html
+type Mapping struct {
+	Dynamic    interface{}         `json:"dynamic,omitempty"` // true, false or "strict"
+	Properties map[string]Property `json:"properties,omitempty"`
+}
+
+// Property is the mapping of a field. Properties holds the fields of an object or nested field, and Fields the
+// multi-fields that index the same value in different ways, eg a keyword sub-field of a text field.
+type Property struct {
+	Type           string              `json:"type,omitempty"`
+	Analyzer       string              `json:"analyzer,omitempty"`
+	SearchAnalyzer string              `json:"search_analyzer,omitempty"`
+	Format         string              `json:"format,omitempty"`
+	Index          *bool               `json:"index,omitempty"`
+	IgnoreAbove    int                 `json:"ignore_above,omitempty"`
+	Properties     map[string]Property `json:"properties,omitempty"`
+	Fields         map[string]Property `json:"fields,omitempty"`
+}
+
+// DiffMappings compares the mappings of two indices and reports fields that are present in only one of them, or
+// that are mapped to different types. Fields are identified by their dotted path, eg "author.name", and the result
+// is sorted by field.
+func (c *Client) DiffMappings(ctx context.Context, indexA, indexB string) ([]MappingDiff, error) {
+
+	a, err := c.fieldTypes(ctx, indexA)
+	if err != nil {
+		return nil, errors.Wrap(err, "DiffMappings")
+	}
+	b, err := c.fieldTypes(ctx, indexB)
+	if err != nil {
+		return nil, errors.Wrap(err, "DiffMappings")
+	}
+
+	var xd []MappingDiff
+	for f, ta := range a {
+		if tb := b[f]; ta != tb {
+			xd = append(xd, MappingDiff{Field: f, TypeA: ta, TypeB: tb})
+		}
+	}
+	for f, tb := range b {
+		if _, ok := a[f]; !ok {
+			xd = append(xd, MappingDiff{Field: f, TypeB: tb})
+		}
+	}
+	sort.Slice(xd, func(i, j int) bool { return xd[i].Field < xd[j].Field })
+
+	return xd, nil
+}
+
+// GetMapping returns the mapping of an index. Mappings from before version 7, which are nested under a type name,
+// are returned as if they were typeless.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-get-mapping.html
+func (c *Client) GetMapping(ctx context.Context, index string) (*Mapping, error) {
+
+	xb, err := c.request(ctx, "GET", "/"+strings.ToLower(index)+"/_mapping", nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetMapping")
+	}
+
+	var m map[string]struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	err = json.Unmarshal(xb, &m)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	if len(m) != 1 {
+		return nil, errors.Errorf("GetMapping - expected the mapping of one index, got %d", len(m))
+	}
+
+	var mapping Mapping
+	for _, v := range m {
+		// Typeless (7.x+) mappings hold properties at the top level, 6.x nests them under the type name
+		err := json.Unmarshal(v.Mappings, &mapping)
+		if err == nil && mapping.Properties != nil {
+			break
+		}
+		var typed map[string]Mapping
+		if json.Unmarshal(v.Mappings, &typed) == nil {
+			for _, t := range typed {
+				if t.Properties != nil {
+					return &t, nil
+				}
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Unmarshal")
+		}
+	}
+
+	return &mapping, nil
+}
+
+// FieldCapability is what can be done with a field of one type. A field mapped to different types in different
+// indices has a FieldCapability for each type. Indices lists the indices with the field as this type, and is only
+// set when that is not all of them; likewise the indices where it is not searchable or aggregatable.
+type FieldCapability struct {
+	Type                   string   `json:"type"`
+	Searchable             bool     `json:"searchable"`
+	Aggregatable           bool     `json:"aggregatable"`
+	Indices                []string `json:"indices"`
+	NonSearchableIndices   []string `json:"non_searchable_indices"`
+	NonAggregatableIndices []string `json:"non_aggregatable_indices"`
+}
+
+// FieldCaps is the result of FieldCaps. Fields maps each field, by dotted path, to its capabilities by type.
+type FieldCaps struct {
+	Indices []string                              `json:"indices"`
+	Fields  map[string]map[string]FieldCapability `json:"fields"`
+}
+
+// FieldCaps returns the type of each field in index, which may be a comma separated list or pattern, and whether it
+// can be searched and aggregated on, eg to build a query UI that offers only the fields that suit an operation.
+// Fields may use wildcards, and are all fields if none are given.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-field-caps.html
+func (c *Client) FieldCaps(ctx context.Context, index string, fields []string) (*FieldCaps, error) {
+
+	if len(fields) == 0 {
+		fields = []string{"*"}
+	}
+	u := "/_field_caps?fields=" + url.QueryEscape(strings.Join(fields, ","))
+	if index != "" {
+		u = "/" + strings.ToLower(index) + u
+	}
+	xb, err := c.request(ctx, "GET", u, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "FieldCaps")
+	}
+
+	var fc FieldCaps
+	if err := json.Unmarshal(xb, &fc); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &fc, nil
+}
+
+// GetFieldMapping returns the mapping of fields, by dotted path and which may use wildcards, in each index matching
+// index, keyed by index and then field. It is cheaper than GetMapping for a few fields of a large mapping.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-get-field-mapping.html
+func (c *Client) GetFieldMapping(ctx context.Context, index string, fields ...string) (map[string]map[string]Property, error) {
+
+	if len(fields) == 0 {
+		return nil, errors.New("GetFieldMapping - fields must be specified")
+	}
+
+	u := "/" + strings.ToLower(index) + "/_mapping/field/" + url.PathEscape(strings.Join(fields, ","))
+	xb, err := c.request(ctx, "GET", u, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetFieldMapping")
+	}
+
+	// Each field holds its full name and its mapping keyed by the last part of the name. Mappings from before
+	// version 7 are nested under the type name.
+	type fieldMapping struct {
+		FullName string              `json:"full_name"`
+		Mapping  map[string]Property `json:"mapping"`
+	}
+	var m map[string]struct {
+		Mappings map[string]json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal(xb, &m); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	xm := make(map[string]map[string]Property, len(m))
+	for idx, v := range m {
+		props := map[string]Property{}
+		for _, raw := range v.Mappings {
+			var fm fieldMapping
+			if err := json.Unmarshal(raw, &fm); err != nil {
+				return nil, errors.Wrap(err, "Unmarshal")
+			}
+			if fm.FullName == "" {
+				var typed map[string]fieldMapping
+				if err := json.Unmarshal(raw, &typed); err != nil {
+					return nil, errors.Wrap(err, "Unmarshal")
+				}
+				for _, fm := range typed {
+					for _, p := range fm.Mapping {
+						props[fm.FullName] = p
+					}
+				}
+				continue
+			}
+			for _, p := range fm.Mapping {
+				props[fm.FullName] = p
+			}
+		}
+		xm[idx] = props
+	}
+
+	return xm, nil
+}
+
+// fieldTypes fetches the mapping of an index and flattens it into a map of dotted field path to field type
+func (c *Client) fieldTypes(ctx context.Context, index string) (map[string]string, error) {
+	m, err := c.GetMapping(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	types := map[string]string{}
+	flattenMapping("", m.Properties, types)
+	return types, nil
+}
+
+// flattenMapping adds each field in props, and any sub-fields, to types keyed by dotted path
+func flattenMapping(prefix string, props map[string]Property, types map[string]string) {
+	for name, f := range props {
+		path := prefix + name
+		t := f.Type
+		if t == "" && f.Properties != nil {
+			t = "object"
+		}
+		types[path] = t
+		flattenMapping(path+".", f.Properties, types)
+		flattenMapping(path+".", f.Fields, types)
+	}
+}
+
+// PutMapping adds fields to, or updates the updatable parameters of fields in, the mapping of an index. The mapping
+// is a JSON object, eg `{"properties": {"title": {"type": "text"}}}`.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-put-mapping.html
+func (c *Client) PutMapping(ctx context.Context, index, mapping string) error {
+	u := "/" + strings.ToLower(index) + "/_mapping"
+	_, err := c.request(ctx, "PUT", u, strings.NewReader(mapping), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "PutMapping")
+	}
+	return nil
+}
+
+// PutFieldIgnoreMalformed sets ignore_malformed on an existing field so that a value of the wrong type, eg text in a
+// numeric field, is skipped instead of the whole document being rejected. This matters for bulk loads where one bad
+// value would otherwise fail the document. Only numeric, date, ip and geo fields support the parameter. Nested
+// object fields and multi-fields are specified with a dotted path, eg "stats.views" or "code.numeric".
+func (c *Client) PutFieldIgnoreMalformed(ctx context.Context, index, field string, ignore bool) error {
+
+	m, err := c.GetMapping(ctx, index)
+	if err != nil {
+		return errors.Wrap(err, "PutFieldIgnoreMalformed")
+	}
+	steps, ok := fieldSteps(m.Properties, field)
+	if !ok {
+		return errors.Errorf("PutFieldIgnoreMalformed - no field %q in index %s", field, index)
+	}
+
+	// Build the mapping from the inside out, wrapping each parent object in properties, or, for a multi-field, its
+	// parent field in fields along with the parent's type, which the update must repeat
+	last := steps[len(steps)-1].p
+	t := last.Type
+	if t == "" && last.Properties != nil {
+		t = "object"
+	}
+	var v interface{} = map[string]interface{}{"type": t, "ignore_malformed": ignore}
+	for i := len(steps) - 1; i > 0; i-- {
+		if steps[i].multi {
+			v = map[string]interface{}{"type": steps[i-1].p.Type, "fields": map[string]interface{}{steps[i].name: v}}
+			continue
+		}
+		v = map[string]interface{}{"properties": map[string]interface{}{steps[i].name: v}}
+	}
+	v = map[string]interface{}{"properties": map[string]interface{}{steps[0].name: v}}
+
+	xb, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	if err := c.PutMapping(ctx, index, string(xb)); err != nil {
+		return errors.Wrap(err, "PutFieldIgnoreMalformed")
+	}
+	return nil
+}
+
+// fieldStep is a segment of a dotted field path and the mapping it names. Multi is set if the segment is a
+// multi-field, under the fields of the segment before, rather than a property of an object.
+type fieldStep struct {
+	name  string
+	p     Property
+	multi bool
+}
+
+// fieldSteps looks up each segment of the dotted field path in props, and reports whether the field exists
+func fieldSteps(props map[string]Property, path string) ([]fieldStep, bool) {
+	var steps []fieldStep
+	var fields map[string]Property
+	for _, name := range strings.Split(path, ".") {
+		p, ok := props[name]
+		multi := false
+		if !ok {
+			if p, ok = fields[name]; !ok {
+				return nil, false
+			}
+			multi = true
+		}
+		steps = append(steps, fieldStep{name: name, p: p, multi: multi})
+		props, fields = p.Properties, p.Fields
+	}
+	return steps, true
+}
+
+// uncreatableSettings are index settings that Elasticsearch sets itself and rejects when creating an index
+var uncreatableSettings = []string{"uuid", "creation_date", "provided_name", "version", "resize", "verified_before_close"}
+
+// CloneSchema creates an empty index, dst, with the same mappings and settings as src. Settings that describe the
+// source index itself, such as its uuid and creation date, are not copied, and nor are aliases.
+func (c *Client) CloneSchema(ctx context.Context, src, dst string) error {
+
+	s := strings.ToLower(src)
+	xb, err := c.request(ctx, "GET", "/"+s, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "CloneSchema")
+	}
+
+	var m map[string]struct {
+		Mappings json.RawMessage `json:"mappings"`
+		Settings struct {
+			Index map[string]interface{} `json:"index"`
+		} `json:"settings"`
+	}
+	err = json.Unmarshal(xb, &m)
+	if err != nil {
+		return errors.Wrap(err, "Unmarshal")
+	}
+	def, ok := m[s]
+	if !ok {
+		return errors.Errorf("CloneSchema - no index %s in response", s)
+	}
+
+	for _, k := range uncreatableSettings {
+		delete(def.Settings.Index, k)
+	}
+
+	body := map[string]interface{}{
+		"settings": map[string]interface{}{"index": def.Settings.Index},
+	}
+	if len(def.Mappings) > 0 {
+		body["mappings"] = def.Mappings
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	err = c.CreateIndexWithBody(ctx, dst, string(b))
+	if err != nil {
+		return errors.Wrap(err, "CloneSchema")
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package elastic_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestDiffMappings(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /articles_v1/_mapping": fixture("mapping_a.json"),
+		"GET /articles_v2/_mapping": fixture("mapping_b.json"),
+	})
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	xd, err := e.DiffMappings(ctx, "articles_v1", "articles_v2")
+	is.NoErr(err)
+	is.Equal(xd, []elastic.MappingDiff{
+		{Field: "author.email", TypeA: "keyword"},
+		{Field: "published", TypeB: "date"},
+		{Field: "views", TypeA: "integer", TypeB: "long"},
+	})
+}
+
+func TestPutFieldIgnoreMalformed(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write(fixture("mapping_b.json"))
+			return
+		}
+		is.Equal(r.Method+" "+r.URL.Path, "PUT /articles_v2/_mapping")
+		xb, _ := ioutil.ReadAll(r.Body)
+		body = string(xb)
+		w.Write([]byte(`{"acknowledged":true}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.PutFieldIgnoreMalformed(ctx, "articles_v2", "views", true))
+	is.Equal(body, `{"properties":{"views":{"ignore_malformed":true,"type":"long"}}}`)
+
+	is.True(e.PutFieldIgnoreMalformed(ctx, "articles_v2", "nope", true) != nil) // unknown field
+
+	// An object property is wrapped in properties, and a multi-field in the fields of its parent
+	is.NoErr(e.PutFieldIgnoreMalformed(ctx, "articles_v2", "author.name", false))
+	is.Equal(body, `{"properties":{"author":{"properties":{"name":{"ignore_malformed":false,"type":"text"}}}}}`)
+	is.NoErr(e.PutFieldIgnoreMalformed(ctx, "articles_v2", "title.keyword", true))
+	is.Equal(body, `{"properties":{"title":{"fields":{"keyword":{"ignore_malformed":true,"type":"keyword"}},"type":"text"}}}`)
+	is.True(e.PutFieldIgnoreMalformed(ctx, "articles_v2", "title.keyword.x", true) != nil)
+}
+
+func TestCloneSchema(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var created map[string]map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /articles_v1":
+			w.Write([]byte(`{"articles_v1":{
+				"aliases":{"articles":{}},
+				"mappings":{"properties":{"title":{"type":"text"}}},
+				"settings":{"index":{"number_of_shards":"3","number_of_replicas":"1","uuid":"abc",
+					"creation_date":"1527290225","provided_name":"articles_v1","version":{"created":"7100299"}}}
+			}}`))
+		case "PUT /articles_v2":
+			is.NoErr(json.NewDecoder(r.Body).Decode(&created))
+			w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	is.NoErr(elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).CloneSchema(ctx, "articles_v1", "articles_v2"))
+	is.Equal(created["settings"], map[string]interface{}{
+		"index": map[string]interface{}{"number_of_shards": "3", "number_of_replicas": "1"},
+	})
+	is.True(created["mappings"] != nil)
+	is.True(created["aliases"] == nil)
+}
+
+func TestGetMapping(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /articles_v1/_mapping": fixture("mapping_a.json"),
+		"GET /articles_v2/_mapping": []byte(`{"articles_v2":{"mappings":{"dynamic":"strict",` +
+			`"properties":{"title":{"type":"text","analyzer":"english"}}}}}`),
+	})
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	m, err := e.GetMapping(ctx, "articles_v1")
+	is.NoErr(err)
+	is.Equal(m.Properties["title"].Fields["keyword"].Type, "keyword") // typed 6.x mapping
+	is.Equal(m.Properties["author"].Properties["email"].Type, "keyword")
+
+	m, err = e.GetMapping(ctx, "articles_v2")
+	is.NoErr(err)
+	is.Equal(m.Dynamic, "strict")
+	is.Equal(m.Properties["title"].Analyzer, "english")
+}
+
+func TestCreateIndexWith(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.Method+" "+r.URL.Path, "PUT /articles")
+		xb, _ := ioutil.ReadAll(r.Body)
+		body = string(xb)
+		w.Write([]byte(`{"acknowledged":true}`))
+	}))
+	defer s.Close()
+
+	replicas := 0
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.CreateIndexWith(ctx, "articles", elastic.IndexDefinition{
+		Settings: &elastic.IndexSettings{NumberOfShards: 1, NumberOfReplicas: &replicas},
+		Mappings: &elastic.Mapping{Properties: map[string]elastic.Property{
+			"title": {Type: "text", Fields: map[string]elastic.Property{"raw": {Type: "keyword"}}},
+		}},
+	}))
+	is.Equal(body, `{"settings":{"number_of_shards":1,"number_of_replicas":0},`+
+		`"mappings":{"properties":{"title":{"type":"text","fields":{"raw":{"type":"keyword"}}}}}}`)
+}
+
+func TestFieldCaps(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var query string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Path + "?" + r.URL.Query().Get("fields")
+		w.Write([]byte(`{"indices":["articles_v1","articles_v2"],"fields":{` +
+			`"title":{"text":{"type":"text","searchable":true,"aggregatable":false}},` +
+			`"views":{"integer":{"type":"integer","searchable":true,"aggregatable":true,"indices":["articles_v1"]},` +
+			`"long":{"type":"long","searchable":true,"aggregatable":true,"indices":["articles_v2"]}}}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	fc, err := e.FieldCaps(ctx, "articles_*", []string{"title", "views"})
+	is.NoErr(err)
+	is.Equal(query, "/articles_*/_field_caps?title,views")
+	is.Equal(len(fc.Indices), 2)
+	is.True(!fc.Fields["title"]["text"].Aggregatable)
+	is.Equal(fc.Fields["views"]["long"].Indices, []string{"articles_v2"})
+}
+
+func TestGetFieldMapping(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /articles/_mapping/field/author.name,title": []byte(`{"articles":{"mappings":{` +
+			`"author.name":{"full_name":"author.name","mapping":{"name":{"type":"keyword","ignore_above":256}}},` +
+			`"title":{"full_name":"title","mapping":{"title":{"type":"text","analyzer":"english"}}}}}}`),
+		"GET /legacy/_mapping/field/title": []byte(`{"legacy":{"mappings":{"_doc":{` +
+			`"title":{"full_name":"title","mapping":{"title":{"type":"text"}}}}}}}`),
+	})
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	m, err := e.GetFieldMapping(ctx, "articles", "author.name", "title")
+	is.NoErr(err)
+	is.Equal(m["articles"]["author.name"], elastic.Property{Type: "keyword", IgnoreAbove: 256})
+	is.Equal(m["articles"]["title"].Analyzer, "english")
+
+	m, err = e.GetFieldMapping(ctx, "legacy", "title") // 6.x, nested under the type
+	is.NoErr(err)
+	is.Equal(m["legacy"]["title"].Type, "text")
+}
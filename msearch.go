@@ -0,0 +1,107 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MultiSearchError reports the searches that failed in a multi-search, keyed by their position in the requests.
+// The results of the other searches are still returned.
+type MultiSearchError struct {
+	Errors map[int]error
+}
+
+func (e *MultiSearchError) Error() string {
+	xi := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		xi = append(xi, i)
+	}
+	sort.Ints(xi)
+	s := strconv.Itoa(len(e.Errors)) + " searches failed"
+	if len(xi) > 0 {
+		s += " - first: [" + strconv.Itoa(xi[0]) + "] " + e.Errors[xi[0]].Error()
+	}
+	return s
+}
+
+// MultiSearch runs several searches in one round trip with the multi search API. The results are in the same order
+// as the requests. If any search fails the error is a *MultiSearchError and the result for that search is empty,
+// while the results of the others are still returned.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-multi-search.html
+func (c *Client) MultiSearch(ctx context.Context, requests []SearchRequest, opts ...RequestOption) ([]SearchResult, error) {
+
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	for _, r := range requests {
+		head := map[string]string{}
+		if r.Index != "" && r.PIT == nil {
+			head["index"] = strings.ToLower(r.Index)
+		}
+		xb, err := json.Marshal(head)
+		if err != nil {
+			return nil, errors.Wrap(err, "Marshal")
+		}
+		buf.Write(xb)
+		buf.WriteByte('\n')
+		xb, err = json.Marshal(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "Marshal")
+		}
+		buf.Write(xb)
+		buf.WriteByte('\n')
+	}
+
+	headers := []header{
+		{Key: "Content-Type", Value: "application/x-ndjson"},
+	}
+
+	xb, err := c.request(ctx, "POST", withOptions("/_msearch", opts), &buf, headers)
+	if err != nil {
+		return nil, errors.Wrap(err, "MultiSearch")
+	}
+
+	var r struct {
+		Responses []json.RawMessage `json:"responses"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	if len(r.Responses) != len(requests) {
+		return nil, errors.Errorf("MultiSearch - %d responses for %d requests", len(r.Responses), len(requests))
+	}
+
+	results := make([]SearchResult, len(requests))
+	failed := map[int]error{}
+	for i, raw := range r.Responses {
+		var item struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, errors.Wrap(err, "Unmarshal")
+		}
+		if len(item.Error) > 0 {
+			e := &Error{StatusCode: item.Status, Body: raw}
+			e.Type, e.Reason, e.Index = jsonError(raw)
+			failed[i] = searchError(e)
+			continue
+		}
+		if err := json.Unmarshal(raw, &results[i]); err != nil {
+			return nil, errors.Wrap(err, "Unmarshal")
+		}
+	}
+
+	if len(failed) > 0 {
+		return results, &MultiSearchError{Errors: failed}
+	}
+	return results, nil
+}
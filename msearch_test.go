@@ -0,0 +1,58 @@
+package elastic_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/query"
+)
+
+func TestMultiSearch(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body, contentType string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		body, contentType = string(xb), r.Header.Get("Content-Type")
+		w.Write([]byte(`{"took":3,"responses":[
+			{"took":1,"hits":{"total":{"value":2,"relation":"eq"},"hits":[{"_id":"1"},{"_id":"2"}]},"status":200},
+			{"error":{"type":"index_not_found_exception","reason":"no such index [missing]","index":"missing"},"status":404},
+			{"took":1,"hits":{"total":{"value":0,"relation":"eq"},"hits":[]},"status":200}
+		]}`))
+	}))
+	defer s.Close()
+
+	size := 0
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	xr, err := e.MultiSearch(ctx, []elastic.SearchRequest{
+		{Index: "articles", Query: query.Term("status", "published")},
+		{Index: "missing"},
+		{Size: &size},
+	})
+	is.Equal(contentType, "application/x-ndjson")
+	is.Equal(body, `{"index":"articles"}
+{"query":{"term":{"status":{"value":"published"}}}}
+{"index":"missing"}
+{}
+{}
+{"size":0}
+`)
+
+	// The other results are returned with the failure
+	is.Equal(len(xr), 3)
+	is.Equal(len(xr[0].Hits.Hits), 2)
+	is.Equal(xr[2].Hits.Total.Value, int64(0))
+
+	var me *elastic.MultiSearchError
+	is.True(errors.As(err, &me))
+	is.Equal(len(me.Errors), 1)
+	is.True(errors.Is(me.Errors[1], elastic.ErrIndexNotFound))
+	is.True(elastic.IsNotFound(me.Errors[1]))
+}
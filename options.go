@@ -0,0 +1,540 @@
+package elastic
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Option configures a Client when it is created
+type Option func(*Client)
+
+// WithBasicAuth authenticates every request with a username and password
+func WithBasicAuth(user, pass string) Option {
+	return func(c *Client) {
+		c.user = user
+		c.pass = pass
+		c.auth = ""
+	}
+}
+
+// WithAPIKey authenticates every request with an API key, as the base64 encoded "id:api_key" returned in the
+// "encoded" field when the key is created, and shown when creating a key in Elastic Cloud. It replaces basic auth.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-create-api-key.html
+func WithAPIKey(encoded string) Option {
+	return func(c *Client) {
+		c.auth = "ApiKey " + encoded
+		c.user, c.pass = "", ""
+	}
+}
+
+// WithBearerToken authenticates every request with an OAuth2 access token, eg one from the get token API or a JWT
+// realm. It replaces basic auth.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.auth = "Bearer " + token
+		c.user, c.pass = "", ""
+	}
+}
+
+// WithHTTPClient sends requests with hc rather than a default http.Client. WithTransport and WithTimeout, if also
+// given, override its Transport and Timeout; hc itself is not modified.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTransport sends requests with rt, eg an *http.Transport tuned for connection pooling, rather than
+// http.DefaultTransport
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+// WithTimeout limits the time taken by each attempt at a request, including reading the response body. There is no
+// timeout by default; use a context to limit a call as a whole, retries included.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithTLSConfig sets the TLS configuration for https hosts, replacing any set up by earlier TLS options. It applies
+// to the default transport, or to an *http.Transport given with WithTransport or WithHTTPClient, which is copied
+// rather than modified.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = cfg.Clone()
+	}
+}
+
+// WithRootCAs verifies server certificates against pool rather than the system roots, eg for a cluster with
+// self-signed certificates. Use LoadCACerts to build the pool from a PEM bundle.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Client) {
+		c.tls().RootCAs = pool
+	}
+}
+
+// WithClientCertificate presents cert to servers that require TLS client authentication. Load it with
+// tls.LoadX509KeyPair.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *Client) {
+		cfg := c.tls()
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+}
+
+// WithInsecureSkipVerify turns off verification of server certificates. This leaves connections open to
+// interception and should only be used for testing; prefer WithRootCAs for self-signed certificates.
+func WithInsecureSkipVerify() Option {
+	return func(c *Client) {
+		c.tls().InsecureSkipVerify = true
+	}
+}
+
+// tls returns the client's TLS configuration, creating it if necessary
+func (c *Client) tls() *tls.Config {
+	if c.tlsConfig == nil {
+		c.tlsConfig = &tls.Config{}
+	}
+	return c.tlsConfig
+}
+
+// LoadCACerts reads a bundle of PEM encoded CA certificates from file, for WithRootCAs
+func LoadCACerts(file string) (*x509.CertPool, error) {
+	xb, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "LoadCACerts")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(xb) {
+		return nil, errors.New("LoadCACerts - no certificates found in " + file)
+	}
+	return pool, nil
+}
+
+// WithHosts adds more node urls to spread requests across, as with NewClientWithHosts
+func WithHosts(urls ...string) Option {
+	return func(c *Client) {
+		c.pool.add(urls...)
+	}
+}
+
+// WithDeadNodeCooldown sets how long a node that could not be connected to is passed over before it is tried again,
+// one minute by default. A dead node is still tried when no live node is left.
+func WithDeadNodeCooldown(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.pool.cooldown = d
+		}
+	}
+}
+
+// WithCircuitBreaker fails requests fast, rather than sending them to a cluster that is down. After threshold
+// consecutive connection errors or 5xx responses from a node it is skipped for cooldown, then a single request is
+// let through to probe it: success returns the node to use, failure skips it for another cooldown. When every node
+// is skipped a request fails straight away with an error that satisfies errors.Is(err, ErrCircuitOpen). The circuit
+// breaker is off by default.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		if threshold > 0 && cooldown > 0 {
+			c.pool.breakerThreshold = threshold
+			c.pool.breakerCooldown = cooldown
+		}
+	}
+}
+
+// WithSniffInterval discovers the cluster's nodes every d with Sniff, so that requests follow nodes as they join
+// and leave. Sniffing is off by default. Close the client to stop it.
+func WithSniffInterval(d time.Duration) Option {
+	return func(c *Client) {
+		c.sniffInterval = d
+	}
+}
+
+// WithGzip compresses request bodies with gzip and asks for gzip-compressed responses, which are decompressed
+// transparently. Elasticsearch only accepts compressed requests when http.compression is enabled, which is the
+// default from 5.0. This is most worthwhile for large Batch payloads sent over slow links.
+func WithGzip() Option {
+	return func(c *Client) {
+		c.gzip = true
+	}
+}
+
+// WithGzipMinSize turns on WithGzip, but sends request bodies smaller than n bytes uncompressed, as compressing a
+// small search or document body costs more CPU than it saves in transfer. Bodies of unknown size, eg from
+// BatchReader, are always compressed.
+func WithGzipMinSize(n int) Option {
+	return func(c *Client) {
+		c.gzip = true
+		c.gzipMinSize = n
+	}
+}
+
+// WithErrorBodyLimit sets the most bytes of an error response that are read when extracting the error reason. The
+// default is 64KB.
+func WithErrorBodyLimit(n int64) Option {
+	return func(c *Client) {
+		c.errorBodyLimit = n
+	}
+}
+
+// WithMaxResponseSize fails a request whose response body is larger than n bytes, after decompression, with
+// ErrResponseTooLarge, rather than reading it all into memory, eg to guard against a search that returns far more
+// than expected. There is no limit by default. Streamed responses, from SearchStream and RawRequest, are not limited
+// as they are read incrementally.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		if n >= 0 {
+			c.maxResponseSize = n
+		}
+	}
+}
+
+// WithServerless configures the client for an Elastic serverless project. Every request carries the
+// Elastic-Api-Version header that serverless requires, bulk and update requests use the typeless endpoints, and
+// CheckOK uses the root endpoint as serverless has no cluster health API.
+func WithServerless() Option {
+	return func(c *Client) {
+		c.serverless = true
+	}
+}
+
+// WithVersion sets the major version of the cluster, eg 6, so that the client builds paths for it without first
+// asking the cluster. By default the version is detected, with Info, the first time a request depends on it.
+func WithVersion(major int) Option {
+	return func(c *Client) {
+		if major > 0 {
+			c.version = int32(major)
+		}
+	}
+}
+
+// WithOpenSearch configures the client for an OpenSearch cluster without detecting it. OpenSearch is otherwise
+// detected, with Info, the first time a request depends on it. Document requests use the typeless endpoints, and
+// OpenPointInTime, the SQL methods and the async search methods use the OpenSearch plugin APIs and parse their
+// responses into the same results. The lifecycle policy methods have no OpenSearch equivalent; its Index State
+// Management plugin works differently.
+func WithOpenSearch() Option {
+	return func(c *Client) {
+		c.openSearch = 1
+	}
+}
+
+// WithMaxInFlight caps the number of requests the client sends at once at n, eg so that several ingestion jobs
+// sharing a client don't overwhelm a small cluster. A request waits for a free slot, or until its context is done.
+// Each attempt at a request takes a slot, which is freed while it waits to retry.
+func WithMaxInFlight(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.inFlight = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithRateLimit caps the client at perSec requests per second, averaged over time, with bursts of up to a second's
+// worth after a quiet spell. A request waits until sending it would not exceed the rate, or until its context is
+// done. Each attempt at a request counts towards the rate. See BulkRateLimit to limit the documents, rather than
+// requests, sent by a BulkIndexer.
+func WithRateLimit(perSec float64) Option {
+	return func(c *Client) {
+		if perSec > 0 {
+			c.limiter = newTokenBucket(perSec)
+		}
+	}
+}
+
+// WithMaxRetries sets how many times a failed request is retried, 3 by default. Requests are retried on a 429, 502,
+// 503 or 504 response, or a connection error, once every host has been tried. 0 turns retries off.
+func WithMaxRetries(n int) Option {
+	return func(c *Client) {
+		if n >= 0 {
+			c.maxRetries = n
+		}
+	}
+}
+
+// WithMaxRetryTime caps the total time spent on a request, including backoff, beyond which it is no longer retried.
+// The default is 30 seconds.
+func WithMaxRetryTime(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.maxRetryTime = d
+		}
+	}
+}
+
+// WithRetryBackoff sets the delay before the first retry, which doubles for each retry after up to max. The delays
+// are jittered, and a Retry-After header on the response takes precedence. The defaults are 100ms and 5s.
+func WithRetryBackoff(initial, max time.Duration) Option {
+	return func(c *Client) {
+		if initial > 0 && max >= initial {
+			c.retryBackoff = initial
+			c.retryBackoffMax = max
+		}
+	}
+}
+
+// WithConflictRetries sets how many times UpsertDoc and UpdateUpsert re-apply an update that conflicts with a
+// concurrent change to the document, 3 by default. 0 turns retries off, so the first conflict is returned as
+// ErrConflict.
+func WithConflictRetries(n int) Option {
+	return func(c *Client) {
+		if n >= 0 {
+			c.conflictRetries = n
+		}
+	}
+}
+
+// WithHook adds a Hook that is called around every attempt at a request, eg to log, trace or measure requests. Hooks
+// are called in the order they are added.
+func WithHook(h Hook) Option {
+	return func(c *Client) {
+		c.hooks = append(c.hooks, h)
+	}
+}
+
+// WithLogger writes debug output, the headers of each request, with any credentials redacted, and the body of each
+// error response, to l. There is no debug output by default.
+func WithLogger(l Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// RequestOption sets a parameter on a single call
+type RequestOption func(*requestOptions)
+
+// requestOptions holds the parameters set by RequestOptions
+type requestOptions struct {
+	params     url.Values
+	detectNoop *bool
+}
+
+// newRequestOptions applies opts
+func newRequestOptions(opts []RequestOption) *requestOptions {
+	o := &requestOptions{params: url.Values{}}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// RequireAlias makes a write fail unless the target index name is an alias, so a write meant for an alias can't
+// auto-create a plain index of the same name. Applies to IndexDoc and Batch.
+func RequireAlias() RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("require_alias", "true")
+	}
+}
+
+// OpTypeCreate makes a write only create a document, failing with ErrConflict if one with the id exists, rather
+// than replacing it. Writes to a data stream must create documents. Applies to IndexDoc and IndexDocStruct.
+func OpTypeCreate() RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("op_type", "create")
+	}
+}
+
+// AllowPartialSearchResults sets whether a search returns the hits from the shards that succeeded when other shards
+// fail or are unavailable, which is the default, or fails with a *SearchPhaseError. Applies to Search and SearchWith.
+func AllowPartialSearchResults(allow bool) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("allow_partial_search_results", strconv.FormatBool(allow))
+	}
+}
+
+// Preference controls which shard copies serve a read. "_primary" reads from the primary shard, and so sees writes
+// that have not yet reached the replicas, for strongly consistent reads after a write; it is only supported before
+// Elasticsearch 7.0. From 7.0 use "_local" to prefer copies on the node that receives the request, or a custom
+// string, eg a session id, which routes reads with the same value to the same copies; to read a write back, send the
+// write with Refresh("wait_for") instead. Applies to QueryDoc and GetDoc.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-search.html#search-preference
+func Preference(p string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("preference", p)
+	}
+}
+
+// DetectNoop sets whether an update that leaves the document unchanged is skipped, which is the default. With
+// detection off the document is always rewritten and its _version incremented. Applies to UpdateDoc, UpdateUpsert
+// and UpsertDoc.
+func DetectNoop(detect bool) RequestOption {
+	return func(o *requestOptions) {
+		o.detectNoop = &detect
+	}
+}
+
+// RetryOnConflict sets how many times an update is re-applied when it conflicts with a concurrent change to the
+// document, before failing with ErrConflict. Applies to UpdateDoc, UpdateDocStruct, UpdateUpsert and UpsertDoc,
+// where it takes precedence over WithConflictRetries.
+func RetryOnConflict(n int) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("retry_on_conflict", strconv.Itoa(n))
+	}
+}
+
+// IfSeqNo makes a write fail with ErrConflict unless the document's last change had sequence number n, as returned
+// in DocResponse.SeqNo. Use it with IfPrimaryTerm so a read-modify-write does not overwrite a concurrent change.
+// Applies to IndexDoc, UpdateDoc and DeleteDoc.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/optimistic-concurrency-control.html
+func IfSeqNo(n int64) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("if_seq_no", strconv.FormatInt(n, 10))
+	}
+}
+
+// IfPrimaryTerm makes a write fail with ErrConflict unless the document's last change had primary term n, as
+// returned in DocResponse.PrimaryTerm. Use it with IfSeqNo.
+func IfPrimaryTerm(n int64) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("if_primary_term", strconv.FormatInt(n, 10))
+	}
+}
+
+// Version makes a write fail with ErrConflict unless the document is at version v, or, with an external
+// VersionType, sets the version of the document. Before Elasticsearch 6.7 this is the only form of optimistic
+// concurrency control; prefer IfSeqNo and IfPrimaryTerm since. Applies to IndexDoc and DeleteDoc.
+func Version(v int64) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("version", strconv.FormatInt(v, 10))
+	}
+}
+
+// VersionType sets how Version is checked: "internal", the default, or "external" or "external_gte" for versions
+// kept by another system, such as a primary database
+func VersionType(t string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("version_type", t)
+	}
+}
+
+// WaitForCompletion sets whether a long running request waits for its result, which is the default. Without waiting
+// the response holds the id of a task to check on later. Applies to DeleteByQuery, UpdateByQuery and ForceMerge.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/tasks.html
+func WaitForCompletion(wait bool) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("wait_for_completion", strconv.FormatBool(wait))
+	}
+}
+
+// Pipeline sends documents through an ingest pipeline, see PutPipeline, before they are indexed. Applies to
+// IndexDoc, IndexDocStruct, Batch and BatchReader, where it is the default for actions that don't name their own.
+func Pipeline(id string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("pipeline", id)
+	}
+}
+
+// DryRun checks a request without carrying it out. Applies to Rollover.
+func DryRun() RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("dry_run", "true")
+	}
+}
+
+// Refresh controls when a write becomes visible to search: "true" refreshes the affected shards immediately, which
+// is costly if done often, "wait_for" waits for the next scheduled refresh before responding, and "false", the
+// default, returns straight away. Use "wait_for" where a search must see a write just made, eg in tests. Applies to
+// IndexDoc, UpdateDoc, DeleteDoc and Batch.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-refresh.html
+func Refresh(v string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("refresh", v)
+	}
+}
+
+// Routing sends the request to the shard for the routing value rather than for the document id. A document indexed
+// with a routing value must be read, updated and deleted with the same value. Applies to IndexDoc, UpdateDoc,
+// DeleteDoc, GetDoc, Batch, where it is the default for actions, and Search, where it limits the shards searched.
+func Routing(r string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("routing", r)
+	}
+}
+
+// WaitForActiveShards sets how many copies of each shard, eg "2" or "all", must be active before a write goes
+// ahead, 1, the primary, by default. The write times out if they don't become active. Applies to IndexDoc, UpdateDoc,
+// DeleteDoc and Batch, and to OpenIndex, CloseIndex, ShrinkIndex, SplitIndex and CloneIndex, which wait for that
+// many copies of each shard to start.
+func WaitForActiveShards(n string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("wait_for_active_shards", n)
+	}
+}
+
+// SourceIncludes returns only the listed fields of each document's source, which may use wildcards, eg "author.*".
+// Applies to GetDoc, MultiGet, Search and SearchWith.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/mapping-source-field.html
+func SourceIncludes(fields ...string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("_source_includes", strings.Join(fields, ","))
+	}
+}
+
+// SourceExcludes leaves the listed fields, which may use wildcards, out of each document's source, eg to skip large
+// blobs that are rarely needed. It takes precedence over SourceIncludes. Applies to GetDoc, MultiGet, Search and
+// SearchWith.
+func SourceExcludes(fields ...string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("_source_excludes", strings.Join(fields, ","))
+	}
+}
+
+// StoredFields returns the listed fields, which must be mapped with "store": true, in GetResult.Fields or
+// Hit.Fields. The source is then not returned unless asked for with SourceIncludes. Applies to GetDoc, MultiGet,
+// Search and SearchWith.
+func StoredFields(fields ...string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("stored_fields", strings.Join(fields, ","))
+	}
+}
+
+// TermVectorFields limits the fields returned by TermVectors, which may use wildcards
+func TermVectorFields(fields ...string) RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("fields", strings.Join(fields, ","))
+	}
+}
+
+// TermStatistics adds the document frequency and total term frequency of each term, across the shard, to the result
+// of TermVectors. They are costly to compute so are off by default.
+func TermStatistics() RequestOption {
+	return func(o *requestOptions) {
+		o.params.Set("term_statistics", "true")
+	}
+}
+
+// withOptions appends the query string for opts to path
+func withOptions(path string, opts []RequestOption) string {
+	return newRequestOptions(opts).path(path)
+}
+
+// path appends the query string for the options to path
+func (o *requestOptions) path(path string) string {
+	if len(o.params) == 0 {
+		return path
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + o.params.Encode()
+}
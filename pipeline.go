@@ -0,0 +1,118 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// IngestPipeline is an ingest pipeline, a list of processors that transform documents before they are indexed. Each
+// processor is a single entry map of the processor type to its settings, eg
+// {"set": {"field": "indexed_at", "value": "{{_ingest.timestamp}}"}}.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/ingest.html
+type IngestPipeline struct {
+	Description string                   `json:"description,omitempty"`
+	Processors  []map[string]interface{} `json:"processors"`
+	OnFailure   []map[string]interface{} `json:"on_failure,omitempty"`
+}
+
+// SimulatedDoc is a document as it would be indexed after going through a pipeline. Error is set instead if the
+// pipeline failed.
+type SimulatedDoc struct {
+	Source json.RawMessage
+	Error  *BulkError
+}
+
+// PutPipeline creates or replaces an ingest pipeline
+func (c *Client) PutPipeline(ctx context.Context, id string, p IngestPipeline) error {
+
+	xb, err := json.Marshal(p)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	_, err = c.request(ctx, "PUT", "/_ingest/pipeline/"+id, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "PutPipeline")
+	}
+	return nil
+}
+
+// GetPipeline returns an ingest pipeline. A pipeline that does not exist is reported with ErrNotFound.
+func (c *Client) GetPipeline(ctx context.Context, id string) (*IngestPipeline, error) {
+
+	xb, err := c.request(ctx, "GET", "/_ingest/pipeline/"+id, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetPipeline")
+	}
+
+	var r map[string]IngestPipeline
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	p, ok := r[id]
+	if !ok {
+		return nil, errors.Wrap(ErrNotFound, "GetPipeline - "+id)
+	}
+	return &p, nil
+}
+
+// DeletePipeline deletes an ingest pipeline
+func (c *Client) DeletePipeline(ctx context.Context, id string) error {
+	_, err := c.request(ctx, "DELETE", "/_ingest/pipeline/"+id, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "DeletePipeline")
+	}
+	return nil
+}
+
+// SimulatePipeline runs docs, each marshaled as a document source, through a pipeline without indexing them, and
+// returns the results in the same order. A document that fails in the pipeline has Error set rather than failing
+// the call.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/simulate-pipeline-api.html
+func (c *Client) SimulatePipeline(ctx context.Context, id string, docs ...interface{}) ([]SimulatedDoc, error) {
+
+	type source struct {
+		Source interface{} `json:"_source"`
+	}
+	body := struct {
+		Docs []source `json:"docs"`
+	}{Docs: make([]source, len(docs))}
+	for i, d := range docs {
+		body.Docs[i].Source = d
+	}
+	xb, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	xb, err = c.request(ctx, "POST", "/_ingest/pipeline/"+id+"/_simulate", bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "SimulatePipeline")
+	}
+
+	var r struct {
+		Docs []json.RawMessage `json:"docs"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	xd := make([]SimulatedDoc, len(r.Docs))
+	for i, raw := range r.Docs {
+		var d struct {
+			Doc struct {
+				Source json.RawMessage `json:"_source"`
+			} `json:"doc"`
+			Error *BulkError `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return nil, errors.Wrap(err, "Unmarshal")
+		}
+		xd[i] = SimulatedDoc{Source: d.Doc.Source, Error: d.Error}
+	}
+
+	return xd, nil
+}
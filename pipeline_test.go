@@ -0,0 +1,76 @@
+package elastic_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestPipelines(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	requests := map[string]string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		requests[r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery] = string(xb)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /_ingest/pipeline/stamp":
+			w.Write([]byte(`{"stamp":{"description":"adds a timestamp","processors":[{"set":{"field":"indexed_at","value":"{{_ingest.timestamp}}"}}]}}`))
+		case "GET /_ingest/pipeline/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{}`))
+		case "POST /_ingest/pipeline/stamp/_simulate":
+			w.Write([]byte(`{"docs":[
+				{"doc":{"_index":"_index","_id":"_id","_source":{"title":"one","indexed_at":"2021-01-01T00:00:00Z"}}},
+				{"error":{"type":"illegal_argument_exception","reason":"field [title] not present"}}
+			]}`))
+		case "PUT /articles/_doc/1":
+			w.Write([]byte(`{"_id":"1","result":"created"}`))
+		default:
+			w.Write([]byte(`{"acknowledged":true}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.PutPipeline(ctx, "stamp", elastic.IngestPipeline{
+		Description: "adds a timestamp",
+		Processors: []map[string]interface{}{
+			{"set": map[string]string{"field": "indexed_at", "value": "{{_ingest.timestamp}}"}},
+		},
+	}))
+	is.Equal(requests["PUT /_ingest/pipeline/stamp?"],
+		`{"description":"adds a timestamp","processors":[{"set":{"field":"indexed_at","value":"{{_ingest.timestamp}}"}}]}`)
+
+	p, err := e.GetPipeline(ctx, "stamp")
+	is.NoErr(err)
+	is.Equal(p.Description, "adds a timestamp")
+	is.Equal(len(p.Processors), 1)
+
+	_, err = e.GetPipeline(ctx, "missing")
+	is.True(elastic.IsNotFound(err))
+
+	xd, err := e.SimulatePipeline(ctx, "stamp", map[string]string{"title": "one"}, map[string]string{})
+	is.NoErr(err)
+	is.Equal(requests["POST /_ingest/pipeline/stamp/_simulate?"], `{"docs":[{"_source":{"title":"one"}},{"_source":{}}]}`)
+	is.Equal(len(xd), 2)
+	is.Equal(string(xd[0].Source), `{"title":"one","indexed_at":"2021-01-01T00:00:00Z"}`)
+	is.True(xd[0].Error == nil)
+	is.Equal(xd[1].Error.Type, "illegal_argument_exception")
+
+	// Indexing through the pipeline
+	_, err = e.IndexDoc(ctx, "articles", "1", `{"title":"one"}`, elastic.Pipeline("stamp"))
+	is.NoErr(err)
+	_, ok := requests["PUT /articles/_doc/1?pipeline=stamp"]
+	is.True(ok)
+
+	is.NoErr(e.DeletePipeline(ctx, "stamp"))
+	_, ok = requests["DELETE /_ingest/pipeline/stamp?"]
+	is.True(ok)
+}
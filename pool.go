@@ -0,0 +1,141 @@
+package elastic
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultDeadNodeCooldown is how long a node that could not be reached is passed over
+const defaultDeadNodeCooldown = time.Minute
+
+// node is a cluster node that requests can be sent to
+type node struct {
+	url       string
+	deadUntil time.Time // zero if the node is live
+
+	failures  int       // consecutive failures, for the circuit breaker
+	openUntil time.Time // when the circuit, if open, becomes half-open
+	probing   bool      // a probe request is in flight while half-open
+}
+
+// nodePool holds the nodes of a cluster. Requests are spread across the live nodes in round-robin order. A node that
+// cannot be connected to is marked dead and only tried as a last resort until its cooldown has passed, when it is
+// tried again.
+type nodePool struct {
+	mu       sync.RWMutex
+	nodes    []*node
+	next     uint32 // round-robin counter used to pick the first node for each request
+	cooldown time.Duration
+
+	breakerThreshold int // consecutive failures that open a node's circuit, 0 if the circuit breaker is off
+	breakerCooldown  time.Duration
+}
+
+// newNodePool returns a pool of the nodes at urls
+func newNodePool(urls []string) *nodePool {
+	p := &nodePool{cooldown: defaultDeadNodeCooldown}
+	p.add(urls...)
+	return p
+}
+
+// add adds nodes to the pool, ignoring any already in it
+func (p *nodePool) add(urls ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, u := range urls {
+		u = strings.TrimSuffix(u, "/")
+		if p.find(u) == nil {
+			p.nodes = append(p.nodes, &node{url: u})
+		}
+	}
+}
+
+// set replaces the nodes with those at urls. Nodes already in the pool keep their state.
+func (p *nodePool) set(urls []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	nodes := make([]*node, 0, len(urls))
+	for _, u := range urls {
+		u = strings.TrimSuffix(u, "/")
+		n := p.find(u)
+		if n == nil {
+			n = &node{url: u}
+		}
+		nodes = append(nodes, n)
+	}
+	p.nodes = nodes
+}
+
+// find returns the node with url, or nil. The caller must hold p.mu.
+func (p *nodePool) find(url string) *node {
+	for _, n := range p.nodes {
+		if n.url == url {
+			return n
+		}
+	}
+	return nil
+}
+
+// urls returns the url of every node
+func (p *nodePool) urls() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	xs := make([]string, len(p.nodes))
+	for i, n := range p.nodes {
+		xs[i] = n.url
+	}
+	return xs
+}
+
+// order returns the nodes in the order a request should try them: the live nodes starting from the next in
+// round-robin order, then the dead nodes, those due to come back soonest first
+func (p *nodePool) order() []*node {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.nodes) == 0 {
+		return nil
+	}
+
+	start := int(atomic.AddUint32(&p.next, 1)-1) % len(p.nodes)
+	now := time.Now()
+	live := make([]*node, 0, len(p.nodes))
+	var dead []*node
+	for i := range p.nodes {
+		n := p.nodes[(start+i)%len(p.nodes)]
+		if n.deadUntil.After(now) {
+			dead = append(dead, n)
+			continue
+		}
+		live = append(live, n)
+	}
+	sort.SliceStable(dead, func(i, j int) bool {
+		return dead[i].deadUntil.Before(dead[j].deadUntil)
+	})
+
+	return append(live, dead...)
+}
+
+// markDead passes over n until the cooldown has passed
+func (p *nodePool) markDead(n *node) {
+	p.mu.Lock()
+	n.deadUntil = time.Now().Add(p.cooldown)
+	p.mu.Unlock()
+}
+
+// markLive returns n to the rotation
+func (p *nodePool) markLive(n *node) {
+	p.mu.RLock()
+	live := n.deadUntil.IsZero()
+	p.mu.RUnlock()
+	if live {
+		return
+	}
+	p.mu.Lock()
+	n.deadUntil = time.Time{}
+	p.mu.Unlock()
+}
@@ -0,0 +1,347 @@
+// Package query builds Elasticsearch query DSL clauses. Each builder marshals to the JSON for its clause so it can be
+// used wherever the DSL expects a query, eg the "query" field of a search request body.
+package query
+
+import "encoding/json"
+
+// Query is a query DSL clause
+type Query interface {
+	json.Marshaler
+
+	// Map returns the clause as it will be marshaled, eg {"term": {"status": {"value": "published"}}}
+	Map() map[string]interface{}
+}
+
+// MatchQuery is a full text match query
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/query-dsl-match-query.html
+type MatchQuery struct {
+	field string
+	text  interface{}
+	name  string
+}
+
+// Match returns a query that matches documents where field contains the analyzed text
+func Match(field string, text interface{}) *MatchQuery {
+	return &MatchQuery{field: field, text: text}
+}
+
+// Name names the clause so hits report whether it matched in Hit.MatchedQueries
+func (q *MatchQuery) Name(name string) *MatchQuery {
+	q.name = name
+	return q
+}
+
+// Map returns the clause as a map
+func (q *MatchQuery) Map() map[string]interface{} {
+	p := map[string]interface{}{"query": q.text}
+	setName(p, q.name)
+	return map[string]interface{}{"match": map[string]interface{}{q.field: p}}
+}
+
+// MarshalJSON marshals the clause
+func (q *MatchQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.Map())
+}
+
+// TermQuery matches an exact, unanalyzed value
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/query-dsl-term-query.html
+type TermQuery struct {
+	field string
+	value interface{}
+	name  string
+}
+
+// Term returns a query that matches documents where field is exactly value
+func Term(field string, value interface{}) *TermQuery {
+	return &TermQuery{field: field, value: value}
+}
+
+// Name names the clause so hits report whether it matched in Hit.MatchedQueries
+func (q *TermQuery) Name(name string) *TermQuery {
+	q.name = name
+	return q
+}
+
+// Map returns the clause as a map
+func (q *TermQuery) Map() map[string]interface{} {
+	p := map[string]interface{}{"value": q.value}
+	setName(p, q.name)
+	return map[string]interface{}{"term": map[string]interface{}{q.field: p}}
+}
+
+// MarshalJSON marshals the clause
+func (q *TermQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.Map())
+}
+
+// BoolQuery combines other queries
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/query-dsl-bool-query.html
+type BoolQuery struct {
+	must   []Query
+	should []Query
+	filter []Query
+	name   string
+}
+
+// Bool returns an empty bool query, which matches all documents until clauses are added
+func Bool() *BoolQuery {
+	return &BoolQuery{}
+}
+
+// Must adds clauses that documents must match, contributing to the score
+func (q *BoolQuery) Must(xq ...Query) *BoolQuery {
+	q.must = append(q.must, xq...)
+	return q
+}
+
+// Should adds clauses that documents should match. If there are no must clauses at least one should clause has to
+// match.
+func (q *BoolQuery) Should(xq ...Query) *BoolQuery {
+	q.should = append(q.should, xq...)
+	return q
+}
+
+// Filter adds clauses that documents must match without contributing to the score. Filter clauses can be cached.
+func (q *BoolQuery) Filter(xq ...Query) *BoolQuery {
+	q.filter = append(q.filter, xq...)
+	return q
+}
+
+// Name names the clause so hits report whether it matched in Hit.MatchedQueries
+func (q *BoolQuery) Name(name string) *BoolQuery {
+	q.name = name
+	return q
+}
+
+// Map returns the clause as a map
+func (q *BoolQuery) Map() map[string]interface{} {
+	p := map[string]interface{}{}
+	setClauses(p, "must", q.must)
+	setClauses(p, "should", q.should)
+	setClauses(p, "filter", q.filter)
+	setName(p, q.name)
+	return map[string]interface{}{"bool": p}
+}
+
+// MarshalJSON marshals the clause
+func (q *BoolQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.Map())
+}
+
+// RangeQuery matches values within a range. Bounds can be numbers, strings or date math such as "now-1d/d".
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/query-dsl-range-query.html
+type RangeQuery struct {
+	field  string
+	bounds map[string]interface{}
+	format string
+	name   string
+}
+
+// Range returns a query that matches documents where field is within the bounds set with Gt, Gte, Lt and Lte
+func Range(field string) *RangeQuery {
+	return &RangeQuery{field: field, bounds: map[string]interface{}{}}
+}
+
+// Gt sets an exclusive lower bound
+func (q *RangeQuery) Gt(v interface{}) *RangeQuery {
+	q.bounds["gt"] = v
+	return q
+}
+
+// Gte sets an inclusive lower bound
+func (q *RangeQuery) Gte(v interface{}) *RangeQuery {
+	q.bounds["gte"] = v
+	return q
+}
+
+// Lt sets an exclusive upper bound
+func (q *RangeQuery) Lt(v interface{}) *RangeQuery {
+	q.bounds["lt"] = v
+	return q
+}
+
+// Lte sets an inclusive upper bound
+func (q *RangeQuery) Lte(v interface{}) *RangeQuery {
+	q.bounds["lte"] = v
+	return q
+}
+
+// Format sets the date format used to parse string bounds on a date field, eg "yyyy-MM-dd"
+func (q *RangeQuery) Format(format string) *RangeQuery {
+	q.format = format
+	return q
+}
+
+// Name names the clause so hits report whether it matched in Hit.MatchedQueries
+func (q *RangeQuery) Name(name string) *RangeQuery {
+	q.name = name
+	return q
+}
+
+// Map returns the clause as a map
+func (q *RangeQuery) Map() map[string]interface{} {
+	p := make(map[string]interface{}, len(q.bounds)+2)
+	for k, v := range q.bounds {
+		p[k] = v
+	}
+	if q.format != "" {
+		p["format"] = q.format
+	}
+	setName(p, q.name)
+	return map[string]interface{}{"range": map[string]interface{}{q.field: p}}
+}
+
+// MarshalJSON marshals the clause
+func (q *RangeQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.Map())
+}
+
+// WildcardQuery matches terms against a pattern where * matches any characters and ? a single character
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/query-dsl-wildcard-query.html
+type WildcardQuery struct {
+	field   string
+	pattern string
+	name    string
+}
+
+// Wildcard returns a query that matches documents where a term in field matches pattern, eg "elast*"
+func Wildcard(field, pattern string) *WildcardQuery {
+	return &WildcardQuery{field: field, pattern: pattern}
+}
+
+// Name names the clause so hits report whether it matched in Hit.MatchedQueries
+func (q *WildcardQuery) Name(name string) *WildcardQuery {
+	q.name = name
+	return q
+}
+
+// Map returns the clause as a map
+func (q *WildcardQuery) Map() map[string]interface{} {
+	p := map[string]interface{}{"value": q.pattern}
+	setName(p, q.name)
+	return map[string]interface{}{"wildcard": map[string]interface{}{q.field: p}}
+}
+
+// MarshalJSON marshals the clause
+func (q *WildcardQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.Map())
+}
+
+// MoreLikeThisQuery finds documents that are similar to some text or to other documents, eg for "related
+// articles". The terms that best characterise what is liked are picked and searched for.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/query-dsl-mlt-query.html
+type MoreLikeThisQuery struct {
+	fields             []string
+	like               []interface{}
+	unlike             []interface{}
+	minTermFreq        *int
+	minDocFreq         *int
+	maxQueryTerms      int
+	minimumShouldMatch string
+	name               string
+}
+
+// MoreLikeThis returns a query for documents like those given to Like and LikeDoc, comparing fields, or the
+// default fields of the index if none are given
+func MoreLikeThis(fields ...string) *MoreLikeThisQuery {
+	return &MoreLikeThisQuery{fields: fields}
+}
+
+// Like adds text to find documents like
+func (q *MoreLikeThisQuery) Like(text string) *MoreLikeThisQuery {
+	q.like = append(q.like, text)
+	return q
+}
+
+// LikeDoc adds a document to find documents like. The document itself is not returned.
+func (q *MoreLikeThisQuery) LikeDoc(index, id string) *MoreLikeThisQuery {
+	q.like = append(q.like, map[string]string{"_index": index, "_id": id})
+	return q
+}
+
+// Unlike adds text whose terms are not picked, eg to steer away from a topic
+func (q *MoreLikeThisQuery) Unlike(text string) *MoreLikeThisQuery {
+	q.unlike = append(q.unlike, text)
+	return q
+}
+
+// UnlikeDoc adds a document whose terms are not picked
+func (q *MoreLikeThisQuery) UnlikeDoc(index, id string) *MoreLikeThisQuery {
+	q.unlike = append(q.unlike, map[string]string{"_index": index, "_id": id})
+	return q
+}
+
+// MinTermFreq sets how often a term must appear in what is liked to be picked, 2 by default. Set it to 1 when
+// liking a short text.
+func (q *MoreLikeThisQuery) MinTermFreq(n int) *MoreLikeThisQuery {
+	q.minTermFreq = &n
+	return q
+}
+
+// MinDocFreq sets how many documents a term must appear in to be picked, 5 by default
+func (q *MoreLikeThisQuery) MinDocFreq(n int) *MoreLikeThisQuery {
+	q.minDocFreq = &n
+	return q
+}
+
+// MaxQueryTerms sets how many terms are picked, 25 by default
+func (q *MoreLikeThisQuery) MaxQueryTerms(n int) *MoreLikeThisQuery {
+	q.maxQueryTerms = n
+	return q
+}
+
+// MinimumShouldMatch sets how many of the picked terms a document must match, eg "30%", the default
+func (q *MoreLikeThisQuery) MinimumShouldMatch(m string) *MoreLikeThisQuery {
+	q.minimumShouldMatch = m
+	return q
+}
+
+// Name names the clause so hits report whether it matched in Hit.MatchedQueries
+func (q *MoreLikeThisQuery) Name(name string) *MoreLikeThisQuery {
+	q.name = name
+	return q
+}
+
+// Map returns the clause as a map
+func (q *MoreLikeThisQuery) Map() map[string]interface{} {
+	p := map[string]interface{}{"like": q.like}
+	if len(q.fields) > 0 {
+		p["fields"] = q.fields
+	}
+	if len(q.unlike) > 0 {
+		p["unlike"] = q.unlike
+	}
+	if q.minTermFreq != nil {
+		p["min_term_freq"] = *q.minTermFreq
+	}
+	if q.minDocFreq != nil {
+		p["min_doc_freq"] = *q.minDocFreq
+	}
+	if q.maxQueryTerms > 0 {
+		p["max_query_terms"] = q.maxQueryTerms
+	}
+	if q.minimumShouldMatch != "" {
+		p["minimum_should_match"] = q.minimumShouldMatch
+	}
+	setName(p, q.name)
+	return map[string]interface{}{"more_like_this": p}
+}
+
+// MarshalJSON marshals the clause
+func (q *MoreLikeThisQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.Map())
+}
+
+// setName adds the _name parameter to a clause's parameters if name is set
+func setName(params map[string]interface{}, name string) {
+	if name != "" {
+		params["_name"] = name
+	}
+}
+
+// setClauses adds a list of clauses to a compound query's parameters if there are any
+func setClauses(params map[string]interface{}, key string, xq []Query) {
+	if len(xq) > 0 {
+		params[key] = xq
+	}
+}
@@ -0,0 +1,56 @@
+package query_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic/query"
+)
+
+func TestNamedClauses(t *testing.T) {
+	is := is.New(t)
+
+	q := query.Bool().
+		Must(query.Term("status", "published")).
+		Should(
+			query.Match("title", "elastic").Name("in_title"),
+			query.Match("body", "elastic").Name("in_body"),
+		)
+
+	xb, err := json.Marshal(q)
+	is.NoErr(err)
+	is.Equal(string(xb), `{"bool":{"must":[{"term":{"status":{"value":"published"}}}],`+
+		`"should":[{"match":{"title":{"_name":"in_title","query":"elastic"}}},`+
+		`{"match":{"body":{"_name":"in_body","query":"elastic"}}}]}}`)
+}
+
+func TestFilterClauses(t *testing.T) {
+	is := is.New(t)
+
+	q := query.Bool().
+		Must(query.Wildcard("title", "elast*")).
+		Filter(query.Range("published").Gte("2020-01-01").Lt("now").Format("yyyy-MM-dd"))
+
+	xb, err := json.Marshal(q)
+	is.NoErr(err)
+	is.Equal(string(xb), `{"bool":{"filter":[{"range":{"published":{"format":"yyyy-MM-dd","gte":"2020-01-01","lt":"now"}}}],`+
+		`"must":[{"wildcard":{"title":{"value":"elast*"}}}]}}`)
+}
+
+func TestMoreLikeThis(t *testing.T) {
+	is := is.New(t)
+
+	q := query.MoreLikeThis("title", "body").
+		LikeDoc("articles", "1").
+		Like("search engines").
+		UnlikeDoc("articles", "2").
+		MinTermFreq(1).
+		MaxQueryTerms(12)
+
+	xb, err := json.Marshal(q)
+	is.NoErr(err)
+	is.Equal(string(xb), `{"more_like_this":{"fields":["title","body"],`+
+		`"like":[{"_id":"1","_index":"articles"},"search engines"],"max_query_terms":12,"min_term_freq":1,`+
+		`"unlike":[{"_id":"2","_index":"articles"}]}}`)
+}
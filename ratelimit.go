@@ -0,0 +1,85 @@
+package elastic
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket limits a rate of events. Tokens are added continuously at rate per second, up to burst, and waiting
+// for more tokens than are available puts the bucket into debt so that the long-run rate is never exceeded.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time // the clock, replaced in tests
+}
+
+// newTokenBucket returns an empty bucket that fills at rate tokens per second and holds at most a second's worth
+func newTokenBucket(rate float64) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: rate, last: time.Now(), now: time.Now}
+}
+
+// reserve takes n tokens and returns how long the caller has to wait before they are available
+func (b *tokenBucket) reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// wait blocks until n tokens are available or ctx is done
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	d := b.reserve(n)
+	if d == 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttle waits until a request can be sent within the limits set by WithMaxInFlight and WithRateLimit, or ctx is
+// done. The returned func must be called when the request is complete to free its in-flight slot.
+func (c *Client) throttle(ctx context.Context) (func(), error) {
+
+	if c.inFlight != nil {
+		select {
+		case c.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if c.inFlight != nil {
+			<-c.inFlight
+		}
+	}
+
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx, 1); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
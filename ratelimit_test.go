@@ -0,0 +1,73 @@
+package elastic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// fakeClock sets the clock of b to one that only moves when advanced
+func fakeClock(b *tokenBucket) func(time.Duration) {
+	t := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.last = t
+	b.now = func() time.Time { return t }
+	return func(d time.Duration) { t = t.Add(d) }
+}
+
+func TestTokenBucketBatches(t *testing.T) {
+	is := is.New(t)
+
+	// 500 docs in batches of 100 at 1000 docs/sec; each batch waits for its own tokens and those owed by earlier ones
+	b := newTokenBucket(1000)
+	advance := fakeClock(b)
+	for _, want := range []time.Duration{100, 200, 300, 400, 500} {
+		is.Equal(b.reserve(100), want*time.Millisecond)
+	}
+
+	advance(500 * time.Millisecond)
+	is.Equal(b.reserve(0), time.Duration(0)) // debt paid off
+
+	// an idle bucket fills to at most a second's worth
+	advance(10 * time.Second)
+	is.Equal(b.reserve(1000), time.Duration(0))
+	is.Equal(b.reserve(100), 100*time.Millisecond)
+}
+
+func TestBulkIndexerLimiter(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	c := NewClient("http://localhost:9200")
+	bi := c.NewBulkIndexer("articles", BulkRateLimit(1000))
+	is.True(bi.limiter != nil)
+	is.Equal(bi.limiter.rate, 1000.0)
+	is.NoErr(bi.Close(ctx))
+
+	bi = c.NewBulkIndexer("articles")
+	is.True(bi.limiter == nil) // unlimited by default
+	is.NoErr(bi.Close(ctx))
+}
+
+func TestClientLimiter(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	c := NewClient("http://localhost:9200", WithRateLimit(100))
+	is.True(c.limiter != nil)
+	is.Equal(c.limiter.rate, 100.0)
+	is.True(NewClient("http://localhost:9200").limiter == nil)
+
+	// 20 requests at 100/sec are spread 10ms apart
+	advance := fakeClock(c.limiter)
+	for i := 1; i <= 20; i++ {
+		is.Equal(c.limiter.reserve(1), time.Duration(i)*10*time.Millisecond)
+	}
+
+	// once the debt is paid a request is let through without waiting
+	advance(210 * time.Millisecond)
+	release, err := c.throttle(ctx)
+	is.NoErr(err)
+	release()
+}
@@ -0,0 +1,219 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ByQueryResponse is the response from the reindex, update by query and delete by query APIs. When the request was
+// made with WaitForCompletion(false) only Task is set, to the id of the task running the request.
+type ByQueryResponse struct {
+	Task             string            `json:"task"`
+	Took             int64             `json:"took"`
+	TimedOut         bool              `json:"timed_out"`
+	Total            int64             `json:"total"`
+	Created          int64             `json:"created"`
+	Updated          int64             `json:"updated"`
+	Deleted          int64             `json:"deleted"`
+	Batches          int64             `json:"batches"`
+	VersionConflicts int64             `json:"version_conflicts"`
+	Noops            int64             `json:"noops"`
+	Failures         []json.RawMessage `json:"failures"`
+}
+
+// ReindexOption configures a Reindex
+type ReindexOption func(*reindexRequest)
+
+// reindexRequest is the body and parameters of a reindex request
+type reindexRequest struct {
+	Source struct {
+		Index  []string        `json:"index"`
+		Query  json.RawMessage `json:"query,omitempty"`
+		Remote *reindexRemote  `json:"remote,omitempty"`
+	} `json:"source"`
+	Dest struct {
+		Index  string `json:"index"`
+		OpType string `json:"op_type,omitempty"`
+	} `json:"dest"`
+	Script *Script `json:"script,omitempty"`
+
+	async bool
+}
+
+// reindexRemote is a remote cluster to reindex from
+type reindexRemote struct {
+	Host     string `json:"host"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// ReindexQuery copies only the documents that match query, a query DSL clause such as
+// `{"term": {"status": "published"}}`
+func ReindexQuery(query string) ReindexOption {
+	return func(r *reindexRequest) {
+		r.Source.Query = json.RawMessage(query)
+	}
+}
+
+// ReindexScript transforms each document with a painless script as it is copied, eg
+// `ctx._source.remove("legacy")`
+func ReindexScript(source string) ReindexOption {
+	return func(r *reindexRequest) {
+		r.Script = &Script{Source: source, Lang: "painless"}
+	}
+}
+
+// ReindexRemote copies from an index on another cluster, eg to migrate between major versions. The host, eg
+// "https://old-cluster:9200", must be listed in reindex.remote.whitelist on the destination cluster.
+func ReindexRemote(host, user, pass string) ReindexOption {
+	return func(r *reindexRequest) {
+		r.Source.Remote = &reindexRemote{Host: host, Username: user, Password: pass}
+	}
+}
+
+// ReindexCreate only creates documents in dest, so a document that already exists there is a version conflict
+// rather than being overwritten. Reindexing into a data stream requires it.
+func ReindexCreate() ReindexOption {
+	return func(r *reindexRequest) {
+		r.Dest.OpType = "create"
+	}
+}
+
+// ReindexAsync starts the reindex without waiting for it to finish. The response holds only the Task, which can be
+// waited on with WaitForTask.
+func ReindexAsync() ReindexOption {
+	return func(r *reindexRequest) {
+		r.async = true
+	}
+}
+
+// Reindex copies the documents in source into dest, which should be created first with the desired mapping.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-reindex.html
+func (c *Client) Reindex(ctx context.Context, source, dest string, opts ...ReindexOption) (*ByQueryResponse, error) {
+	if source == "" {
+		return nil, errors.New("Reindex - source index must be specified")
+	}
+	r, err := c.reindex(ctx, []string{source}, dest, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Reindex")
+	}
+	return r, nil
+}
+
+// ReindexMulti copies the documents from several source indices into a single destination index, eg to consolidate
+// monthly indices. Sources may include wildcard patterns such as "logs-2018-*".
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-reindex.html
+func (c *Client) ReindexMulti(ctx context.Context, sources []string, dst string, opts ...ReindexOption) (*ByQueryResponse, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("ReindexMulti - at least one source index must be specified")
+	}
+	for _, s := range sources {
+		if s == "" {
+			return nil, errors.New("ReindexMulti - source index must not be empty")
+		}
+	}
+	r, err := c.reindex(ctx, sources, dst, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "ReindexMulti")
+	}
+	return r, nil
+}
+
+// reindex builds and sends a reindex request
+func (c *Client) reindex(ctx context.Context, sources []string, dst string, opts []ReindexOption) (*ByQueryResponse, error) {
+
+	var body reindexRequest
+	for _, s := range sources {
+		body.Source.Index = append(body.Source.Index, strings.ToLower(s))
+	}
+	body.Dest.Index = strings.ToLower(dst)
+	for _, o := range opts {
+		o(&body)
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	u := "/_reindex"
+	if body.async {
+		u += "?wait_for_completion=false"
+	}
+	return c.byQuery(ctx, u, string(b))
+}
+
+// DeleteByQuery deletes the documents in index that match query, a search request body such as
+// `{"query": {"term": {"status": "draft"}}}`. Documents changed while the request runs are counted in
+// VersionConflicts rather than deleted.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-delete-by-query.html
+func (c *Client) DeleteByQuery(ctx context.Context, index, query string, opts ...RequestOption) (*ByQueryResponse, error) {
+	u := withOptions("/"+strings.ToLower(index)+"/_delete_by_query", opts)
+	r, err := c.byQuery(ctx, u, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "DeleteByQuery")
+	}
+	return r, nil
+}
+
+// UpdateByQuery runs a painless script, such as `ctx._source.views = 0`, on each document in index that matches
+// query, a search request body as for DeleteByQuery. An empty script rewrites the documents unchanged, eg to pick up
+// a mapping change. An empty query matches every document.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-update-by-query.html
+func (c *Client) UpdateByQuery(ctx context.Context, index, query, script string, opts ...RequestOption) (*ByQueryResponse, error) {
+
+	if script != "" {
+		var err error
+		query, err = setBodyFields(query, map[string]interface{}{"script": Script{Source: script, Lang: "painless"}})
+		if err != nil {
+			return nil, errors.Wrap(err, "UpdateByQuery")
+		}
+	}
+
+	u := withOptions("/"+strings.ToLower(index)+"/_update_by_query", opts)
+	r, err := c.byQuery(ctx, u, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "UpdateByQuery")
+	}
+	return r, nil
+}
+
+// byQuery posts body to a by query endpoint and parses the response
+func (c *Client) byQuery(ctx context.Context, path, body string) (*ByQueryResponse, error) {
+
+	if strings.TrimSpace(body) == "" {
+		body = "{}"
+	}
+
+	xb, err := c.request(ctx, "POST", path, strings.NewReader(body), standardHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	var r ByQueryResponse
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &r, nil
+}
+
+// EstimateReindexSize returns the number of documents in src and the size of its primary shards on disk, as an
+// estimate of what a reindex of src will write to the destination. The destination's replicas, compression and
+// mapping differences will all make the actual size on disk differ.
+func (c *Client) EstimateReindexSize(ctx context.Context, src string) (docs, sizeInBytes int64, err error) {
+
+	st, err := c.Stats(ctx, src, "docs", "store")
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "EstimateReindexSize")
+	}
+
+	p := st.All.Primaries
+	if p.Docs == nil || p.Store == nil {
+		return 0, 0, errors.New("EstimateReindexSize - no docs or store stats in response")
+	}
+
+	return p.Docs.Count, p.Store.SizeInBytes, nil
+}
@@ -0,0 +1,180 @@
+package elastic_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestByQuery(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.URL.Query().Get("wait_for_completion") == "false":
+			w.Write([]byte(`{"task":"oTUltX4IQMOUUVeiohTt8A:12345"}`))
+		case r.URL.Path == "/articles/_delete_by_query":
+			w.Write([]byte(`{"took":12,"timed_out":false,"total":3,"deleted":3,"batches":1,"version_conflicts":0,"failures":[]}`))
+		case r.URL.Path == "/articles/_update_by_query":
+			w.Write([]byte(`{"took":9,"timed_out":false,"total":2,"updated":2,"batches":1,"version_conflicts":0,"failures":[]}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.DeleteByQuery(ctx, "articles", `{"query":{"term":{"status":"draft"}}}`)
+	is.NoErr(err)
+	is.Equal(r.Deleted, int64(3))
+	is.True(body["query"] != nil)
+
+	r, err = e.UpdateByQuery(ctx, "articles", `{"query":{"term":{"status":"draft"}}}`, "ctx._source.views = 0")
+	is.NoErr(err)
+	is.Equal(r.Updated, int64(2))
+	is.Equal(body["script"], map[string]interface{}{"source": "ctx._source.views = 0", "lang": "painless"})
+
+	r, err = e.DeleteByQuery(ctx, "articles", "", elastic.WaitForCompletion(false))
+	is.NoErr(err)
+	is.Equal(r.Task, "oTUltX4IQMOUUVeiohTt8A:12345") // async
+}
+
+func TestReindex(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body string
+	polls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_reindex":
+			xb, _ := ioutil.ReadAll(r.Body)
+			body = string(xb)
+			is.Equal(r.URL.Query().Get("wait_for_completion"), "false")
+			w.Write([]byte(`{"task":"n1:42"}`))
+		case "/_tasks/n1:42":
+			polls++
+			if polls < 3 {
+				w.Write([]byte(`{"completed":false,"task":{"node":"n1","id":42,"action":"indices:data/write/reindex"}}`))
+				return
+			}
+			w.Write([]byte(`{"completed":true,"task":{"node":"n1","id":42},"response":{"total":5,"created":5}}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.Reindex(ctx, "articles_v1", "articles_v2",
+		elastic.ReindexQuery(`{"term":{"status":"published"}}`),
+		elastic.ReindexScript(`ctx._source.remove("legacy")`),
+		elastic.ReindexRemote("https://old:9200", "u", "p"),
+		elastic.ReindexAsync(),
+	)
+	is.NoErr(err)
+	is.Equal(r.Task, "n1:42")
+	is.Equal(body, `{"source":{"index":["articles_v1"],"query":{"term":{"status":"published"}},`+
+		`"remote":{"host":"https://old:9200","username":"u","password":"p"}},"dest":{"index":"articles_v2"},`+
+		`"script":{"source":"ctx._source.remove(\"legacy\")","lang":"painless"}}`)
+
+	ts, err := e.WaitForTask(ctx, r.Task, time.Millisecond)
+	is.NoErr(err)
+	is.Equal(polls, 3)
+	is.True(ts.Completed)
+	is.Equal(ts.Response.Created, int64(5))
+}
+
+func TestReindexMulti(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.Method+" "+r.URL.Path, "POST /_reindex")
+		xb, _ := ioutil.ReadAll(r.Body)
+		body = string(xb)
+		w.Write([]byte(`{"total":7,"created":7}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.ReindexMulti(ctx, []string{"Logs-2018-01", "logs-2018-02-*"}, "logs-2018")
+	is.NoErr(err)
+	is.Equal(r.Created, int64(7))
+	is.Equal(body, `{"source":{"index":["logs-2018-01","logs-2018-02-*"]},"dest":{"index":"logs-2018"}}`)
+
+	body = ""
+	_, err = e.ReindexMulti(ctx, nil, "logs-2018")
+	is.Equal(err.Error(), "ReindexMulti - at least one source index must be specified")
+	_, err = e.ReindexMulti(ctx, []string{"logs-2018-01", ""}, "logs-2018")
+	is.Equal(err.Error(), "ReindexMulti - source index must not be empty")
+	_, err = e.Reindex(ctx, "", "logs-2018")
+	is.Equal(err.Error(), "Reindex - source index must be specified")
+	is.Equal(body, "") // nothing sent
+}
+
+func TestListAndCancelTasks(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var query string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /_tasks":
+			query = r.URL.RawQuery
+			w.Write([]byte(`{"tasks":[{"node":"n1","id":42,"action":"indices:data/write/reindex","cancellable":true,
+				"description":"reindex from [a] to [b]","running_time_in_nanos":1500000000}]}`))
+		case "POST /_tasks/n1:42/_cancel":
+			w.Write([]byte(`{"nodes":{}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	xt, err := e.ListTasks(ctx, elastic.TaskFilter{Actions: []string{"*reindex"}, Detailed: true})
+	is.NoErr(err)
+	is.Equal(query, "actions=%2Areindex&detailed=true&group_by=none")
+	is.Equal(len(xt), 1)
+	is.Equal(xt[0].TaskID(), "n1:42")
+	is.Equal(xt[0].Description, "reindex from [a] to [b]")
+
+	is.NoErr(e.CancelTask(ctx, xt[0].TaskID()))
+	is.True(e.CancelTask(ctx, "n1:43") != nil)
+}
+
+func TestEstimateReindexSize(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var uri string
+	stats := `{"_all":{"primaries":{"docs":{"count":1200,"deleted":3},"store":{"size_in_bytes":52428800}}}}`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri = r.RequestURI
+		w.Write([]byte(stats))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	docs, size, err := e.EstimateReindexSize(ctx, "Articles_v1")
+	is.NoErr(err)
+	is.Equal(uri, "/articles_v1/_stats/docs,store")
+	is.Equal(docs, int64(1200))
+	is.Equal(size, int64(52428800))
+
+	stats = `{"_all":{"primaries":{"docs":{"count":1200,"deleted":3}}}}` // no store stats
+	_, _, err = e.EstimateReindexSize(ctx, "articles_v1")
+	is.Equal(err.Error(), "EstimateReindexSize - no docs or store stats in response")
+	stats = `{"_all":{"primaries":{}}}`
+	_, _, err = e.EstimateReindexSize(ctx, "articles_v1")
+	is.True(err != nil)
+}
@@ -0,0 +1,134 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ResizeResult is the response from ShrinkIndex, SplitIndex and CloneIndex. ShardsAcknowledged is false if the
+// request timed out waiting for the shards of the new index to start, in which case the resize carries on in the
+// background.
+type ResizeResult struct {
+	Acknowledged       bool   `json:"acknowledged"`
+	ShardsAcknowledged bool   `json:"shards_acknowledged"`
+	Index              string `json:"index"`
+}
+
+// ForceMergeResult is the response from ForceMerge. With WaitForCompletion(false) only Task is set, to the id of the
+// task running the merge, which can be waited on with WaitForTask.
+type ForceMergeResult struct {
+	Task   string     `json:"task"`
+	Shards ShardsInfo `json:"_shards"`
+}
+
+// SetWriteBlock sets or clears index.blocks.write, which stops documents being written to an index while still
+// allowing metadata changes. An index must be write blocked before it is shrunk, split or cloned. Clearing removes
+// the setting rather than setting it false.
+func (c *Client) SetWriteBlock(ctx context.Context, index string, block bool) error {
+	v := "null"
+	if block {
+		v = "true"
+	}
+	if err := c.PutIndexSettings(ctx, index, `{"index": {"blocks.write": `+v+`}}`); err != nil {
+		return errors.Wrap(err, "SetWriteBlock")
+	}
+	return nil
+}
+
+// ShrinkIndex copies source into a new index, target, with fewer primary shards, which must be a factor of the
+// number in source. Source must be write blocked, see SetWriteBlock, and have a copy of every shard on one node, eg by
+// setting index.routing.allocation.require._name. Those settings are cleared on target, so it can be written to
+// straight away. The request waits for the primary shards of target to start, or for as many copies as are set with
+// WaitForActiveShards.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-shrink-index.html
+func (c *Client) ShrinkIndex(ctx context.Context, source, target string, shards int, opts ...RequestOption) (*ResizeResult, error) {
+	r, err := c.resize(ctx, "_shrink", source, target, shards, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "ShrinkIndex")
+	}
+	return r, nil
+}
+
+// SplitIndex copies source into a new index, target, with more primary shards, which must be a multiple of the
+// number in source. Source must be write blocked, see SetWriteBlock, and the block is cleared on target. The request
+// waits for shards as for ShrinkIndex.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-split-index.html
+func (c *Client) SplitIndex(ctx context.Context, source, target string, shards int, opts ...RequestOption) (*ResizeResult, error) {
+	r, err := c.resize(ctx, "_split", source, target, shards, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "SplitIndex")
+	}
+	return r, nil
+}
+
+// CloneIndex copies source into a new index, target, with the same settings and mappings, which requires
+// Elasticsearch 7.4 or later. Source must be write blocked, see SetWriteBlock, and the block is cleared on target.
+// The request waits for shards as for ShrinkIndex.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-clone-index.html
+func (c *Client) CloneIndex(ctx context.Context, source, target string, opts ...RequestOption) (*ResizeResult, error) {
+	r, err := c.resize(ctx, "_clone", source, target, 0, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "CloneIndex")
+	}
+	return r, nil
+}
+
+// resize sends a shrink, split or clone request. Shards is the number of primary shards of target, or 0 to leave it
+// as for source.
+func (c *Client) resize(ctx context.Context, action, source, target string, shards int, opts []RequestOption) (*ResizeResult, error) {
+
+	settings := map[string]interface{}{"index.blocks.write": nil}
+	if action == "_shrink" {
+		settings["index.routing.allocation.require._name"] = nil
+	}
+	if shards > 0 {
+		settings["index.number_of_shards"] = shards
+	}
+	xb, err := json.Marshal(map[string]interface{}{"settings": settings})
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	u := withOptions("/"+strings.ToLower(source)+"/"+action+"/"+strings.ToLower(target), opts)
+	xb, err = c.request(ctx, "POST", u, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	var r ResizeResult
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &r, nil
+}
+
+// ForceMerge merges the segments of each shard of index, which may be a pattern or comma separated list, down to at
+// most maxSegments, or to the number Elasticsearch decides on if maxSegments is 0. Merging to 1 segment makes
+// searches on an index that is no longer written to faster and frees the space held by deleted documents. It is
+// costly, so best run outside busy times. The request waits for the merge to finish, which can take a long time for a
+// large index, unless WaitForCompletion(false) is passed, in which case the result holds the Task.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-forcemerge.html
+func (c *Client) ForceMerge(ctx context.Context, index string, maxSegments int, opts ...RequestOption) (*ForceMergeResult, error) {
+
+	if maxSegments > 0 {
+		opts = append(opts[:len(opts):len(opts)], func(o *requestOptions) {
+			o.params.Set("max_num_segments", strconv.Itoa(maxSegments))
+		})
+	}
+	u := withOptions("/"+strings.ToLower(index)+"/_forcemerge", opts)
+	xb, err := c.request(ctx, "POST", u, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "ForceMerge")
+	}
+
+	var r ForceMergeResult
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &r, nil
+}
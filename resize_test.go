@@ -0,0 +1,79 @@
+package elastic_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/elastictest"
+)
+
+func TestResize(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	const ack = `{"acknowledged":true,"shards_acknowledged":true,"index":"logs-small"}`
+	tr := elastictest.NewTransport()
+	tr.Handle("PUT", "/logs/_settings", http.StatusOK, `{"acknowledged":true}`)
+	tr.Handle("POST", "/logs/_shrink/logs-small", http.StatusOK, ack)
+	tr.Handle("POST", "/logs/_split/logs-big", http.StatusOK, ack)
+	tr.Handle("POST", "/logs/_clone/logs-copy", http.StatusOK, ack)
+	tr.Handle("POST", "/logs/_close", http.StatusOK, `{"acknowledged":true,"shards_acknowledged":true}`)
+	tr.Handle("POST", "/logs/_open", http.StatusOK, `{"acknowledged":true,"shards_acknowledged":true}`)
+	e := elastictest.NewClient(tr)
+
+	is.NoErr(e.SetWriteBlock(ctx, "logs", true))
+	r, _ := tr.LastRequest("PUT", "/logs/_settings")
+	is.Equal(string(r.Body), `{"index": {"blocks.write": true}}`)
+
+	res, err := e.ShrinkIndex(ctx, "logs", "logs-small", 1, elastic.WaitForActiveShards("all"))
+	is.NoErr(err)
+	is.True(res.ShardsAcknowledged)
+	r, _ = tr.LastRequest("POST", "/logs/_shrink/logs-small")
+	is.Equal(r.Query.Get("wait_for_active_shards"), "all")
+	is.Equal(string(r.Body), `{"settings":{"index.blocks.write":null,"index.number_of_shards":1,`+
+		`"index.routing.allocation.require._name":null}}`)
+
+	_, err = e.SplitIndex(ctx, "logs", "logs-big", 10)
+	is.NoErr(err)
+	r, _ = tr.LastRequest("POST", "/logs/_split/logs-big")
+	is.Equal(string(r.Body), `{"settings":{"index.blocks.write":null,"index.number_of_shards":10}}`)
+
+	_, err = e.CloneIndex(ctx, "logs", "logs-copy")
+	is.NoErr(err)
+	r, _ = tr.LastRequest("POST", "/logs/_clone/logs-copy")
+	is.Equal(string(r.Body), `{"settings":{"index.blocks.write":null}}`)
+
+	is.NoErr(e.CloseIndex(ctx, "logs"))
+	is.NoErr(e.OpenIndex(ctx, "logs", elastic.WaitForActiveShards("2")))
+	r, _ = tr.LastRequest("POST", "/logs/_open")
+	is.Equal(r.Query.Get("wait_for_active_shards"), "2")
+}
+
+func TestForceMerge(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	tr := elastictest.NewTransport()
+	tr.HandleFunc("POST", "/logs-2026.09/_forcemerge", func(r elastictest.Request) (int, string) {
+		if r.Query.Get("wait_for_completion") == "false" {
+			return http.StatusOK, `{"task":"n1:42"}`
+		}
+		return http.StatusOK, `{"_shards":{"total":2,"successful":2,"failed":0}}`
+	})
+	e := elastictest.NewClient(tr)
+
+	res, err := e.ForceMerge(ctx, "logs-2026.09", 1)
+	is.NoErr(err)
+	is.Equal(res.Shards.Successful, 2)
+	r, _ := tr.LastRequest("POST", "/logs-2026.09/_forcemerge")
+	is.Equal(r.Query.Get("max_num_segments"), "1")
+
+	res, err = e.ForceMerge(ctx, "logs-2026.09", 0, elastic.WaitForCompletion(false))
+	is.NoErr(err)
+	is.Equal(res.Task, "n1:42")
+	r, _ = tr.LastRequest("POST", "/logs-2026.09/_forcemerge")
+	is.Equal(r.Query.Get("max_num_segments"), "")
+}
@@ -0,0 +1,57 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RolloverConditions are the conditions under which Rollover creates a new index. Any one being met is enough, and
+// with none set the alias is always rolled over. Ages and sizes are in Elasticsearch units, eg "7d" and "50gb".
+type RolloverConditions struct {
+	MaxAge              string `json:"max_age,omitempty"`
+	MaxDocs             int64  `json:"max_docs,omitempty"`
+	MaxSize             string `json:"max_size,omitempty"`
+	MaxPrimaryShardSize string `json:"max_primary_shard_size,omitempty"` // from 7.12
+}
+
+// RolloverResult reports whether a rollover happened. Conditions holds whether each condition was met, keyed as in
+// the request, eg "[max_docs: 1000]".
+type RolloverResult struct {
+	OldIndex   string          `json:"old_index"`
+	NewIndex   string          `json:"new_index"`
+	RolledOver bool            `json:"rolled_over"`
+	DryRun     bool            `json:"dry_run"`
+	Conditions map[string]bool `json:"conditions"`
+}
+
+// Rollover creates a new index for a write alias, or data stream, and points the alias at it if any of the
+// conditions are met. The new index is named by incrementing the number at the end of the current one, eg
+// logs-000001 to logs-000002. Pass DryRun to check the conditions without rolling over.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-rollover-index.html
+func (c *Client) Rollover(ctx context.Context, alias string, conditions RolloverConditions, opts ...RequestOption) (*RolloverResult, error) {
+
+	body := map[string]interface{}{}
+	if conditions != (RolloverConditions{}) {
+		body["conditions"] = conditions
+	}
+	xb, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	u := withOptions("/"+strings.ToLower(alias)+"/_rollover", opts)
+	xb, err = c.request(ctx, "POST", u, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "Rollover")
+	}
+
+	var r RolloverResult
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &r, nil
+}
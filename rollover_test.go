@@ -0,0 +1,51 @@
+package elastic_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestRollover(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var query, body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		query, body = r.URL.Path+"?"+r.URL.RawQuery, string(xb)
+		dry := r.URL.Query().Get("dry_run") == "true"
+		rolled := strconv.FormatBool(!dry)
+		w.Write([]byte(`{"acknowledged":` + rolled + `,"old_index":"logs-000001","new_index":"logs-000002",` +
+			`"rolled_over":` + rolled + `,"dry_run":` + strconv.FormatBool(dry) + `,` +
+			`"conditions":{"[max_age: 7d]":false,"[max_docs: 1000]":true}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	cond := elastic.RolloverConditions{MaxAge: "7d", MaxDocs: 1000}
+
+	r, err := e.Rollover(ctx, "logs", cond, elastic.DryRun())
+	is.NoErr(err)
+	is.Equal(query, "/logs/_rollover?dry_run=true")
+	is.Equal(body, `{"conditions":{"max_age":"7d","max_docs":1000}}`)
+	is.True(r.DryRun)
+	is.True(!r.RolledOver)
+	is.True(r.Conditions["[max_docs: 1000]"])
+
+	r, err = e.Rollover(ctx, "logs", cond)
+	is.NoErr(err)
+	is.True(r.RolledOver)
+	is.Equal(r.NewIndex, "logs-000002")
+
+	// Unconditional
+	_, err = e.Rollover(ctx, "logs", elastic.RolloverConditions{})
+	is.NoErr(err)
+	is.Equal(body, `{}`)
+}
@@ -0,0 +1,791 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SearchResult is the parsed response from a search
+type SearchResult struct {
+	Took         int                        `json:"took"`
+	TimedOut     bool                       `json:"timed_out"`
+	Shards       ShardsInfo                 `json:"_shards"`
+	Hits         SearchHits                 `json:"hits"`
+	Aggregations map[string]json.RawMessage `json:"aggregations"`
+	PitID        string                     `json:"pit_id"` // the latest id of the point in time searched, if any
+	Suggest      map[string][]SuggestEntry  `json:"suggest"`
+}
+
+// SuggestEntry is the suggestions for a term of the suggest text, or for the whole text for phrase and completion
+// suggesters
+type SuggestEntry struct {
+	Text    string          `json:"text"`
+	Offset  int             `json:"offset"`
+	Length  int             `json:"length"`
+	Options []SuggestOption `json:"options"`
+}
+
+// SuggestOption is a suggestion. Freq is set for term suggestions, Highlighted for phrase suggestions with
+// highlighting, and ID, Index and Source, the suggested document, for completion suggestions.
+type SuggestOption struct {
+	Text        string          `json:"text"`
+	Score       float64         `json:"score"`
+	Freq        int64           `json:"freq"`
+	Highlighted string          `json:"highlighted"`
+	ID          string          `json:"_id"`
+	Index       string          `json:"_index"`
+	Source      json.RawMessage `json:"_source"`
+}
+
+// UnmarshalJSON reads the score of a completion suggestion, which is returned as "_score" rather than "score"
+func (o *SuggestOption) UnmarshalJSON(xb []byte) error {
+	type suggestOption SuggestOption
+	var v struct {
+		suggestOption
+		DocScore *float64 `json:"_score"`
+	}
+	if err := json.Unmarshal(xb, &v); err != nil {
+		return err
+	}
+	*o = SuggestOption(v.suggestOption)
+	if v.DocScore != nil {
+		o.Score = *v.DocScore
+	}
+	return nil
+}
+
+// ShardsInfo reports how many shards a request ran on. Failed is greater than zero, and Failures holds the
+// reasons, when a search returned partial results.
+type ShardsInfo struct {
+	Total      int            `json:"total"`
+	Successful int            `json:"successful"`
+	Skipped    int            `json:"skipped"`
+	Failed     int            `json:"failed"`
+	Failures   []ShardFailure `json:"failures"`
+}
+
+// SearchHits holds the matching documents in a SearchResult
+type SearchHits struct {
+	Total    TotalHits `json:"total"`
+	MaxScore float64   `json:"max_score"`
+	Hits     []Hit     `json:"hits"`
+}
+
+// TotalHits is the number of documents matching a search. Relation is "eq" when Value is exact, or "gte" when it is
+// a lower bound because the count stopped at track_total_hits.
+type TotalHits struct {
+	Value    int64  `json:"value"`
+	Relation string `json:"relation"`
+}
+
+// UnmarshalJSON accepts the plain count returned before version 7 as well as the object returned since
+func (t *TotalHits) UnmarshalJSON(xb []byte) error {
+	if len(xb) > 0 && xb[0] != '{' && xb[0] != 'n' {
+		t.Relation = "eq"
+		return json.Unmarshal(xb, &t.Value)
+	}
+	type totalHits TotalHits
+	return json.Unmarshal(xb, (*totalHits)(t))
+}
+
+// Hit is a single matching document. Source holds the raw document. MatchedQueries lists the named query clauses,
+// those with a "_name", that the document matched. Sort values are kept raw so that long values, eg a numeric id
+// used as a tie-breaker, are passed back in SearchAfter without losing precision.
+type Hit struct {
+	Index          string                   `json:"_index"`
+	ID             string                   `json:"_id"`
+	Score          float64                  `json:"_score"`
+	Source         json.RawMessage          `json:"_source"`
+	Fields         map[string][]interface{} `json:"fields"`
+	MatchedQueries []string                 `json:"matched_queries"`
+	Sort           []json.RawMessage        `json:"sort"`      // the sort values, when the search was sorted
+	Highlight      map[string][]string      `json:"highlight"` // snippets by field, when highlighting was asked for
+}
+
+// ErrSearchPhase is the cause of a search that failed on every shard, eg because it sorts on an unmapped field. The
+// error is a *SearchPhaseError holding the reason for each shard.
+var ErrSearchPhase = errors.New("all shards failed")
+
+// SearchPhaseError is a search_phase_execution_exception. Check for it with errors.Is(err, ErrSearchPhase), and use
+// errors.As to get the shard failures.
+type SearchPhaseError struct {
+	Phase    string         `json:"phase"`
+	Reason   string         `json:"reason"`
+	Failures []ShardFailure `json:"failed_shards"`
+}
+
+// ShardFailure is the reason a search failed on one shard
+type ShardFailure struct {
+	Shard  int    `json:"shard"`
+	Index  string `json:"index"`
+	Node   string `json:"node"`
+	Reason struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"reason"`
+}
+
+func (e *SearchPhaseError) Error() string {
+	xs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		xs[i] = "[" + f.Index + "][" + strconv.Itoa(f.Shard) + "] " + f.Reason.Reason
+	}
+	return ErrSearchPhase.Error() + " in " + e.Phase + " phase - " + strings.Join(xs, "; ")
+}
+
+// Is makes errors.Is(err, ErrSearchPhase) true for a *SearchPhaseError
+func (e *SearchPhaseError) Is(target error) bool {
+	return target == ErrSearchPhase
+}
+
+// searchError returns a *SearchPhaseError if err is a search_phase_execution_exception, otherwise err
+func searchError(err error) error {
+	var e *Error
+	if !errors.As(err, &e) || e.Type != "search_phase_execution_exception" {
+		return err
+	}
+	var r struct {
+		Error SearchPhaseError `json:"error"`
+	}
+	if json.Unmarshal(e.Body, &r) != nil {
+		// Truncated body, make do with the reason
+		r.Error = SearchPhaseError{Reason: e.Reason}
+	}
+	return &r.Error
+}
+
+// SearchRequest builds a search request body for SearchWith. Query can be anything that marshals to a query DSL
+// clause, such as a builder from the query package or a json.RawMessage, and likewise the values of Aggs.
+type SearchRequest struct {
+	Index        string                 `json:"-"`
+	Query        interface{}            `json:"query,omitempty"`
+	ScriptFields map[string]ScriptField `json:"script_fields,omitempty"`
+	Aggs         map[string]interface{} `json:"aggs,omitempty"` // eg builders from the aggs package, by name
+	From         int                    `json:"from,omitempty"`
+	Size         *int                   `json:"size,omitempty"` // 10 by default, set to 0 to return only aggregations
+	Sort         []SearchSort           `json:"sort,omitempty"`
+	SearchAfter  []interface{}          `json:"search_after,omitempty"` // the Sort of the last hit on the previous page
+	PIT          *PointInTime           `json:"pit,omitempty"`
+	Suggest      map[string]interface{} `json:"suggest,omitempty"` // eg builders from the suggest package, by name
+	Highlight    *Highlight             `json:"highlight,omitempty"`
+}
+
+// Highlight asks for snippets of the text that matched the query, from each of Fields, to be returned in
+// Hit.Highlight. The matches are wrapped in PreTags and PostTags, "<em>" and "</em>" by default. FragmentSize, 100
+// characters by default, and NumberOfFragments, 5 by default, apply to every field unless the field sets its own.
+// NumberOfFragments of 0 returns the whole field value, highlighted.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/highlighting.html
+type Highlight struct {
+	Fields            map[string]HighlightField `json:"fields"`
+	PreTags           []string                  `json:"pre_tags,omitempty"`
+	PostTags          []string                  `json:"post_tags,omitempty"`
+	FragmentSize      int                       `json:"fragment_size,omitempty"`
+	NumberOfFragments *int                      `json:"number_of_fragments,omitempty"`
+}
+
+// HighlightField sets how one field is highlighted. The zero value uses the settings of the Highlight.
+type HighlightField struct {
+	FragmentSize      int  `json:"fragment_size,omitempty"`
+	NumberOfFragments *int `json:"number_of_fragments,omitempty"`
+}
+
+// PointInTime searches a point in time opened with OpenPointInTime rather than an index. KeepAlive extends the
+// point in time by that much from this search.
+type PointInTime struct {
+	ID        string
+	KeepAlive time.Duration
+}
+
+// MarshalJSON marshals the point in time as {"id": "...", "keep_alive": "1m"}
+func (p PointInTime) MarshalJSON() ([]byte, error) {
+	m := map[string]string{"id": p.ID}
+	if p.KeepAlive > 0 {
+		m["keep_alive"] = timeValue(p.KeepAlive)
+	}
+	return json.Marshal(m)
+}
+
+// SearchSort sorts hits by a field, or by "_score" or "_doc". Missing is "_first" or "_last" to say where hits with
+// no value for the field go, "_last" by default.
+type SearchSort struct {
+	Field   string
+	Desc    bool
+	Missing string
+}
+
+// MarshalJSON marshals the sort as {"field": {"order": "asc"}}
+func (f SearchSort) MarshalJSON() ([]byte, error) {
+	p := map[string]string{"order": "asc"}
+	if f.Desc {
+		p["order"] = "desc"
+	}
+	if f.Missing != "" {
+		p["missing"] = f.Missing
+	}
+	return json.Marshal(map[string]interface{}{f.Field: p})
+}
+
+// ScriptField is a value computed for each hit by a script, returned in Hit.Fields
+type ScriptField struct {
+	Script Script `json:"script"`
+}
+
+// Script is an inline script, painless by default
+type Script struct {
+	Source string                 `json:"source"`
+	Lang   string                 `json:"lang,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// Search runs a search against the specified index, or all indices if index is empty. The query is a search request
+// body in the query DSL, eg `{"query": {"match": {"title": "elastic"}}}`.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-search.html
+func (c *Client) Search(ctx context.Context, index, query string, opts ...RequestOption) (*SearchResult, error) {
+	r, err := c.search(ctx, index, strings.NewReader(query), opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Search")
+	}
+	return r, nil
+}
+
+// SearchWith runs the search described by r. Script fields are returned in the Fields of each hit. A search of a
+// point in time ignores Index, as the point in time is bound to the indices it was opened on.
+func (c *Client) SearchWith(ctx context.Context, r SearchRequest, opts ...RequestOption) (*SearchResult, error) {
+
+	xb, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	index := r.Index
+	if r.PIT != nil {
+		index = ""
+	}
+
+	sr, err := c.search(ctx, index, bytes.NewReader(xb), opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "SearchWith")
+	}
+	return sr, nil
+}
+
+// SearchStream is like Search but returns the response body unread, so that a very large result can be decoded
+// incrementally, eg with a json.Decoder, rather than held in memory in full. Unlike StreamSearch, which scrolls
+// through every match, it makes a single search request. The caller must close the body.
+// Failures while reading it, such as the connection dropping, are returned by Read and are not retried.
+func (c *Client) SearchStream(ctx context.Context, index, query string, opts ...RequestOption) (io.ReadCloser, error) {
+
+	u := "/_search"
+	if index != "" {
+		u = "/" + strings.ToLower(index) + u
+	}
+	u = withOptions(u, opts)
+
+	rc, err := c.requestStream(ctx, "POST", u, strings.NewReader(query), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(searchError(err), "SearchStream")
+	}
+	return rc, nil
+}
+
+// search posts a search request body and parses the result
+func (c *Client) search(ctx context.Context, index string, body io.Reader, opts []RequestOption) (*SearchResult, error) {
+
+	u := "/_search"
+	if index != "" {
+		u = "/" + strings.ToLower(index) + u
+	}
+	u = withOptions(u, opts)
+
+	xb, err := c.request(ctx, "POST", u, body, standardHeaders)
+	if err != nil {
+		return nil, searchError(err)
+	}
+
+	var r SearchResult
+	err = json.Unmarshal(xb, &r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	return &r, nil
+}
+
+// DecodeAgg decodes the named aggregation result into v, eg a struct with a Buckets field for a terms aggregation
+func (r *SearchResult) DecodeAgg(name string, v interface{}) error {
+	xb, ok := r.Aggregations[name]
+	if !ok {
+		return errors.Errorf("DecodeAgg - no aggregation named %q", name)
+	}
+	return errors.Wrap(json.Unmarshal(xb, v), "DecodeAgg")
+}
+
+// StreamSearch runs query against index and writes every matching hit to w as a JSON array, fetching the hits a page
+// at a time with the scroll API so that memory use is bounded by the page size rather than the size of the result
+// set. The page size can be set with "size" in the query. If w is an http.Flusher it is flushed after each page.
+// On error the array written to w will be incomplete.
+func (c *Client) StreamSearch(ctx context.Context, index, query string, w io.Writer) error {
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return errors.Wrap(err, "StreamSearch")
+	}
+
+	first := true
+	err := c.scroll(ctx, index, query, func(hits []json.RawMessage) error {
+		for _, h := range hits {
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(h); err != nil {
+				return err
+			}
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "StreamSearch")
+	}
+
+	_, err = io.WriteString(w, "]")
+	return errors.Wrap(err, "StreamSearch")
+}
+
+// scrollKeepAlive is how long a scroll context is kept open between pages
+const scrollKeepAlive = time.Minute
+
+// Scroll iterates over every hit for a query a page at a time using the scroll API. Call Next to fetch each page,
+// then Hits to get it, and check Err once Next returns false:
+//
+//	s := c.Scroll(ctx, "articles", `{"size": 1000}`, time.Minute)
+//	defer s.Close()
+//	for s.Next() {
+//		for _, h := range s.Hits() {
+//			...
+//		}
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+//
+// The scroll context is cleared when the hits run out, Next fails or Close is called.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/paginate-search-results.html#scroll-search-results
+type Scroll struct {
+	c         *Client
+	ctx       context.Context
+	index     string
+	query     string
+	keepAlive string
+	id        string
+	raw       []json.RawMessage
+	hits      []Hit
+	done      bool
+	err       error
+}
+
+// Scroll returns an iterator over the hits for query against index. The page size can be set with "size" in the
+// query. keepAlive is how long the scroll context is kept open between pages, one minute if it is zero.
+func (c *Client) Scroll(ctx context.Context, index, query string, keepAlive time.Duration) *Scroll {
+	if keepAlive <= 0 {
+		keepAlive = scrollKeepAlive
+	}
+	if query == "" {
+		query = "{}"
+	}
+	return &Scroll{
+		c:         c,
+		ctx:       ctx,
+		index:     index,
+		query:     query,
+		keepAlive: timeValue(keepAlive),
+	}
+}
+
+// Next fetches the next page of hits, returning false when there are no more or on error
+func (s *Scroll) Next() bool {
+
+	if s.done {
+		return false
+	}
+
+	var xb []byte
+	var err error
+	if s.id == "" {
+		u := "/_search?scroll=" + s.keepAlive
+		if s.index != "" {
+			u = "/" + strings.ToLower(s.index) + u
+		}
+		xb, err = s.c.request(s.ctx, "POST", u, strings.NewReader(s.query), standardHeaders)
+		err = searchError(err)
+	} else {
+		body, _ := json.Marshal(map[string]string{"scroll": s.keepAlive, "scroll_id": s.id})
+		xb, err = s.c.request(s.ctx, "POST", "/_search/scroll", bytes.NewReader(body), standardHeaders)
+	}
+	if err != nil {
+		return s.fail(errors.Wrap(err, "Scroll"))
+	}
+
+	var page struct {
+		ScrollID string `json:"_scroll_id"`
+		Hits     struct {
+			Hits json.RawMessage `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(xb, &page); err != nil {
+		return s.fail(errors.Wrap(err, "Unmarshal"))
+	}
+	if page.ScrollID != "" {
+		s.id = page.ScrollID
+	}
+	s.raw, s.hits = nil, nil
+	if len(page.Hits.Hits) > 0 {
+		if err := json.Unmarshal(page.Hits.Hits, &s.raw); err != nil {
+			return s.fail(errors.Wrap(err, "Unmarshal"))
+		}
+	}
+	if len(s.raw) == 0 {
+		s.Close()
+		return false
+	}
+	if err := json.Unmarshal(page.Hits.Hits, &s.hits); err != nil {
+		return s.fail(errors.Wrap(err, "Unmarshal"))
+	}
+
+	return true
+}
+
+// Hits returns the current page of hits
+func (s *Scroll) Hits() []Hit {
+	return s.hits
+}
+
+// Err returns the error, if any, that stopped Next
+func (s *Scroll) Err() error {
+	return s.err
+}
+
+// Close clears the scroll context. It is safe to call more than once, and need not be called after Next has returned
+// false.
+func (s *Scroll) Close() error {
+	s.done = true
+	if s.id == "" {
+		return nil
+	}
+	id := s.id
+	s.id = ""
+	// Detached from the iterator's context so a cancelled scroll is still cleared
+	return errors.Wrap(s.c.clearScroll(context.Background(), id), "Scroll")
+}
+
+// timeValue formats d in the largest whole unit that Elasticsearch accepts in a time value, eg "1m" or "1500ms"
+func timeValue(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	case d%time.Second == 0:
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+}
+
+// fail records err, clears the scroll context and returns false for Next
+func (s *Scroll) fail(err error) bool {
+	s.err = err
+	s.Close() // best effort, the context expires anyway
+	return false
+}
+
+// scroll runs query against index using the scroll API and calls fn with the raw hits of each page, until there are
+// no more hits or fn returns an error. The scroll context is cleared before returning.
+func (c *Client) scroll(ctx context.Context, index, query string, fn func(hits []json.RawMessage) error) error {
+	s := c.Scroll(ctx, index, query, scrollKeepAlive)
+	defer s.Close()
+	for s.Next() {
+		if err := fn(s.raw); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// clearScroll releases the resources held by a scroll context
+func (c *Client) clearScroll(ctx context.Context, scrollID string) error {
+	body, _ := json.Marshal(map[string][]string{"scroll_id": {scrollID}})
+	_, err := c.request(ctx, "DELETE", "/_search/scroll", bytes.NewReader(body), standardHeaders)
+	return err
+}
+
+// SearchIterator pages through every hit of a sorted search using search_after, which, unlike from and size, is
+// not limited to the first 10,000 hits and, unlike scroll, holds no resources on the cluster between pages. It is
+// used in the same way as Scroll:
+//
+//	it := c.SearchIterator(ctx, r, 1000)
+//	for it.Next() {
+//		for _, h := range it.Hits() {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// The search must be sorted, with a unique field, such as an id, as the last sort field to break ties. Documents
+// changed while paging may be skipped or seen twice, unless the search is of a point in time, see OpenPointInTime,
+// which gives a consistent view of the index across pages. On version 7.12 and later a point in time search can be
+// sorted by "_shard_doc" alone, the most efficient order.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/paginate-search-results.html#search-after
+type SearchIterator struct {
+	c    *Client
+	ctx  context.Context
+	r    SearchRequest
+	opts []RequestOption
+	hits []Hit
+	done bool
+	err  error
+}
+
+// SearchIterator returns an iterator over the hits of r, size at a time. Any From in r is ignored.
+func (c *Client) SearchIterator(ctx context.Context, r SearchRequest, size int, opts ...RequestOption) *SearchIterator {
+	r.From = 0
+	if size > 0 {
+		r.Size = &size
+	}
+	return &SearchIterator{c: c, ctx: ctx, r: r, opts: opts}
+}
+
+// Next fetches the next page of hits, returning false when there are no more or on error
+func (it *SearchIterator) Next() bool {
+
+	if it.done {
+		return false
+	}
+	if len(it.r.Sort) == 0 {
+		return it.fail(errors.New("SearchIterator - search has no sort"))
+	}
+
+	sr, err := it.c.SearchWith(it.ctx, it.r, it.opts...)
+	if err != nil {
+		return it.fail(errors.Wrap(err, "SearchIterator"))
+	}
+
+	if it.r.PIT != nil && sr.PitID != "" {
+		pit := *it.r.PIT
+		pit.ID = sr.PitID // the id can change between searches
+		it.r.PIT = &pit
+	}
+
+	it.hits = sr.Hits.Hits
+	if len(it.hits) == 0 {
+		it.done = true
+		return false
+	}
+	if it.r.Size != nil && len(it.hits) < *it.r.Size {
+		it.done = true // a short page is the last
+	}
+
+	last := it.hits[len(it.hits)-1].Sort
+	it.r.SearchAfter = make([]interface{}, len(last))
+	for i, v := range last {
+		it.r.SearchAfter[i] = v
+	}
+
+	return true
+}
+
+// Hits returns the current page of hits
+func (it *SearchIterator) Hits() []Hit {
+	return it.hits
+}
+
+// Err returns the error, if any, that stopped Next
+func (it *SearchIterator) Err() error {
+	return it.err
+}
+
+// fail records err and returns false for Next
+func (it *SearchIterator) fail(err error) bool {
+	it.err = err
+	it.done = true
+	return false
+}
+
+// OpenPointInTime opens a point in time on index, which may be a comma separated list or pattern, for searches
+// with SearchRequest.PIT to see the index as it was when opened. It is kept for keepAlive, which each search can
+// extend, and should be closed with ClosePointInTime when done. Available from version 7.10, and OpenSearch 2.4.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/point-in-time-api.html
+func (c *Client) OpenPointInTime(ctx context.Context, index string, keepAlive time.Duration) (string, error) {
+
+	u := "/" + strings.ToLower(index) + "/_pit?keep_alive=" + timeValue(keepAlive)
+	if c.isOpenSearch(ctx) {
+		u = "/" + strings.ToLower(index) + "/_search/point_in_time?keep_alive=" + timeValue(keepAlive)
+	}
+	xb, err := c.request(ctx, "POST", u, nil, standardHeaders)
+	if err != nil {
+		return "", errors.Wrap(err, "OpenPointInTime")
+	}
+
+	var r struct {
+		ID    string `json:"id"`
+		PitID string `json:"pit_id"` // OpenSearch
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return "", errors.Wrap(err, "Unmarshal")
+	}
+	if r.ID == "" {
+		r.ID = r.PitID
+	}
+	return r.ID, nil
+}
+
+// ClosePointInTime releases the resources held by a point in time
+func (c *Client) ClosePointInTime(ctx context.Context, id string) error {
+	u := "/_pit"
+	body, _ := json.Marshal(map[string]string{"id": id})
+	if c.isOpenSearch(ctx) {
+		u = "/_search/point_in_time"
+		body, _ = json.Marshal(map[string][]string{"pit_id": {id}})
+	}
+	_, err := c.request(ctx, "DELETE", u, bytes.NewReader(body), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "ClosePointInTime")
+	}
+	return nil
+}
+
+// SearchIDs returns the ids of every document in index that matches query. The documents themselves are not fetched
+// (_source is false) and the hits are paged through with the scroll API, so it is an efficient way to gather ids,
+// eg to feed a bulk delete.
+func (c *Client) SearchIDs(ctx context.Context, index, query string) ([]string, error) {
+
+	q, err := setBodyFields(query, map[string]interface{}{"_source": false})
+	if err != nil {
+		return nil, errors.Wrap(err, "SearchIDs")
+	}
+
+	var ids []string
+	err = c.scroll(ctx, index, q, func(hits []json.RawMessage) error {
+		for _, h := range hits {
+			var hit struct {
+				ID string `json:"_id"`
+			}
+			if err := json.Unmarshal(h, &hit); err != nil {
+				return errors.Wrap(err, "Unmarshal")
+			}
+			ids = append(ids, hit.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "SearchIDs")
+	}
+
+	return ids, nil
+}
+
+// setBodyFields sets top-level fields in a JSON request body, replacing any that are already present
+func setBodyFields(body string, fields map[string]interface{}) (string, error) {
+
+	m := map[string]json.RawMessage{}
+	if strings.TrimSpace(body) != "" {
+		if err := json.Unmarshal([]byte(body), &m); err != nil {
+			return "", errors.Wrap(err, "Unmarshal")
+		}
+	}
+
+	for k, v := range fields {
+		xb, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.Wrap(err, "Marshal")
+		}
+		m[k] = xb
+	}
+
+	xb, err := json.Marshal(m)
+	if err != nil {
+		return "", errors.Wrap(err, "Marshal")
+	}
+	return string(xb), nil
+}
+
+// Count returns the number of documents in index, or all indices if index is empty, that match query, a request
+// body holding only a query such as `{"query": {"term": {"status": "draft"}}}`. An empty query counts every
+// document. Unlike hits.total from a search, the count is always exact.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-count.html
+func (c *Client) Count(ctx context.Context, index, query string) (int64, error) {
+
+	u := "/_count"
+	if index != "" {
+		u = "/" + strings.ToLower(index) + u
+	}
+
+	var body io.Reader
+	if strings.TrimSpace(query) != "" {
+		body = strings.NewReader(query)
+	}
+
+	xb, err := c.request(ctx, "POST", u, body, standardHeaders)
+	if err != nil {
+		return 0, errors.Wrap(err, "Count")
+	}
+
+	var r struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return 0, errors.Wrap(err, "Unmarshal")
+	}
+	return r.Count, nil
+}
+
+// RankEval evaluates the quality of ranked search results against a set of rated documents and returns the raw
+// response, which holds the overall metric score and the details for each request. The body holds the requests,
+// their ratings and the metric, eg precision or recall.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-rank-eval.html
+func (c *Client) RankEval(ctx context.Context, index, body string) ([]byte, error) {
+
+	u := "/_rank_eval"
+	if index != "" {
+		u = "/" + strings.ToLower(index) + u
+	}
+
+	xb, err := c.request(ctx, "POST", u, strings.NewReader(body), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "RankEval")
+	}
+
+	return xb, nil
+}
+
+// WarmAggregations runs aggregations once against index, returning no hits, so that the field data, global ordinals
+// and caches they need are loaded before users run them. Call it after an index is opened or a node starts so the
+// first user-facing aggregation is not the slow one. The aggs are a JSON object of named aggregations, as in the
+// "aggs" of a search request.
+func (c *Client) WarmAggregations(ctx context.Context, index, aggs string) error {
+
+	if !json.Valid([]byte(aggs)) {
+		return errors.New("WarmAggregations - aggs is not valid JSON")
+	}
+
+	body := `{"size": 0, "aggs": ` + aggs + `}`
+	_, err := c.search(ctx, index, strings.NewReader(body), nil)
+	if err != nil {
+		return errors.Wrap(err, "WarmAggregations")
+	}
+
+	return nil
+}
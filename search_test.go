@@ -0,0 +1,658 @@
+package elastic_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/aggs"
+	"github.com/mikedonnici/elastic/query"
+	"github.com/mikedonnici/elastic/suggest"
+)
+
+func TestSearchAggregations(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"POST /articles/_search": fixture("search_aggs.json"),
+	})
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.Search(ctx, "articles", `{"aggs": {"by_category": {"terms": {"field": "category"}}}}`)
+	is.NoErr(err)
+	is.Equal(len(r.Hits.Hits), 3)
+	is.Equal(r.Hits.Total, elastic.TotalHits{Value: 3, Relation: "eq"})
+	is.Equal(r.Hits.MaxScore, 1.0)
+	is.Equal(string(r.Hits.Hits[2].Source), `{"title": "three", "category": "sport"}`)
+
+	var agg struct {
+		Buckets []struct {
+			Key      string `json:"key"`
+			DocCount int    `json:"doc_count"`
+		} `json:"buckets"`
+	}
+	is.NoErr(r.DecodeAgg("by_category", &agg))
+	is.Equal(len(agg.Buckets), 2)
+	is.Equal(agg.Buckets[0].Key, "news")
+	is.Equal(agg.Buckets[0].DocCount, 2)
+
+	is.True(r.DecodeAgg("missing", &agg) != nil) // unknown aggregation
+}
+
+func TestSearchAggResults(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"POST /sales/_search": fixture("search_aggs_nested.json"),
+	})
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.SearchWith(ctx, elastic.SearchRequest{
+		Index: "sales",
+		Aggs: map[string]interface{}{
+			"by_category": aggs.Terms("category").SubAgg("per_month", aggs.DateHistogram("sold_at").
+				CalendarInterval("month").
+				SubAgg("avg_price", aggs.Avg("price"))),
+			"by_price":  aggs.Range("price").AddRange("cheap", nil, 10).AddRange("dear", 10, nil),
+			"customers": aggs.Cardinality("customer_id"),
+		},
+	})
+	is.NoErr(err)
+
+	cat, err := r.Agg("by_category")
+	is.NoErr(err)
+	is.Equal(cat.SumOtherDocCount, int64(1))
+	is.Equal(len(cat.Buckets), 2)
+	is.Equal(cat.Buckets[0].Key, "books")
+	is.Equal(cat.Buckets[0].DocCount, int64(3))
+
+	months := cat.Buckets[0].Agg("per_month").Buckets
+	is.Equal(len(months), 2)
+	is.Equal(months[1].KeyAsString, "2021-02")
+	is.Equal(*months[1].Agg("avg_price").Value, 9.0)
+	is.True(cat.Buckets[1].Agg("per_month").Buckets[0].Agg("avg_price").Value == nil) // no prices to average
+
+	price, err := r.Agg("by_price")
+	is.NoErr(err)
+	is.Equal(len(price.Buckets), 2)
+	is.Equal(price.Buckets[0].Key, "cheap") // keyed buckets
+	is.Equal(*price.Buckets[0].To, 10.0)
+	is.True(price.Buckets[0].From == nil)
+
+	customers, err := r.Agg("customers")
+	is.NoErr(err)
+	is.Equal(*customers.Value, 4.0)
+
+	_, err = r.Agg("missing")
+	is.True(err != nil)
+}
+
+func TestSearchSuggest(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		body = string(xb)
+		w.Write(fixture("search_suggest.json"))
+	}))
+	defer s.Close()
+
+	size := 0
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.SearchWith(ctx, elastic.SearchRequest{
+		Index: "books",
+		Size:  &size,
+		Suggest: map[string]interface{}{
+			"did_you_mean": suggest.Term("title", "elastc serch"),
+			"autocomplete": suggest.Completion("suggest", "elas").SkipDuplicates(),
+		},
+	})
+	is.NoErr(err)
+	is.Equal(body, `{"size":0,"suggest":{"autocomplete":{"completion":{"field":"suggest","skip_duplicates":true},`+
+		`"prefix":"elas"},"did_you_mean":{"term":{"field":"title"},"text":"elastc serch"}}}`)
+
+	dym := r.Suggest["did_you_mean"]
+	is.Equal(len(dym), 2)
+	is.Equal(dym[1].Text, "serch")
+	is.Equal(dym[1].Options[0].Text, "search")
+	is.Equal(dym[1].Options[0].Freq, int64(30))
+
+	ac := r.Suggest["autocomplete"][0].Options[0]
+	is.Equal(ac.ID, "7")
+	is.Equal(ac.Score, 3.0)
+	is.Equal(string(ac.Source), `{"title": "Elasticsearch in Action"}`)
+}
+
+func TestSearchHighlight(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		body = string(xb)
+		w.Write([]byte(`{"hits":{"hits":[{"_id":"1","_source":{},"highlight":{
+			"title":["Getting started with <mark>Elastic</mark>"],
+			"body":["...install <mark>Elastic</mark> on...","...configure <mark>Elastic</mark> to..."]
+		}}]}}`))
+	}))
+	defer s.Close()
+
+	whole := 0
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	r, err := e.SearchWith(ctx, elastic.SearchRequest{
+		Index: "articles",
+		Query: query.Match("body", "elastic"),
+		Highlight: &elastic.Highlight{
+			Fields: map[string]elastic.HighlightField{
+				"title": {NumberOfFragments: &whole},
+				"body":  {},
+			},
+			PreTags:      []string{"<mark>"},
+			PostTags:     []string{"</mark>"},
+			FragmentSize: 50,
+		},
+	})
+	is.NoErr(err)
+	is.Equal(body, `{"query":{"match":{"body":{"query":"elastic"}}},"highlight":{"fields":{"body":{},`+
+		`"title":{"number_of_fragments":0}},"pre_tags":["\u003cmark\u003e"],"post_tags":["\u003c/mark\u003e"],`+
+		`"fragment_size":50}}`)
+
+	h := r.Hits.Hits[0].Highlight
+	is.Equal(h["title"], []string{"Getting started with <mark>Elastic</mark>"})
+	is.Equal(len(h["body"]), 2)
+}
+
+func TestSearchTotalHits(t *testing.T) {
+	is := is.New(t)
+
+	var r elastic.SearchResult
+	is.NoErr(json.Unmarshal([]byte(`{"hits":{"total":12,"hits":[]}}`), &r)) // before version 7
+	is.Equal(r.Hits.Total, elastic.TotalHits{Value: 12, Relation: "eq"})
+
+	r = elastic.SearchResult{}
+	is.NoErr(json.Unmarshal([]byte(`{"hits":{"total":{"value":10000,"relation":"gte"},"hits":[]}}`), &r))
+	is.Equal(r.Hits.Total, elastic.TotalHits{Value: 10000, Relation: "gte"})
+}
+
+func TestStreamSearch(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	pages := []string{
+		`{"_scroll_id":"s1","hits":{"hits":[{"_id":"1"},{"_id":"2"}]}}`,
+		`{"_scroll_id":"s1","hits":{"hits":[{"_id":"3"}]}}`,
+		`{"_scroll_id":"s1","hits":{"hits":[]}}`,
+	}
+	var cleared bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/articles/_search":
+			is.Equal(r.URL.Query().Get("scroll"), "1m")
+		case r.Method == "POST" && r.URL.Path == "/_search/scroll":
+		case r.Method == "DELETE" && r.URL.Path == "/_search/scroll":
+			cleared = true
+			w.Write([]byte(`{"succeeded":true}`))
+			return
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(pages[0]))
+		pages = pages[1:]
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	var buf bytes.Buffer
+	is.NoErr(e.StreamSearch(ctx, "articles", `{"size":2}`, &buf))
+
+	var hits []struct {
+		ID string `json:"_id"`
+	}
+	is.NoErr(json.Unmarshal(buf.Bytes(), &hits)) // output is a JSON array
+	is.Equal(len(hits), 3)
+	is.Equal(hits[2].ID, "3")
+	is.True(cleared) // scroll context released
+}
+
+func TestSearchStream(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/articles/_search" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"search_phase_execution_exception","reason":"all shards failed"},"status":400}`))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`{"took":1,"hits":{"hits":[{"_id":"1"},{"_id":"2"}]}}`))
+		zw.Close()
+	}))
+	defer s.Close()
+
+	// the limit does not apply to a streamed response
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithGzip(), elastic.WithMaxResponseSize(10))
+	rc, err := e.SearchStream(ctx, "articles", `{"query":{"match_all":{}}}`)
+	is.NoErr(err)
+	defer rc.Close()
+
+	var r struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	is.NoErr(json.NewDecoder(rc).Decode(&r)) // decompressed as it is read
+	is.Equal(len(r.Hits.Hits), 2)
+	is.Equal(r.Hits.Hits[1].ID, "2")
+
+	_, err = e.SearchStream(ctx, "missing", `{}`)
+	var spe *elastic.SearchPhaseError
+	is.True(errors.As(err, &spe))
+}
+
+func TestScroll(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	pages := []string{
+		`{"_scroll_id":"s1","hits":{"hits":[{"_id":"1","_source":{"n":1}},{"_id":"2","_source":{"n":2}}]}}`,
+		`{"_scroll_id":"s2","hits":{"hits":[{"_id":"3","_source":{"n":3}}]}}`,
+		`{"_scroll_id":"s2","hits":{"hits":[]}}`,
+	}
+	var cleared string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/articles/_search":
+			is.Equal(r.URL.Query().Get("scroll"), "30s")
+		case r.Method == "POST" && r.URL.Path == "/_search/scroll":
+			is.Equal(body["scroll"], "30s")
+		case r.Method == "DELETE" && r.URL.Path == "/_search/scroll":
+			cleared = body["scroll_id"].([]interface{})[0].(string)
+			w.Write([]byte(`{"succeeded":true}`))
+			return
+		}
+		w.Write([]byte(pages[0]))
+		pages = pages[1:]
+	}))
+	defer s.Close()
+
+	sc := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).Scroll(ctx, "articles", `{"size":2}`, 30*time.Second)
+	defer sc.Close()
+	var ids []string
+	for sc.Next() {
+		for _, h := range sc.Hits() {
+			ids = append(ids, h.ID)
+		}
+	}
+	is.NoErr(sc.Err())
+	is.Equal(ids, []string{"1", "2", "3"})
+	is.Equal(cleared, "s2") // latest scroll id released
+	is.NoErr(sc.Close())    // already closed
+}
+
+func TestSearchIDs(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/articles/_search":
+			var body map[string]interface{}
+			is.NoErr(json.NewDecoder(r.Body).Decode(&body))
+			is.Equal(body["_source"], false)
+			is.True(body["query"] != nil) // query is kept
+			w.Write([]byte(`{"_scroll_id":"s1","hits":{"hits":[{"_id":"a"},{"_id":"b"}]}}`))
+		case "/_search/scroll":
+			if r.Method == "POST" {
+				w.Write([]byte(`{"_scroll_id":"s1","hits":{"hits":[]}}`))
+			}
+		}
+	}))
+	defer s.Close()
+
+	ids, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).SearchIDs(ctx, "articles", `{"query":{"term":{"status":"draft"}}}`)
+	is.NoErr(err)
+	is.Equal(ids, []string{"a", "b"})
+}
+
+func TestMatchedQueries(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"POST /articles/_search": []byte(`{"hits":{"hits":[
+			{"_id":"1","matched_queries":["in_title","in_body"]},
+			{"_id":"2","matched_queries":["in_body"]}
+		]}}`),
+	})
+	defer s.Close()
+
+	q := query.Bool().Should(
+		query.Match("title", "elastic").Name("in_title"),
+		query.Match("body", "elastic").Name("in_body"),
+	)
+	xb, err := json.Marshal(map[string]interface{}{"query": q})
+	is.NoErr(err)
+
+	r, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).Search(ctx, "articles", string(xb))
+	is.NoErr(err)
+	is.Equal(r.Hits.Hits[0].MatchedQueries, []string{"in_title", "in_body"})
+	is.Equal(r.Hits.Hits[1].MatchedQueries, []string{"in_body"})
+}
+
+func TestScriptFields(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		is.Equal(string(xb), `{"query":{"term":{"status":{"value":"published"}}},`+
+			`"script_fields":{"total":{"script":{"source":"doc['price'].value * params.qty","params":{"qty":2}}}}}`)
+		w.Write([]byte(`{"hits":{"hits":[{"_id":"1","fields":{"total":[42.5]}}]}}`))
+	}))
+	defer s.Close()
+
+	r, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).SearchWith(ctx, elastic.SearchRequest{
+		Index: "articles",
+		Query: query.Term("status", "published"),
+		ScriptFields: map[string]elastic.ScriptField{
+			"total": {Script: elastic.Script{
+				Source: "doc['price'].value * params.qty",
+				Params: map[string]interface{}{"qty": 2},
+			}},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(r.Hits.Hits[0].Fields["total"], []interface{}{42.5})
+}
+
+func TestSearchPhaseError(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(fixture("search_phase_error.json"))
+	}))
+	defer s.Close()
+
+	_, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).Search(ctx, "articles", `{"sort":["created"]}`)
+	is.True(errors.Is(err, elastic.ErrSearchPhase))
+
+	var spe *elastic.SearchPhaseError
+	is.True(errors.As(err, &spe))
+	is.Equal(spe.Phase, "query")
+	is.Equal(len(spe.Failures), 1)
+	is.Equal(spe.Failures[0].Reason.Type, "query_shard_exception")
+}
+
+func TestAllowPartialSearchResults(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("allow_partial_search_results") == "false" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"type":"search_phase_execution_exception","reason":"Partial shards failure",` +
+				`"phase":"query","failed_shards":[{"shard":1,"index":"articles","reason":{"type":"no_shard_available_action_exception"}}]},"status":503}`))
+			return
+		}
+		w.Write([]byte(`{"_shards":{"total":2,"successful":1,"failed":1,"failures":[{"shard":1,"index":"articles"}]},` +
+			`"hits":{"hits":[{"_id":"1"}]}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+
+	r, err := e.Search(ctx, "articles", `{}`)
+	is.NoErr(err)
+	is.Equal(r.Shards.Failed, 1) // partial results by default
+
+	_, err = e.Search(ctx, "articles", `{}`, elastic.AllowPartialSearchResults(false))
+	is.True(errors.Is(err, elastic.ErrSearchPhase))
+}
+
+func TestCount(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/articles/_count" && len(xb) > 0:
+			w.Write([]byte(`{"count":7,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0}}`))
+		case r.URL.Path == "/_count":
+			w.Write([]byte(`{"count":120,"_shards":{"total":5,"successful":5,"skipped":0,"failed":0}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	n, err := e.Count(ctx, "articles", `{"query":{"term":{"status":"draft"}}}`)
+	is.NoErr(err)
+	is.Equal(n, int64(7))
+
+	n, err = e.Count(ctx, "", "")
+	is.NoErr(err)
+	is.Equal(n, int64(120)) // every document in every index
+}
+
+func TestRankEval(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	const res = `{"metric_score":0.75,"details":{"go":{"metric_score":0.75,"unrated_docs":[]}},"failures":{}}`
+	var path, body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		path, body = r.Method+" "+r.URL.Path, string(xb)
+		w.Write([]byte(res))
+	}))
+	defer s.Close()
+
+	const req = `{"requests":[{"id":"go","request":{"query":{"match":{"title":"go"}}},` +
+		`"ratings":[{"_index":"articles","_id":"1","rating":1}]}],"metric":{"precision":{"k":10}}}`
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	xb, err := e.RankEval(ctx, "Articles", req)
+	is.NoErr(err)
+	is.Equal(path, "POST /articles/_rank_eval")
+	is.Equal(body, req)
+	is.Equal(string(xb), res)
+
+	_, err = e.RankEval(ctx, "", req)
+	is.NoErr(err)
+	is.Equal(path, "POST /_rank_eval")
+}
+
+func TestWarmAggregations(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var path, body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		path, body = r.Method+" "+r.URL.Path, string(xb)
+		w.Write([]byte(`{"hits":{"total":{"value":1200,"relation":"eq"},"hits":[]},` +
+			`"aggregations":{"tags":{"buckets":[]}}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.WarmAggregations(ctx, "articles", `{"tags":{"terms":{"field":"tags"}}}`))
+	is.Equal(path, "POST /articles/_search")
+	var v struct {
+		Size *int            `json:"size"`
+		Aggs json.RawMessage `json:"aggs"`
+	}
+	is.NoErr(json.Unmarshal([]byte(body), &v))
+	is.True(v.Size != nil && *v.Size == 0)
+	is.Equal(string(v.Aggs), `{"tags":{"terms":{"field":"tags"}}}`)
+
+	body = ""
+	err := e.WarmAggregations(ctx, "articles", `{"tags":`)
+	is.Equal(err.Error(), "WarmAggregations - aggs is not valid JSON")
+	is.Equal(body, "") // nothing sent
+}
+
+func TestSearchIterator(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	pages := map[string]string{
+		"":                                 `[{"_id":"1","sort":[1609459200000,9007199254740993]},{"_id":"2","sort":[1609459200000,9007199254740995]}]`,
+		`[1609459200000,9007199254740995]`: `[{"_id":"3","sort":[1612137600000,1]}]`,
+	}
+	var bodies []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(xb))
+		var req struct {
+			SearchAfter json.RawMessage `json:"search_after"`
+		}
+		json.Unmarshal(xb, &req)
+		w.Write([]byte(`{"hits":{"hits":` + pages[string(req.SearchAfter)] + `}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	it := e.SearchIterator(ctx, elastic.SearchRequest{
+		Index: "articles",
+		From:  20, // ignored
+		Sort:  []elastic.SearchSort{{Field: "published", Desc: true}, {Field: "seq"}},
+	}, 2)
+
+	var ids []string
+	for it.Next() {
+		for _, h := range it.Hits() {
+			ids = append(ids, h.ID)
+		}
+	}
+	is.NoErr(it.Err())
+	is.Equal(ids, []string{"1", "2", "3"})
+	is.Equal(len(bodies), 2) // the short second page is the last
+	is.Equal(bodies[0], `{"size":2,"sort":[{"published":{"order":"desc"}},{"seq":{"order":"asc"}}]}`)
+	is.Equal(bodies[1], `{"size":2,"sort":[{"published":{"order":"desc"}},{"seq":{"order":"asc"}}],`+
+		`"search_after":[1609459200000,9007199254740995]}`)
+
+	// search_after needs a sort
+	it = e.SearchIterator(ctx, elastic.SearchRequest{Index: "articles"}, 10)
+	is.True(!it.Next())
+	is.True(it.Err() != nil)
+}
+
+func TestPointInTime(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var calls, bodies []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		calls = append(calls, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		switch r.URL.Path {
+		case "/articles/_pit":
+			w.Write([]byte(`{"id":"pit-1"}`))
+		case "/_search":
+			bodies = append(bodies, string(xb))
+			hits := `[{"_id":"1","sort":[0]},{"_id":"2","sort":[1]}]`
+			if len(bodies) > 1 {
+				hits = `[]`
+			}
+			w.Write([]byte(`{"pit_id":"pit-` + strconv.Itoa(len(bodies)+1) + `","hits":{"hits":` + hits + `}}`))
+		case "/_pit":
+			bodies = append(bodies, string(xb))
+			w.Write([]byte(`{"succeeded":true}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8))
+	id, err := e.OpenPointInTime(ctx, "articles", time.Minute)
+	is.NoErr(err)
+	is.Equal(id, "pit-1")
+
+	it := e.SearchIterator(ctx, elastic.SearchRequest{
+		Index: "articles", // ignored in favour of the point in time
+		Sort:  []elastic.SearchSort{{Field: "_shard_doc"}},
+		PIT:   &elastic.PointInTime{ID: id, KeepAlive: time.Minute},
+	}, 2)
+	n := 0
+	for it.Next() {
+		n += len(it.Hits())
+	}
+	is.NoErr(it.Err())
+	is.Equal(n, 2)
+
+	is.NoErr(e.ClosePointInTime(ctx, "pit-3"))
+
+	is.Equal(calls, []string{"POST /articles/_pit?keep_alive=1m", "POST /_search?", "POST /_search?", "DELETE /_pit?"})
+	is.Equal(bodies[0], `{"size":2,"sort":[{"_shard_doc":{"order":"asc"}}],"pit":{"id":"pit-1","keep_alive":"1m"}}`)
+	is.Equal(bodies[1], `{"size":2,"sort":[{"_shard_doc":{"order":"asc"}}],"search_after":[1],`+
+		`"pit":{"id":"pit-2","keep_alive":"1m"}}`) // the id from the first page
+	is.Equal(bodies[2], `{"id":"pit-3"}`)
+}
+
+func TestAsyncSearch(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var calls []string
+	polls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		switch r.Method {
+		case "POST":
+			w.Write([]byte(`{"id":"FmRld","is_partial":true,"is_running":true,"response":{"hits":{"total":{"value":10,"relation":"gte"},"hits":[]}}}`))
+		case "GET":
+			polls++
+			running := strconv.FormatBool(polls < 2)
+			w.Write([]byte(`{"id":"FmRld","is_partial":` + running + `,"is_running":` + running + `,` +
+				`"response":{"hits":{"total":{"value":1200,"relation":"eq"},"hits":[]}}}`))
+		case "DELETE":
+			w.Write([]byte(`{"acknowledged":true}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8))
+	as, err := e.SubmitAsyncSearch(ctx, elastic.SearchRequest{Index: "logs-*"}, 2*time.Second, time.Hour)
+	is.NoErr(err)
+	is.True(as.IsRunning)
+	is.Equal(as.ID, "FmRld")
+
+	as, err = e.WaitForAsyncSearch(ctx, as.ID, time.Millisecond)
+	is.NoErr(err)
+	is.True(!as.IsPartial)
+	is.Equal(as.Response.Hits.Total.Value, int64(1200))
+
+	is.NoErr(e.DeleteAsyncSearch(ctx, as.ID))
+	is.Equal(calls, []string{
+		"POST /logs-*/_async_search?keep_alive=1h&wait_for_completion_timeout=2s",
+		"GET /_async_search/FmRld?",
+		"GET /_async_search/FmRld?",
+		"DELETE /_async_search/FmRld?",
+	})
+}
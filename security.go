@@ -0,0 +1,152 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// User is a native realm user. Password is only sent, and may be left empty when updating a user to keep the
+// current one.
+type User struct {
+	Password string                 `json:"password,omitempty"`
+	Roles    []string               `json:"roles"`
+	FullName string                 `json:"full_name,omitempty"`
+	Email    string                 `json:"email,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Enabled  *bool                  `json:"enabled,omitempty"` // true by default
+}
+
+// Role is a set of privileges. Cluster privileges are eg "monitor" or "manage_index_templates", and index privileges
+// are eg "read", "write" or "create_index".
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/security-privileges.html
+type Role struct {
+	Cluster []string          `json:"cluster,omitempty"`
+	Indices []IndexPrivileges `json:"indices,omitempty"`
+	RunAs   []string          `json:"run_as,omitempty"`
+}
+
+// IndexPrivileges grants privileges on the indices matching Names, which may be patterns, eg "logs-*"
+type IndexPrivileges struct {
+	Names      []string    `json:"names"`
+	Privileges []string    `json:"privileges"`
+	Query      interface{} `json:"query,omitempty"` // limits the documents that can be read, eg a builder from the query package
+}
+
+// APIKey is a new API key. Encoded is the value to pass to WithAPIKey; the key itself cannot be fetched again.
+type APIKey struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Expiration int64  `json:"expiration"` // in milliseconds since the epoch, 0 if the key does not expire
+	APIKey     string `json:"api_key"`
+	Encoded    string `json:"encoded"` // from version 7.16
+}
+
+// PutUser creates a native realm user, or updates an existing one
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-put-user.html
+func (c *Client) PutUser(ctx context.Context, username string, u User) error {
+	if err := c.putSecurity(ctx, "/_security/user/"+username, u); err != nil {
+		return errors.Wrap(err, "PutUser")
+	}
+	return nil
+}
+
+// DeleteUser deletes a native realm user
+func (c *Client) DeleteUser(ctx context.Context, username string) error {
+	_, err := c.request(ctx, "DELETE", "/_security/user/"+username, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "DeleteUser")
+	}
+	return nil
+}
+
+// PutRole creates a role, or replaces an existing one
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-put-role.html
+func (c *Client) PutRole(ctx context.Context, name string, r Role) error {
+	if err := c.putSecurity(ctx, "/_security/role/"+name, r); err != nil {
+		return errors.Wrap(err, "PutRole")
+	}
+	return nil
+}
+
+// DeleteRole deletes a role
+func (c *Client) DeleteRole(ctx context.Context, name string) error {
+	_, err := c.request(ctx, "DELETE", "/_security/role/"+name, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "DeleteRole")
+	}
+	return nil
+}
+
+// CreateAPIKey creates an API key, eg one for each service, that expires after expiration, or never if it is zero.
+// The key has the privileges of the user creating it, limited to roles if any are given, by name.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-create-api-key.html
+func (c *Client) CreateAPIKey(ctx context.Context, name string, expiration time.Duration, roles map[string]Role) (*APIKey, error) {
+
+	body := map[string]interface{}{"name": name}
+	if expiration > 0 {
+		body["expiration"] = timeValue(expiration)
+	}
+	if len(roles) > 0 {
+		body["role_descriptors"] = roles
+	}
+	xb, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	xb, err = c.request(ctx, "POST", "/_security/api_key", bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "CreateAPIKey")
+	}
+
+	var k APIKey
+	if err := json.Unmarshal(xb, &k); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &k, nil
+}
+
+// InvalidateAPIKeys invalidates the API keys with ids, so that they can no longer be used. Keys that were already
+// invalidated are not an error.
+func (c *Client) InvalidateAPIKeys(ctx context.Context, ids ...string) error {
+
+	xb, err := json.Marshal(map[string][]string{"ids": ids})
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	xb, err = c.request(ctx, "DELETE", "/_security/api_key", bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "InvalidateAPIKeys")
+	}
+
+	var r struct {
+		ErrorCount   int               `json:"error_count"`
+		ErrorDetails []json.RawMessage `json:"error_details"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return errors.Wrap(err, "Unmarshal")
+	}
+	if r.ErrorCount > 0 {
+		var reason string
+		if len(r.ErrorDetails) > 0 {
+			_, reason, _ = jsonError([]byte(`{"error":` + string(r.ErrorDetails[0]) + `}`))
+		}
+		return errors.Errorf("InvalidateAPIKeys - %d keys could not be invalidated: %s", r.ErrorCount, reason)
+	}
+	return nil
+}
+
+// putSecurity puts v as the body of a security API request
+func (c *Client) putSecurity(ctx context.Context, path string, v interface{}) error {
+	xb, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+	_, err = c.request(ctx, "PUT", path, bytes.NewReader(xb), standardHeaders)
+	return err
+}
@@ -0,0 +1,80 @@
+package elastic_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestSecurity(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	requests := map[string]string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		requests[r.Method+" "+r.URL.Path] = string(xb)
+		switch r.URL.Path {
+		case "/_security/api_key":
+			if r.Method == "POST" {
+				w.Write([]byte(`{"id":"k1","name":"ingest","expiration":1700000000000,"api_key":"secret",` +
+					`"encoded":"azE6c2VjcmV0"}`))
+				return
+			}
+			w.Write([]byte(`{"invalidated_api_keys":["k1"],"previously_invalidated_api_keys":[],"error_count":0}`))
+		case "/_security/user/ingest":
+			w.Write([]byte(`{"created":true}`))
+		default:
+			w.Write([]byte(`{"found":true}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+
+	role := elastic.Role{
+		Cluster: []string{"monitor"},
+		Indices: []elastic.IndexPrivileges{{Names: []string{"logs-*"}, Privileges: []string{"create_doc"}}},
+	}
+	is.NoErr(e.PutRole(ctx, "logs_writer", role))
+	is.Equal(requests["PUT /_security/role/logs_writer"],
+		`{"cluster":["monitor"],"indices":[{"names":["logs-*"],"privileges":["create_doc"]}]}`)
+
+	is.NoErr(e.PutUser(ctx, "ingest", elastic.User{Password: "changeme", Roles: []string{"logs_writer"}}))
+	is.Equal(requests["PUT /_security/user/ingest"], `{"password":"changeme","roles":["logs_writer"]}`)
+
+	k, err := e.CreateAPIKey(ctx, "ingest", 30*24*time.Hour, map[string]elastic.Role{"logs_writer": role})
+	is.NoErr(err)
+	is.Equal(k.Encoded, "azE6c2VjcmV0")
+	is.True(strings.HasPrefix(requests["POST /_security/api_key"], `{"expiration":"720h","name":"ingest","role_descriptors":`))
+
+	is.NoErr(e.InvalidateAPIKeys(ctx, k.ID))
+	is.Equal(requests["DELETE /_security/api_key"], `{"ids":["k1"]}`)
+
+	is.NoErr(e.DeleteUser(ctx, "ingest"))
+	is.NoErr(e.DeleteRole(ctx, "logs_writer"))
+	_, ok := requests["DELETE /_security/role/logs_writer"]
+	is.True(ok)
+}
+
+func TestInvalidateAPIKeysError(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"invalidated_api_keys":[],"previously_invalidated_api_keys":[],"error_count":1,` +
+			`"error_details":[{"type":"exception","reason":"error invalidating api key"}]}`))
+	}))
+	defer s.Close()
+
+	err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).InvalidateAPIKeys(ctx, "k1")
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "error invalidating api key"))
+}
@@ -0,0 +1,244 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IndexSettings are common settings for a new index, for CreateIndexWith. Analysis holds custom analyzers,
+// tokenizers and filters, eg {"analyzer": {"folding": {"tokenizer": "standard", "filter": ["lowercase",
+// "asciifolding"]}}}.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/index-modules.html
+type IndexSettings struct {
+	NumberOfShards   int                    `json:"number_of_shards,omitempty"`
+	NumberOfReplicas *int                   `json:"number_of_replicas,omitempty"` // a pointer so 0 can be set
+	RefreshInterval  string                 `json:"refresh_interval,omitempty"`   // eg "30s", or "-1" to disable
+	Analysis         map[string]interface{} `json:"analysis,omitempty"`
+	Lifecycle        *LifecycleSettings     `json:"lifecycle,omitempty"`
+}
+
+// LifecycleSettings attach an index lifecycle policy to an index, or to the indices created from a template.
+// RolloverAlias is the write alias rolled over by a rollover action, if the policy has one.
+type LifecycleSettings struct {
+	Name          string `json:"name"`
+	RolloverAlias string `json:"rollover_alias,omitempty"`
+}
+
+// UnmarshalJSON reads settings as Elasticsearch returns them, nested under "index" with numbers as strings, as well
+// as in the form they are marshaled
+func (s *IndexSettings) UnmarshalJSON(xb []byte) error {
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(xb, &m); err != nil {
+		return err
+	}
+	if v, ok := m["index"]; ok {
+		var index map[string]json.RawMessage
+		if err := json.Unmarshal(v, &index); err != nil {
+			return err
+		}
+		for k, v := range index {
+			m[k] = v
+		}
+	}
+
+	*s = IndexSettings{}
+	for k, v := range m {
+		var err error
+		switch k {
+		case "number_of_shards":
+			s.NumberOfShards, err = intSetting(v)
+		case "number_of_replicas":
+			var n int
+			n, err = intSetting(v)
+			s.NumberOfReplicas = &n
+		case "refresh_interval":
+			err = json.Unmarshal(v, &s.RefreshInterval)
+		case "analysis":
+			err = json.Unmarshal(v, &s.Analysis)
+		case "lifecycle":
+			err = json.Unmarshal(v, &s.Lifecycle)
+		}
+		if err != nil {
+			return errors.Wrap(err, k)
+		}
+	}
+
+	return nil
+}
+
+// intSetting parses an integer setting, which Elasticsearch returns as a string
+func intSetting(v json.RawMessage) (int, error) {
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return strconv.Atoi(s)
+	}
+	var n int
+	err := json.Unmarshal(v, &n)
+	return n, err
+}
+
+// PutIndexSettings updates the dynamic settings of an index. The settings are a JSON object of setting names and
+// values, eg `{"index": {"number_of_replicas": 2}}`.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-update-settings.html
+func (c *Client) PutIndexSettings(ctx context.Context, index, settings string) error {
+	u := "/" + strings.ToLower(index) + "/_settings"
+	_, err := c.request(ctx, "PUT", u, strings.NewReader(settings), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "PutIndexSettings")
+	}
+	return nil
+}
+
+// GetIndexSettings returns the settings of index, which may be a wildcard pattern or comma separated list, keyed by
+// index name and then by the full setting name, eg "index.refresh_interval". Values are strings, or lists of
+// strings, as Elasticsearch returns them.
+func (c *Client) GetIndexSettings(ctx context.Context, index string) (map[string]map[string]interface{}, error) {
+
+	u := "/" + strings.ToLower(index) + "/_settings?flat_settings=true"
+	xb, err := c.request(ctx, "GET", u, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetIndexSettings")
+	}
+
+	var r map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	m := make(map[string]map[string]interface{}, len(r))
+	for k, v := range r {
+		m[k] = v.Settings
+	}
+	return m, nil
+}
+
+// SetRefreshInterval sets index.refresh_interval, how often new writes become visible to search, eg "30s", or "-1"
+// to stop refreshing altogether. Turning refresh off for a bulk load, then back on, speeds up indexing considerably.
+// An empty interval resets the setting to the default of "1s".
+func (c *Client) SetRefreshInterval(ctx context.Context, index, interval string) error {
+	v := "null"
+	if interval != "" {
+		v = strconv.Quote(interval)
+	}
+	if err := c.PutIndexSettings(ctx, index, `{"index": {"refresh_interval": `+v+`}}`); err != nil {
+		return errors.Wrap(err, "SetRefreshInterval")
+	}
+	return nil
+}
+
+// SetReplicas sets index.number_of_replicas. Dropping replicas to 0 for a bulk load, then restoring them, means
+// documents are indexed once rather than on every copy, with the replicas rebuilt from the primaries afterwards.
+func (c *Client) SetReplicas(ctx context.Context, index string, n int) error {
+	s := `{"index": {"number_of_replicas": ` + strconv.Itoa(n) + `}}`
+	if err := c.PutIndexSettings(ctx, index, s); err != nil {
+		return errors.Wrap(err, "SetReplicas")
+	}
+	return nil
+}
+
+// SetMaxResultWindow sets index.max_result_window, the upper limit of from + size for searches on the index, which
+// defaults to 10000. Raising it allows deeper from/size paging but every page has to be collected and sorted by each
+// shard in memory, so heap use grows with the window. Prefer search_after or scroll for deep paging where possible.
+func (c *Client) SetMaxResultWindow(ctx context.Context, index string, n int) error {
+	s := `{"index": {"max_result_window": ` + strconv.Itoa(n) + `}}`
+	if err := c.PutIndexSettings(ctx, index, s); err != nil {
+		return errors.Wrap(err, "SetMaxResultWindow")
+	}
+	return nil
+}
+
+// SetBestCompression switches an index to the best_compression codec, which uses DEFLATE rather than LZ4 for stored
+// fields. This typically saves 15-25% of disk space in exchange for slower indexing and slower fetching of _source,
+// so it suits cold indices that are rarely written or read. The codec is a static setting so the index is closed
+// while it is changed, and is unavailable for that time, then re-opened. Only segments written after the change are
+// compressed with the new codec; force merge the index to rewrite the existing segments. Once closed the index is
+// re-opened with a context detached from ctx, so cancelling ctx does not leave it closed.
+func (c *Client) SetBestCompression(ctx context.Context, index string) error {
+
+	err := c.CloseIndex(ctx, index)
+	if err != nil {
+		return errors.Wrap(err, "SetBestCompression")
+	}
+
+	err = c.PutIndexSettings(ctx, index, `{"index": {"codec": "best_compression"}}`)
+
+	// Re-open even if the update failed, or ctx was cancelled, so the index isn't left closed
+	if oerr := c.OpenIndex(context.Background(), index); err == nil {
+		err = oerr
+	}
+	if err != nil {
+		return errors.Wrap(err, "SetBestCompression")
+	}
+
+	return nil
+}
+
+// ClusterSettings are cluster-wide settings, keyed by the full setting name, eg "cluster.routing.allocation.enable".
+// Persistent settings survive a full cluster restart while transient settings do not; transient settings are
+// deprecated from Elasticsearch 7.16. For PutClusterSettings a nil value resets a setting to its default.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/cluster-update-settings.html
+type ClusterSettings struct {
+	Persistent map[string]interface{} `json:"persistent,omitempty"`
+	Transient  map[string]interface{} `json:"transient,omitempty"`
+}
+
+// GetClusterSettings returns the cluster settings that have been set explicitly. Settings left at their default are
+// not included.
+func (c *Client) GetClusterSettings(ctx context.Context) (*ClusterSettings, error) {
+
+	xb, err := c.request(ctx, "GET", "/_cluster/settings?flat_settings=true", nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetClusterSettings")
+	}
+
+	var s ClusterSettings
+	if err := json.Unmarshal(xb, &s); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &s, nil
+}
+
+// PutClusterSettings updates the cluster settings in s, leaving others unchanged, eg
+//
+//	c.PutClusterSettings(ctx, elastic.ClusterSettings{Persistent: map[string]interface{}{
+//		"cluster.routing.allocation.enable": "primaries",
+//	}})
+func (c *Client) PutClusterSettings(ctx context.Context, s ClusterSettings) error {
+
+	xb, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	_, err = c.request(ctx, "PUT", "/_cluster/settings", bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "PutClusterSettings")
+	}
+	return nil
+}
+
+// SetClusterReadOnly sets or clears the persistent cluster.blocks.read_only setting. While set, no index can be
+// written to and no metadata, such as mappings, can be changed, which freezes the whole cluster for maintenance in
+// one step rather than blocking indices individually. Clearing removes the setting rather than setting it false.
+func (c *Client) SetClusterReadOnly(ctx context.Context, readOnly bool) error {
+
+	v := "null"
+	if readOnly {
+		v = "true"
+	}
+	body := `{"persistent": {"cluster.blocks.read_only": ` + v + `}}`
+
+	_, err := c.request(ctx, "PUT", "/_cluster/settings", strings.NewReader(body), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "SetClusterReadOnly")
+	}
+
+	return nil
+}
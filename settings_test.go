@@ -0,0 +1,113 @@
+package elastic_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/elastictest"
+)
+
+func TestClusterSettings(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	tr := elastictest.NewTransport()
+	tr.Handle("GET", "/_cluster/settings", http.StatusOK, `{"persistent":{"cluster.routing.allocation.enable":"primaries"},`+
+		`"transient":{}}`)
+	tr.Handle("PUT", "/_cluster/settings", http.StatusOK, `{"acknowledged":true,"persistent":{},"transient":{}}`)
+	e := elastictest.NewClient(tr)
+
+	s, err := e.GetClusterSettings(ctx)
+	is.NoErr(err)
+	is.Equal(s.Persistent["cluster.routing.allocation.enable"], "primaries")
+	r, _ := tr.LastRequest("GET", "/_cluster/settings")
+	is.Equal(r.Query.Get("flat_settings"), "true")
+
+	err = e.PutClusterSettings(ctx, elastic.ClusterSettings{Persistent: map[string]interface{}{
+		"cluster.routing.allocation.enable": nil, // reset
+	}})
+	is.NoErr(err)
+	r, _ = tr.LastRequest("PUT", "/_cluster/settings")
+	is.Equal(string(r.Body), `{"persistent":{"cluster.routing.allocation.enable":null}}`)
+
+	is.NoErr(e.SetClusterReadOnly(ctx, true))
+	r, _ = tr.LastRequest("PUT", "/_cluster/settings")
+	is.Equal(string(r.Body), `{"persistent": {"cluster.blocks.read_only": true}}`)
+	is.NoErr(e.SetClusterReadOnly(ctx, false))
+	r, _ = tr.LastRequest("PUT", "/_cluster/settings")
+	is.Equal(string(r.Body), `{"persistent": {"cluster.blocks.read_only": null}}`) // removed, not false
+}
+
+func TestIndexSettings(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	tr := elastictest.NewTransport()
+	tr.Handle("GET", "/articles/_settings", http.StatusOK, `{"articles":{"settings":{"index.number_of_replicas":"1",`+
+		`"index.refresh_interval":"30s","index.routing.allocation.include._tier_preference":"data_content"}}}`)
+	tr.Handle("PUT", "/articles/_settings", http.StatusOK, `{"acknowledged":true}`)
+	e := elastictest.NewClient(tr)
+
+	m, err := e.GetIndexSettings(ctx, "articles")
+	is.NoErr(err)
+	is.Equal(m["articles"]["index.refresh_interval"], "30s")
+	is.Equal(m["articles"]["index.number_of_replicas"], "1")
+
+	// around a bulk load
+	is.NoErr(e.SetRefreshInterval(ctx, "articles", "-1"))
+	r, _ := tr.LastRequest("PUT", "/articles/_settings")
+	is.Equal(string(r.Body), `{"index": {"refresh_interval": "-1"}}`)
+	is.NoErr(e.SetReplicas(ctx, "articles", 0))
+	r, _ = tr.LastRequest("PUT", "/articles/_settings")
+	is.Equal(string(r.Body), `{"index": {"number_of_replicas": 0}}`)
+	is.NoErr(e.SetRefreshInterval(ctx, "articles", ""))
+	r, _ = tr.LastRequest("PUT", "/articles/_settings")
+	is.Equal(string(r.Body), `{"index": {"refresh_interval": null}}`)
+
+	is.NoErr(e.PutIndexSettings(ctx, "Articles", `{"index": {"number_of_replicas": 2}}`))
+	r, _ = tr.LastRequest("PUT", "/articles/_settings")
+	is.Equal(string(r.Body), `{"index": {"number_of_replicas": 2}}`)
+	is.NoErr(e.SetMaxResultWindow(ctx, "articles", 50000))
+	r, _ = tr.LastRequest("PUT", "/articles/_settings")
+	is.Equal(string(r.Body), `{"index": {"max_result_window": 50000}}`)
+}
+
+func TestSetBestCompression(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls []string
+	fail := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := io.ReadAll(r.Body)
+		calls = append(calls, r.Method+" "+r.URL.Path+" "+string(xb))
+		if r.Method == "PUT" && fail {
+			cancel()
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"illegal_argument_exception","reason":"bad codec"},"status":400}`))
+			return
+		}
+		w.Write([]byte(`{"acknowledged":true}`))
+	}))
+	defer s.Close()
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+
+	is.NoErr(e.SetBestCompression(ctx, "logs"))
+	is.Equal(calls, []string{
+		"POST /logs/_close ",
+		`PUT /logs/_settings {"index": {"codec": "best_compression"}}`,
+		"POST /logs/_open ",
+	})
+
+	// the index is re-opened after a failed update, even once ctx is cancelled
+	calls, fail = nil, true
+	is.True(e.SetBestCompression(ctx, "logs") != nil)
+	is.Equal(len(calls), 3)
+	is.Equal(calls[2], "POST /logs/_open ")
+}
@@ -0,0 +1,196 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SnapshotInfo describes a snapshot. State is IN_PROGRESS while it is being taken, then SUCCESS, PARTIAL if some
+// shards could not be snapshotted, or FAILED.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/snapshot-restore.html
+type SnapshotInfo struct {
+	Snapshot          string            `json:"snapshot"`
+	UUID              string            `json:"uuid"`
+	State             string            `json:"state"`
+	Indices           []string          `json:"indices"`
+	StartTimeInMillis int64             `json:"start_time_in_millis"`
+	EndTimeInMillis   int64             `json:"end_time_in_millis"`
+	DurationInMillis  int64             `json:"duration_in_millis"`
+	Failures          []json.RawMessage `json:"failures"`
+	Shards            struct {
+		Total      int `json:"total"`
+		Failed     int `json:"failed"`
+		Successful int `json:"successful"`
+	} `json:"shards"`
+}
+
+// RestoreOptions selects what RestoreSnapshot restores and where to. With no Indices every index in the snapshot is
+// restored. RenamePattern and RenameReplacement rename the restored indices, eg "(.+)" and "restored_$1" to restore
+// alongside the live indices rather than into them, which would require them to be closed first.
+type RestoreOptions struct {
+	Indices           []string
+	RenamePattern     string
+	RenameReplacement string
+}
+
+// PutRepository registers, or updates, a snapshot repository. The type is eg "fs", with a "location" setting that
+// is listed in path.repo on every node, or "s3" with a "bucket" setting.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/put-snapshot-repo-api.html
+func (c *Client) PutRepository(ctx context.Context, name, typ string, settings map[string]interface{}) error {
+
+	xb, err := json.Marshal(map[string]interface{}{"type": typ, "settings": settings})
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	_, err = c.request(ctx, "PUT", "/_snapshot/"+name, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "PutRepository")
+	}
+	return nil
+}
+
+// VerifyRepository checks that every node can write to a repository and returns the names of the nodes that can
+func (c *Client) VerifyRepository(ctx context.Context, name string) ([]string, error) {
+
+	xb, err := c.request(ctx, "POST", "/_snapshot/"+name+"/_verify", nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "VerifyRepository")
+	}
+
+	var r struct {
+		Nodes map[string]struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	var xs []string
+	for _, n := range r.Nodes {
+		xs = append(xs, n.Name)
+	}
+	return xs, nil
+}
+
+// CreateSnapshot starts a snapshot of indices, or of every index if there are none, into repo. The snapshot runs in
+// the background: wait for it with WaitForSnapshot.
+func (c *Client) CreateSnapshot(ctx context.Context, repo, name string, indices []string) error {
+
+	body := map[string]interface{}{}
+	if len(indices) > 0 {
+		body["indices"] = strings.Join(indices, ",")
+	}
+	xb, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	_, err = c.request(ctx, "PUT", "/_snapshot/"+repo+"/"+name, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "CreateSnapshot")
+	}
+	return nil
+}
+
+// GetSnapshot returns a snapshot in repo
+func (c *Client) GetSnapshot(ctx context.Context, repo, name string) (*SnapshotInfo, error) {
+	xs, err := c.snapshots(ctx, repo, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetSnapshot")
+	}
+	if len(xs) != 1 {
+		return nil, errors.Wrap(ErrNotFound, "GetSnapshot - "+name)
+	}
+	return &xs[0], nil
+}
+
+// ListSnapshots returns every snapshot in repo, oldest first
+func (c *Client) ListSnapshots(ctx context.Context, repo string) ([]SnapshotInfo, error) {
+	xs, err := c.snapshots(ctx, repo, "_all")
+	if err != nil {
+		return nil, errors.Wrap(err, "ListSnapshots")
+	}
+	return xs, nil
+}
+
+// snapshots fetches the snapshots in repo matching name
+func (c *Client) snapshots(ctx context.Context, repo, name string) ([]SnapshotInfo, error) {
+
+	xb, err := c.request(ctx, "GET", "/_snapshot/"+repo+"/"+name, nil, standardHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	var r struct {
+		Snapshots []SnapshotInfo `json:"snapshots"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return r.Snapshots, nil
+}
+
+// WaitForSnapshot polls a snapshot every interval until it is no longer in progress and returns its final state.
+// The error is non-nil if the snapshot did not succeed in full.
+func (c *Client) WaitForSnapshot(ctx context.Context, repo, name string, interval time.Duration) (*SnapshotInfo, error) {
+
+	for {
+		si, err := c.GetSnapshot(ctx, repo, name)
+		if err != nil {
+			return nil, errors.Wrap(err, "WaitForSnapshot")
+		}
+		switch si.State {
+		case "IN_PROGRESS", "STARTED":
+		case "SUCCESS":
+			return si, nil
+		default:
+			return si, errors.New("WaitForSnapshot - snapshot " + name + " finished in state " + si.State +
+				" with " + strconv.Itoa(si.Shards.Failed) + " failed shards")
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, errors.Wrap(err, "WaitForSnapshot")
+		}
+	}
+}
+
+// RestoreSnapshot starts restoring a snapshot. Restored indices must not already be open, so either close them
+// first or rename them with opts. The restore runs in the background, and the indices become yellow or green as
+// their shards are recovered.
+func (c *Client) RestoreSnapshot(ctx context.Context, repo, name string, opts RestoreOptions) error {
+
+	body := map[string]interface{}{}
+	if len(opts.Indices) > 0 {
+		body["indices"] = strings.Join(opts.Indices, ",")
+	}
+	if opts.RenamePattern != "" {
+		body["rename_pattern"] = opts.RenamePattern
+		body["rename_replacement"] = opts.RenameReplacement
+	}
+	xb, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	_, err = c.request(ctx, "POST", "/_snapshot/"+repo+"/"+name+"/_restore", bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "RestoreSnapshot")
+	}
+	return nil
+}
+
+// DeleteSnapshot deletes a snapshot, freeing the files in the repository that no other snapshot uses
+func (c *Client) DeleteSnapshot(ctx context.Context, repo, name string) error {
+	_, err := c.request(ctx, "DELETE", "/_snapshot/"+repo+"/"+name, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "DeleteSnapshot")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package elastic_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestSnapshots(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	bodies := map[string]string{}
+	polls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		bodies[r.Method+" "+r.URL.Path] = string(xb)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /_snapshot/backups/_verify":
+			w.Write([]byte(`{"nodes":{"abc":{"name":"node-1"}}}`))
+		case "GET /_snapshot/backups/nightly":
+			polls++
+			state := "IN_PROGRESS"
+			if polls > 1 {
+				state = "SUCCESS"
+			}
+			w.Write([]byte(`{"snapshots":[{"snapshot":"nightly","state":"` + state + `","indices":["articles"],` +
+				`"shards":{"total":1,"failed":0,"successful":1}}]}`))
+		case "GET /_snapshot/backups/_all":
+			w.Write([]byte(`{"snapshots":[{"snapshot":"a","state":"SUCCESS"},{"snapshot":"b","state":"PARTIAL"}]}`))
+		default:
+			w.Write([]byte(`{"acknowledged":true}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	is.NoErr(e.PutRepository(ctx, "backups", "fs", map[string]interface{}{"location": "/mnt/backups"}))
+	is.Equal(bodies["PUT /_snapshot/backups"], `{"settings":{"location":"/mnt/backups"},"type":"fs"}`)
+
+	nodes, err := e.VerifyRepository(ctx, "backups")
+	is.NoErr(err)
+	is.Equal(nodes, []string{"node-1"})
+
+	is.NoErr(e.CreateSnapshot(ctx, "backups", "nightly", []string{"articles", "authors"}))
+	is.Equal(bodies["PUT /_snapshot/backups/nightly"], `{"indices":"articles,authors"}`)
+
+	si, err := e.WaitForSnapshot(ctx, "backups", "nightly", time.Millisecond)
+	is.NoErr(err)
+	is.Equal(polls, 2)
+	is.Equal(si.State, "SUCCESS")
+
+	xs, err := e.ListSnapshots(ctx, "backups")
+	is.NoErr(err)
+	is.Equal(len(xs), 2)
+	is.Equal(xs[1].State, "PARTIAL")
+
+	is.NoErr(e.RestoreSnapshot(ctx, "backups", "nightly", elastic.RestoreOptions{
+		Indices:           []string{"articles"},
+		RenamePattern:     "(.+)",
+		RenameReplacement: "restored_$1",
+	}))
+	is.Equal(bodies["POST /_snapshot/backups/nightly/_restore"],
+		`{"indices":"articles","rename_pattern":"(.+)","rename_replacement":"restored_$1"}`)
+
+	is.NoErr(e.DeleteSnapshot(ctx, "backups", "nightly"))
+}
@@ -0,0 +1,97 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// uriNodesHTTP lists the http publish address of every node
+const uriNodesHTTP = "/_nodes/http"
+
+// Sniff replaces the client's hosts with the http addresses of the nodes currently in the cluster, so that new nodes
+// start receiving requests and removed nodes stop. The scheme of the configured hosts is kept. If the cluster
+// reports no nodes with http enabled the hosts are left as they are. See WithSniffInterval to sniff periodically.
+func (c *Client) Sniff(ctx context.Context) error {
+
+	xb, err := c.request(ctx, "GET", uriNodesHTTP, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "Sniff")
+	}
+
+	var r struct {
+		Nodes map[string]struct {
+			HTTP struct {
+				PublishAddress string `json:"publish_address"`
+			} `json:"http"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return errors.Wrap(err, "Unmarshal")
+	}
+
+	scheme := "http"
+	if hosts := c.pool.urls(); len(hosts) > 0 {
+		if u, err := url.Parse(hosts[0]); err == nil && u.Scheme != "" {
+			scheme = u.Scheme
+		}
+	}
+
+	var urls []string
+	for _, n := range r.Nodes {
+		if addr := publishHost(n.HTTP.PublishAddress); addr != "" {
+			urls = append(urls, scheme+"://"+addr)
+		}
+	}
+	if len(urls) > 0 {
+		c.pool.set(urls)
+	}
+
+	return nil
+}
+
+// publishHost returns host:port from a publish address, which is either "ip:port" or "hostname/ip:port". The
+// hostname is preferred so that TLS certificates for it verify.
+func publishHost(addr string) string {
+	i := strings.Index(addr, "/")
+	if i < 0 {
+		return addr
+	}
+	_, port, err := net.SplitHostPort(addr[i+1:])
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(addr[:i], port)
+}
+
+// sniff calls Sniff every interval until the client is closed. Errors are ignored, leaving the hosts as they were
+// until the next sniff.
+func (c *Client) sniff(interval time.Duration) {
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			c.Sniff(ctx)
+			cancel()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Close stops background work such as sniffing. The client must not be used after it is closed.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
@@ -0,0 +1,113 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// SQLResult is a page of rows from an SQL query. Columns are only returned with the first page. If Cursor is set
+// there are more rows, fetched with SQLNext.
+type SQLResult struct {
+	Columns []SQLColumn     `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	Cursor  string          `json:"cursor"`
+}
+
+// SQLColumn is the name and Elasticsearch type, eg "text", "long" or "datetime", of a column in an SQLResult
+type SQLColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// SQL runs an Elasticsearch SQL query, eg `SELECT author, COUNT(*) FROM articles GROUP BY author`, and returns the
+// first page of rows. Large results are paged: pass the Cursor to SQLNext for each following page, and to SQLClose
+// if giving up before the last.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/sql-rest.html
+func (c *Client) SQL(ctx context.Context, query string) (*SQLResult, error) {
+	r, err := c.sql(ctx, map[string]string{"query": query})
+	if err != nil {
+		return nil, errors.Wrap(err, "SQL")
+	}
+	return r, nil
+}
+
+// SQLNext fetches the next page of rows of an SQL query. The cursor is closed once the last page has been fetched,
+// which has no Cursor.
+func (c *Client) SQLNext(ctx context.Context, cursor string) (*SQLResult, error) {
+	r, err := c.sql(ctx, map[string]string{"cursor": cursor})
+	if err != nil {
+		return nil, errors.Wrap(err, "SQLNext")
+	}
+	return r, nil
+}
+
+// SQLClose releases a cursor before its rows have all been fetched
+func (c *Client) SQLClose(ctx context.Context, cursor string) error {
+	xb, _ := json.Marshal(map[string]string{"cursor": cursor})
+	_, err := c.request(ctx, "POST", c.sqlPath(ctx, "/close"), bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "SQLClose")
+	}
+	return nil
+}
+
+// SQLTranslate returns the query DSL search request body that an SQL query is run as, eg to use with Search or to
+// learn the query DSL. OpenSearch returns its query plan instead, which holds the request body.
+func (c *Client) SQLTranslate(ctx context.Context, query string) (json.RawMessage, error) {
+	path := "/translate"
+	if c.isOpenSearch(ctx) {
+		path = "/_explain"
+	}
+	xb, _ := json.Marshal(map[string]string{"query": query})
+	xb, err := c.request(ctx, "POST", c.sqlPath(ctx, path), bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "SQLTranslate")
+	}
+	return xb, nil
+}
+
+// sql posts a query or cursor request to the SQL API. OpenSearch returns the rows in its jdbc format, with the
+// columns as "schema" and the rows as "datarows".
+func (c *Client) sql(ctx context.Context, body map[string]string) (*SQLResult, error) {
+
+	xb, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Marshal")
+	}
+
+	u := "/_sql?format=json"
+	if c.isOpenSearch(ctx) {
+		u = "/_plugins/_sql"
+	}
+	xb, err = c.request(ctx, "POST", u, bytes.NewReader(xb), standardHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	var r struct {
+		SQLResult
+		Schema   []SQLColumn     `json:"schema"`
+		DataRows [][]interface{} `json:"datarows"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	if r.Columns == nil {
+		r.Columns = r.Schema
+	}
+	if r.Rows == nil {
+		r.Rows = r.DataRows
+	}
+	return &r.SQLResult, nil
+}
+
+// sqlPath returns the path of an SQL API endpoint, eg "/close", for the cluster
+func (c *Client) sqlPath(ctx context.Context, path string) string {
+	if c.isOpenSearch(ctx) {
+		return "/_plugins/_sql" + path
+	}
+	return "/_sql" + path
+}
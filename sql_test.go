@@ -0,0 +1,67 @@
+package elastic_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestSQL(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var bodies []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xb, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, r.URL.Path+"?"+r.URL.RawQuery+" "+string(xb))
+		var req struct {
+			Cursor string `json:"cursor"`
+		}
+		json.Unmarshal(xb, &req)
+		switch {
+		case r.URL.Path == "/_sql/translate":
+			w.Write([]byte(`{"size":1000,"_source":false,"fields":[{"field":"author"}]}`))
+		case r.URL.Path == "/_sql/close":
+			w.Write([]byte(`{"succeeded":true}`))
+		case req.Cursor == "":
+			w.Write([]byte(`{"columns":[{"name":"author","type":"text"},{"name":"views","type":"long"}],` +
+				`"rows":[["Ann",12],["Bob",3]],"cursor":"c1"}`))
+		default:
+			w.Write([]byte(`{"rows":[["Cat",7]]}`))
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8))
+	r, err := e.SQL(ctx, "SELECT author, views FROM articles")
+	is.NoErr(err)
+	is.Equal(r.Columns, []elastic.SQLColumn{{Name: "author", Type: "text"}, {Name: "views", Type: "long"}})
+	is.Equal(len(r.Rows), 2)
+	is.Equal(r.Rows[0][0], "Ann")
+	is.Equal(r.Rows[0][1], 12.0)
+	is.Equal(r.Cursor, "c1")
+
+	r, err = e.SQLNext(ctx, r.Cursor)
+	is.NoErr(err)
+	is.Equal(len(r.Rows), 1)
+	is.Equal(r.Cursor, "") // last page
+
+	is.NoErr(e.SQLClose(ctx, "c2"))
+
+	dsl, err := e.SQLTranslate(ctx, "SELECT author FROM articles")
+	is.NoErr(err)
+	is.Equal(string(dsl), `{"size":1000,"_source":false,"fields":[{"field":"author"}]}`)
+
+	is.Equal(bodies, []string{
+		`/_sql?format=json {"query":"SELECT author, views FROM articles"}`,
+		`/_sql?format=json {"cursor":"c1"}`,
+		`/_sql/close? {"cursor":"c2"}`,
+		`/_sql/translate? {"query":"SELECT author FROM articles"}`,
+	})
+}
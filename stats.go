@@ -0,0 +1,211 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IndicesStats is the response from the index stats API. All holds the stats summed across the requested indices.
+type IndicesStats struct {
+	All     IndexStats            `json:"_all"`
+	Indices map[string]IndexStats `json:"indices"`
+}
+
+// IndexStats holds the stats for the primary shards, and for all shards including replicas
+type IndexStats struct {
+	Primaries StatsGroup `json:"primaries"`
+	Total     StatsGroup `json:"total"`
+}
+
+// StatsGroup holds the stats for a set of shards. Metrics that were not requested are nil.
+type StatsGroup struct {
+	Docs     *DocsStats     `json:"docs"`
+	Store    *StoreStats    `json:"store"`
+	Indexing *IndexingStats `json:"indexing"`
+	Search   *SearchStats   `json:"search"`
+	Merges   *MergeStats    `json:"merges"`
+}
+
+// DocsStats counts documents, excluding nested documents
+type DocsStats struct {
+	Count   int64 `json:"count"`
+	Deleted int64 `json:"deleted"`
+}
+
+// StoreStats is the size of the shards on disk
+type StoreStats struct {
+	SizeInBytes int64 `json:"size_in_bytes"`
+}
+
+// IndexingStats describes indexing and delete operations
+type IndexingStats struct {
+	IndexTotal           int64 `json:"index_total"`
+	IndexTimeInMillis    int64 `json:"index_time_in_millis"`
+	IndexCurrent         int64 `json:"index_current"`
+	IndexFailed          int64 `json:"index_failed"`
+	DeleteTotal          int64 `json:"delete_total"`
+	DeleteTimeInMillis   int64 `json:"delete_time_in_millis"`
+	ThrottleTimeInMillis int64 `json:"throttle_time_in_millis"`
+}
+
+// SearchStats describes the query and fetch phases of searches
+type SearchStats struct {
+	QueryTotal        int64 `json:"query_total"`
+	QueryTimeInMillis int64 `json:"query_time_in_millis"`
+	QueryCurrent      int64 `json:"query_current"`
+	FetchTotal        int64 `json:"fetch_total"`
+	FetchTimeInMillis int64 `json:"fetch_time_in_millis"`
+	ScrollTotal       int64 `json:"scroll_total"`
+	ScrollCurrent     int64 `json:"scroll_current"`
+}
+
+// MergeStats describes segment merges
+type MergeStats struct {
+	Current           int64 `json:"current"`
+	Total             int64 `json:"total"`
+	TotalTimeInMillis int64 `json:"total_time_in_millis"`
+	TotalSizeInBytes  int64 `json:"total_size_in_bytes"`
+}
+
+// Stats fetches statistics for an index, or all indices if index is empty. By default every metric is returned,
+// pass metrics, eg "indexing", "search" or "merge", to fetch only those and keep the response small.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-stats.html
+func (c *Client) Stats(ctx context.Context, index string, metrics ...string) (*IndicesStats, error) {
+
+	u := "/_stats"
+	if index != "" {
+		u = "/" + strings.ToLower(index) + u
+	}
+	if len(metrics) > 0 {
+		u += "/" + strings.Join(metrics, ",")
+	}
+
+	xb, err := c.request(ctx, "GET", u, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "Stats")
+	}
+
+	var r IndicesStats
+	err = json.Unmarshal(xb, &r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	return &r, nil
+}
+
+// ClusterStats is a summary of the cluster from the cluster stats API
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/cluster-stats.html
+type ClusterStats struct {
+	ClusterName string `json:"cluster_name"`
+	ClusterUUID string `json:"cluster_uuid"`
+	Status      string `json:"status"`
+	Indices     struct {
+		Count  int        `json:"count"`
+		Docs   DocsStats  `json:"docs"`
+		Store  StoreStats `json:"store"`
+		Shards struct {
+			Total       int     `json:"total"`
+			Primaries   int     `json:"primaries"`
+			Replication float64 `json:"replication"`
+		} `json:"shards"`
+	} `json:"indices"`
+	Nodes struct {
+		Count    map[string]int `json:"count"` // keyed by role, plus "total"
+		Versions []string       `json:"versions"`
+		JVM      struct {
+			Mem struct {
+				HeapUsedInBytes int64 `json:"heap_used_in_bytes"`
+				HeapMaxInBytes  int64 `json:"heap_max_in_bytes"`
+			} `json:"mem"`
+		} `json:"jvm"`
+		FS FSStats `json:"fs"`
+	} `json:"nodes"`
+}
+
+// NodeStats holds the stats for a single node. Metrics that were not requested are empty.
+type NodeStats struct {
+	Name    string     `json:"name"`
+	Host    string     `json:"host"`
+	Roles   []string   `json:"roles"`
+	Indices StatsGroup `json:"indices"`
+	JVM     struct {
+		UptimeInMillis int64 `json:"uptime_in_millis"`
+		Mem            struct {
+			HeapUsedInBytes int64 `json:"heap_used_in_bytes"`
+			HeapUsedPercent int   `json:"heap_used_percent"`
+			HeapMaxInBytes  int64 `json:"heap_max_in_bytes"`
+		} `json:"mem"`
+	} `json:"jvm"`
+	OS struct {
+		CPU struct {
+			Percent int `json:"percent"`
+		} `json:"cpu"`
+	} `json:"os"`
+	FS struct {
+		Total FSStats `json:"total"`
+	} `json:"fs"`
+	ThreadPool map[string]ThreadPoolStats `json:"thread_pool"`
+}
+
+// FSStats is the disk space of the data paths
+type FSStats struct {
+	TotalInBytes     int64 `json:"total_in_bytes"`
+	FreeInBytes      int64 `json:"free_in_bytes"`
+	AvailableInBytes int64 `json:"available_in_bytes"`
+}
+
+// ThreadPoolStats describes a thread pool on a node. Rejected counts tasks turned away because the queue was full.
+type ThreadPoolStats struct {
+	Threads   int   `json:"threads"`
+	Queue     int   `json:"queue"`
+	Active    int   `json:"active"`
+	Rejected  int64 `json:"rejected"`
+	Completed int64 `json:"completed"`
+}
+
+// ClusterStats fetches index, shard and node totals for the whole cluster
+func (c *Client) ClusterStats(ctx context.Context) (*ClusterStats, error) {
+
+	xb, err := c.request(ctx, "GET", uriClusterStats, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "ClusterStats")
+	}
+
+	var r ClusterStats
+	err = json.Unmarshal(xb, &r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	return &r, nil
+}
+
+// NodesStats fetches the stats of every node, keyed by node id. As with Stats, pass metrics, eg "jvm", "os", "fs",
+// "thread_pool" or "indices", to fetch only those.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/cluster-nodes-stats.html
+func (c *Client) NodesStats(ctx context.Context, metrics ...string) (map[string]NodeStats, error) {
+
+	u := "/_nodes/stats"
+	if len(metrics) > 0 {
+		u += "/" + strings.Join(metrics, ",")
+	}
+
+	xb, err := c.request(ctx, "GET", u, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "NodesStats")
+	}
+
+	var r struct {
+		Nodes map[string]NodeStats `json:"nodes"`
+	}
+	err = json.Unmarshal(xb, &r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+
+	return r.Nodes, nil
+}
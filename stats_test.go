@@ -0,0 +1,75 @@
+package elastic_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestStatsMetrics(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /articles/_stats/indexing": []byte(`{
+			"_shards": {"total": 2, "successful": 2, "failed": 0},
+			"_all": {
+				"primaries": {"indexing": {"index_total": 120, "index_time_in_millis": 340}},
+				"total": {"indexing": {"index_total": 240, "index_time_in_millis": 700}}
+			},
+			"indices": {
+				"articles": {
+					"primaries": {"indexing": {"index_total": 120, "index_time_in_millis": 340}},
+					"total": {"indexing": {"index_total": 240, "index_time_in_millis": 700}}
+				}
+			}
+		}`),
+	})
+	defer s.Close()
+
+	r, err := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass)).Stats(ctx, "articles", "indexing")
+	is.NoErr(err)
+	is.Equal(r.All.Primaries.Indexing.IndexTotal, int64(120))
+	is.Equal(r.Indices["articles"].Total.Indexing.IndexTimeInMillis, int64(700))
+	is.True(r.All.Primaries.Docs == nil) // not requested
+}
+
+func TestClusterAndNodesStats(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	s := mockServer(map[string][]byte{
+		"GET /_cluster/stats": []byte(`{
+			"cluster_name": "docs",
+			"status": "green",
+			"indices": {"count": 4, "docs": {"count": 1200, "deleted": 3}, "shards": {"total": 8, "primaries": 4}},
+			"nodes": {"count": {"total": 3, "data": 2}, "versions": ["7.10.2"], "fs": {"available_in_bytes": 1024}}
+		}`),
+		"GET /_nodes/stats/jvm,thread_pool": []byte(`{
+			"nodes": {
+				"abc": {
+					"name": "node-1",
+					"jvm": {"mem": {"heap_used_percent": 63}},
+					"thread_pool": {"write": {"threads": 2, "queue": 5, "rejected": 7}}
+				}
+			}
+		}`),
+	})
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	cs, err := e.ClusterStats(ctx)
+	is.NoErr(err)
+	is.Equal(cs.Indices.Docs.Count, int64(1200))
+	is.Equal(cs.Indices.Shards.Primaries, 4)
+	is.Equal(cs.Nodes.Count["data"], 2)
+	is.Equal(cs.Nodes.FS.AvailableInBytes, int64(1024))
+
+	ns, err := e.NodesStats(ctx, "jvm", "thread_pool")
+	is.NoErr(err)
+	is.Equal(ns["abc"].Name, "node-1")
+	is.Equal(ns["abc"].JVM.Mem.HeapUsedPercent, 63)
+	is.Equal(ns["abc"].ThreadPool["write"].Rejected, int64(7))
+}
@@ -0,0 +1,156 @@
+// Package suggest builds Elasticsearch suggesters. Each builder marshals to the JSON for its suggester so it can be
+// used as a named entry in the "suggest" of a search request body.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-suggesters.html
+package suggest
+
+import "encoding/json"
+
+// Suggester is a suggester definition
+type Suggester interface {
+	json.Marshaler
+
+	// Map returns the suggester as it will be marshaled, eg {"text": "elastc", "term": {"field": "title"}}
+	Map() map[string]interface{}
+}
+
+// TermSuggester suggests corrections for each term of the text, based on edit distance, eg for did-you-mean
+type TermSuggester struct {
+	field       string
+	text        string
+	size        int
+	suggestMode string
+}
+
+// Term returns a term suggester for text, using the terms in field
+func Term(field, text string) *TermSuggester {
+	return &TermSuggester{field: field, text: text}
+}
+
+// Size sets the most suggestions returned for each term, 5 by default
+func (s *TermSuggester) Size(n int) *TermSuggester {
+	s.size = n
+	return s
+}
+
+// SuggestMode sets which terms get suggestions: "missing", the default, only for terms not in the index, "popular"
+// for terms in fewer documents than their suggestions, or "always"
+func (s *TermSuggester) SuggestMode(mode string) *TermSuggester {
+	s.suggestMode = mode
+	return s
+}
+
+// Map returns the suggester as a map
+func (s *TermSuggester) Map() map[string]interface{} {
+	p := map[string]interface{}{"field": s.field}
+	if s.size > 0 {
+		p["size"] = s.size
+	}
+	if s.suggestMode != "" {
+		p["suggest_mode"] = s.suggestMode
+	}
+	return map[string]interface{}{"text": s.text, "term": p}
+}
+
+// MarshalJSON marshals the suggester
+func (s *TermSuggester) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Map())
+}
+
+// PhraseSuggester suggests a corrected version of the whole text, taking word order into account. It works best on
+// a field with a shingle analyzer.
+type PhraseSuggester struct {
+	field   string
+	text    string
+	size    int
+	preTag  string
+	postTag string
+}
+
+// Phrase returns a phrase suggester for text, using field
+func Phrase(field, text string) *PhraseSuggester {
+	return &PhraseSuggester{field: field, text: text}
+}
+
+// Size sets the most suggestions returned, 5 by default
+func (s *PhraseSuggester) Size(n int) *PhraseSuggester {
+	s.size = n
+	return s
+}
+
+// Highlight wraps the corrected terms of each suggestion in pre and post, eg "<em>" and "</em>", returned in the
+// Highlighted field of the option
+func (s *PhraseSuggester) Highlight(pre, post string) *PhraseSuggester {
+	s.preTag, s.postTag = pre, post
+	return s
+}
+
+// Map returns the suggester as a map
+func (s *PhraseSuggester) Map() map[string]interface{} {
+	p := map[string]interface{}{"field": s.field}
+	if s.size > 0 {
+		p["size"] = s.size
+	}
+	if s.preTag != "" || s.postTag != "" {
+		p["highlight"] = map[string]string{"pre_tag": s.preTag, "post_tag": s.postTag}
+	}
+	return map[string]interface{}{"text": s.text, "phrase": p}
+}
+
+// MarshalJSON marshals the suggester
+func (s *PhraseSuggester) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Map())
+}
+
+// CompletionSuggester suggests whole values of a completion field that start with a prefix, for search-as-you-type.
+// The options it returns are documents, with their source.
+type CompletionSuggester struct {
+	field          string
+	prefix         string
+	size           int
+	skipDuplicates bool
+	fuzziness      interface{}
+}
+
+// Completion returns a completion suggester for values of field, which must be mapped as "completion", starting
+// with prefix
+func Completion(field, prefix string) *CompletionSuggester {
+	return &CompletionSuggester{field: field, prefix: prefix}
+}
+
+// Size sets the most suggestions returned, 5 by default
+func (s *CompletionSuggester) Size(n int) *CompletionSuggester {
+	s.size = n
+	return s
+}
+
+// SkipDuplicates drops suggestions with the same text from different documents
+func (s *CompletionSuggester) SkipDuplicates() *CompletionSuggester {
+	s.skipDuplicates = true
+	return s
+}
+
+// Fuzzy matches prefixes within an edit distance, eg 1, 2 or "AUTO", so that typos still get suggestions
+func (s *CompletionSuggester) Fuzzy(fuzziness interface{}) *CompletionSuggester {
+	s.fuzziness = fuzziness
+	return s
+}
+
+// Map returns the suggester as a map
+func (s *CompletionSuggester) Map() map[string]interface{} {
+	p := map[string]interface{}{"field": s.field}
+	if s.size > 0 {
+		p["size"] = s.size
+	}
+	if s.skipDuplicates {
+		p["skip_duplicates"] = true
+	}
+	if s.fuzziness != nil {
+		p["fuzzy"] = map[string]interface{}{"fuzziness": s.fuzziness}
+	}
+	return map[string]interface{}{"prefix": s.prefix, "completion": p}
+}
+
+// MarshalJSON marshals the suggester
+func (s *CompletionSuggester) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Map())
+}
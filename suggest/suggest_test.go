@@ -0,0 +1,27 @@
+package suggest_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic/suggest"
+)
+
+func TestSuggesters(t *testing.T) {
+	is := is.New(t)
+
+	xb, err := json.Marshal(suggest.Term("title", "elastc").Size(3).SuggestMode("popular"))
+	is.NoErr(err)
+	is.Equal(string(xb), `{"term":{"field":"title","size":3,"suggest_mode":"popular"},"text":"elastc"}`)
+
+	xb, err = json.Marshal(suggest.Phrase("title.trigram", "elastc serch").Highlight("<em>", "</em>"))
+	is.NoErr(err)
+	is.Equal(string(xb), `{"phrase":{"field":"title.trigram","highlight":{"post_tag":"\u003c/em\u003e","pre_tag":"\u003cem\u003e"}},`+
+		`"text":"elastc serch"}`)
+
+	xb, err = json.Marshal(suggest.Completion("suggest", "elas").Size(5).SkipDuplicates().Fuzzy("AUTO"))
+	is.NoErr(err)
+	is.Equal(string(xb), `{"completion":{"field":"suggest","fuzzy":{"fuzziness":"AUTO"},"size":5,"skip_duplicates":true},`+
+		`"prefix":"elas"}`)
+}
@@ -0,0 +1,136 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TaskStatus is the state of a task, such as a reindex started with ReindexAsync. Response holds the result once
+// Completed, and Error the reason if the task failed.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/tasks.html
+type TaskStatus struct {
+	Completed bool             `json:"completed"`
+	Task      TaskInfo         `json:"task"`
+	Response  *ByQueryResponse `json:"response"`
+	Error     json.RawMessage  `json:"error"`
+}
+
+// TaskInfo describes a running task. Status holds task specific progress, eg the counts so far for a reindex.
+type TaskInfo struct {
+	Node               string          `json:"node"`
+	ID                 int64           `json:"id"`
+	Action             string          `json:"action"`
+	Description        string          `json:"description"`
+	StartTimeInMillis  int64           `json:"start_time_in_millis"`
+	RunningTimeInNanos int64           `json:"running_time_in_nanos"`
+	Cancellable        bool            `json:"cancellable"`
+	ParentTaskID       string          `json:"parent_task_id"`
+	Status             json.RawMessage `json:"status"`
+}
+
+// TaskID returns the "node:id" task id, as taken by GetTask and CancelTask
+func (t TaskInfo) TaskID() string {
+	return t.Node + ":" + strconv.FormatInt(t.ID, 10)
+}
+
+// TaskFilter selects the tasks returned by ListTasks. Actions match with wildcards, eg "*reindex" or
+// "indices:data/write/*", and Detailed includes each task's Description and Status.
+type TaskFilter struct {
+	Actions      []string
+	Nodes        []string
+	ParentTaskID string
+	Detailed     bool
+}
+
+// GetTask returns the status of a task, by its "node:id" task id
+func (c *Client) GetTask(ctx context.Context, taskID string) (*TaskStatus, error) {
+
+	if taskID == "" {
+		return nil, errors.New("GetTask - task id must be specified")
+	}
+
+	xb, err := c.request(ctx, "GET", "/_tasks/"+taskID, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetTask")
+	}
+
+	var ts TaskStatus
+	if err := json.Unmarshal(xb, &ts); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &ts, nil
+}
+
+// WaitForTask polls a task every interval until it completes, and returns its final status. If the task failed
+// the error holds the reason. Use a context with a deadline to give up waiting; the task keeps running.
+func (c *Client) WaitForTask(ctx context.Context, taskID string, interval time.Duration) (*TaskStatus, error) {
+
+	for {
+		ts, err := c.GetTask(ctx, taskID)
+		if err != nil {
+			return nil, errors.Wrap(err, "WaitForTask")
+		}
+		if ts.Completed {
+			if len(ts.Error) > 0 {
+				_, reason, _ := jsonError([]byte(`{"error":` + string(ts.Error) + `}`))
+				return ts, errors.New("WaitForTask - task failed: " + reason)
+			}
+			return ts, nil
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, errors.Wrap(err, "WaitForTask")
+		}
+	}
+}
+
+// ListTasks returns the tasks running on the cluster that match filter
+func (c *Client) ListTasks(ctx context.Context, filter TaskFilter) ([]TaskInfo, error) {
+
+	q := url.Values{"group_by": {"none"}}
+	if len(filter.Actions) > 0 {
+		q.Set("actions", strings.Join(filter.Actions, ","))
+	}
+	if len(filter.Nodes) > 0 {
+		q.Set("nodes", strings.Join(filter.Nodes, ","))
+	}
+	if filter.ParentTaskID != "" {
+		q.Set("parent_task_id", filter.ParentTaskID)
+	}
+	if filter.Detailed {
+		q.Set("detailed", "true")
+	}
+
+	xb, err := c.request(ctx, "GET", "/_tasks?"+q.Encode(), nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "ListTasks")
+	}
+
+	var r struct {
+		Tasks []TaskInfo `json:"tasks"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return r.Tasks, nil
+}
+
+// CancelTask asks a cancellable task, by its "node:id" task id, to stop. The task stops at its next check, so it
+// may still be running, and some of its work done, when CancelTask returns.
+func (c *Client) CancelTask(ctx context.Context, taskID string) error {
+
+	if taskID == "" {
+		return errors.New("CancelTask - task id must be specified")
+	}
+
+	_, err := c.request(ctx, "POST", "/_tasks/"+taskID+"/_cancel", nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "CancelTask")
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Template is an index template, applied to new indices whose names match IndexPatterns. Where more than one
+// template matches, the one with the highest Priority wins for composable templates, and for legacy templates they
+// are merged in order of Priority, which is sent as "order". DataStream makes a composable template create a data
+// stream, rather than an index, for names that match; it is not supported by legacy templates.
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/index-templates.html
+type Template struct {
+	IndexPatterns []string
+	Priority      int
+	Settings      *IndexSettings
+	Mappings      *Mapping
+	DataStream    bool
+}
+
+// indexTemplate is the wire format of a composable template
+type indexTemplate struct {
+	IndexPatterns []string  `json:"index_patterns"`
+	Priority      int       `json:"priority,omitempty"`
+	DataStream    *struct{} `json:"data_stream,omitempty"`
+	Template      struct {
+		Settings *IndexSettings `json:"settings,omitempty"`
+		Mappings *Mapping       `json:"mappings,omitempty"`
+	} `json:"template"`
+}
+
+// legacyTemplate is the wire format of a legacy template
+type legacyTemplate struct {
+	IndexPatterns []string       `json:"index_patterns"`
+	Order         int            `json:"order,omitempty"`
+	Settings      *IndexSettings `json:"settings,omitempty"`
+	Mappings      *Mapping       `json:"mappings,omitempty"`
+}
+
+// PutIndexTemplate creates or replaces a composable index template, which requires Elasticsearch 7.8 or later
+func (c *Client) PutIndexTemplate(ctx context.Context, name string, t Template) error {
+	var it indexTemplate
+	it.IndexPatterns, it.Priority = t.IndexPatterns, t.Priority
+	it.Template.Settings, it.Template.Mappings = t.Settings, t.Mappings
+	if t.DataStream {
+		it.DataStream = &struct{}{}
+	}
+	if err := c.putTemplate(ctx, "/_index_template/"+name, it); err != nil {
+		return errors.Wrap(err, "PutIndexTemplate")
+	}
+	return nil
+}
+
+// GetIndexTemplate returns a composable index template
+func (c *Client) GetIndexTemplate(ctx context.Context, name string) (*Template, error) {
+
+	xb, err := c.request(ctx, "GET", "/_index_template/"+name, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetIndexTemplate")
+	}
+
+	var r struct {
+		IndexTemplates []struct {
+			Name          string        `json:"name"`
+			IndexTemplate indexTemplate `json:"index_template"`
+		} `json:"index_templates"`
+	}
+	if err := json.Unmarshal(xb, &r); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	for _, v := range r.IndexTemplates {
+		if v.Name == name {
+			it := v.IndexTemplate
+			return &Template{
+				IndexPatterns: it.IndexPatterns,
+				Priority:      it.Priority,
+				Settings:      it.Template.Settings,
+				Mappings:      it.Template.Mappings,
+				DataStream:    it.DataStream != nil,
+			}, nil
+		}
+	}
+
+	return nil, errors.Wrap(ErrNotFound, "GetIndexTemplate - "+name)
+}
+
+// DeleteIndexTemplate deletes a composable index template
+func (c *Client) DeleteIndexTemplate(ctx context.Context, name string) error {
+	_, err := c.request(ctx, "DELETE", "/_index_template/"+name, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "DeleteIndexTemplate")
+	}
+	return nil
+}
+
+// PutLegacyTemplate creates or replaces a legacy index template, for clusters before 7.8. Legacy templates are
+// deprecated from 7.8 and take lower precedence than composable templates.
+func (c *Client) PutLegacyTemplate(ctx context.Context, name string, t Template) error {
+	lt := legacyTemplate{t.IndexPatterns, t.Priority, t.Settings, t.Mappings}
+	if err := c.putTemplate(ctx, "/_template/"+name, lt); err != nil {
+		return errors.Wrap(err, "PutLegacyTemplate")
+	}
+	return nil
+}
+
+// GetLegacyTemplate returns a legacy index template
+func (c *Client) GetLegacyTemplate(ctx context.Context, name string) (*Template, error) {
+
+	xb, err := c.request(ctx, "GET", "/_template/"+name, nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetLegacyTemplate")
+	}
+
+	var m map[string]legacyTemplate
+	if err := json.Unmarshal(xb, &m); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	lt, ok := m[name]
+	if !ok {
+		return nil, errors.Wrap(ErrNotFound, "GetLegacyTemplate - "+name)
+	}
+
+	return &Template{IndexPatterns: lt.IndexPatterns, Priority: lt.Order, Settings: lt.Settings, Mappings: lt.Mappings}, nil
+}
+
+// DeleteLegacyTemplate deletes a legacy index template
+func (c *Client) DeleteLegacyTemplate(ctx context.Context, name string) error {
+	_, err := c.request(ctx, "DELETE", "/_template/"+name, nil, standardHeaders)
+	if err != nil {
+		return errors.Wrap(err, "DeleteLegacyTemplate")
+	}
+	return nil
+}
+
+// putTemplate marshals v and puts it to path
+func (c *Client) putTemplate(ctx context.Context, path string, v interface{}) error {
+	xb, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+	_, err = c.request(ctx, "PUT", path, bytes.NewReader(xb), standardHeaders)
+	return err
+}
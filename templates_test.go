@@ -0,0 +1,68 @@
+package elastic_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestIndexTemplates(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	bodies := map[string]string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "PUT /_index_template/logs", "PUT /_template/logs":
+			xb, _ := ioutil.ReadAll(r.Body)
+			bodies[r.URL.Path] = string(xb)
+			w.Write([]byte(`{"acknowledged":true}`))
+		case "GET /_index_template/logs":
+			w.Write([]byte(`{"index_templates":[{"name":"logs","index_template":{"index_patterns":["logs-*"],` +
+				`"priority":10,"template":{"settings":{"index":{"number_of_shards":"2","number_of_replicas":"0"}},` +
+				`"mappings":{"properties":{"message":{"type":"text"}}}}}}]}`))
+		case "GET /_template/logs":
+			w.Write([]byte(`{"logs":{"order":3,"index_patterns":["logs-*"],"settings":{"index":{"number_of_shards":"1"}},` +
+				`"mappings":{"properties":{"message":{"type":"text"}}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass))
+	tmpl := elastic.Template{
+		IndexPatterns: []string{"logs-*"},
+		Priority:      10,
+		Settings:      &elastic.IndexSettings{NumberOfShards: 2},
+		Mappings:      &elastic.Mapping{Properties: map[string]elastic.Property{"message": {Type: "text"}}},
+	}
+	is.NoErr(e.PutIndexTemplate(ctx, "logs", tmpl))
+	is.Equal(bodies["/_index_template/logs"], `{"index_patterns":["logs-*"],"priority":10,"template":{`+
+		`"settings":{"number_of_shards":2},"mappings":{"properties":{"message":{"type":"text"}}}}}`)
+	is.NoErr(e.PutLegacyTemplate(ctx, "logs", tmpl))
+	is.Equal(bodies["/_template/logs"], `{"index_patterns":["logs-*"],"order":10,`+
+		`"settings":{"number_of_shards":2},"mappings":{"properties":{"message":{"type":"text"}}}}`)
+
+	got, err := e.GetIndexTemplate(ctx, "logs")
+	is.NoErr(err)
+	is.Equal(got.Priority, 10)
+	is.Equal(got.Settings.NumberOfShards, 2) // parsed from a string under "index"
+	is.Equal(*got.Settings.NumberOfReplicas, 0)
+	is.Equal(got.Mappings.Properties["message"].Type, "text")
+
+	got, err = e.GetLegacyTemplate(ctx, "logs")
+	is.NoErr(err)
+	is.Equal(got.Priority, 3)
+	is.Equal(got.IndexPatterns, []string{"logs-*"})
+
+	_, err = e.GetIndexTemplate(ctx, "missing")
+	is.True(errors.Is(err, elastic.ErrNotFound))
+	is.True(e.DeleteIndexTemplate(ctx, "missing") != nil)
+}
@@ -0,0 +1,80 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TermVectors are the terms of the fields of a document, from TermVectors, keyed by field
+type TermVectors struct {
+	Index   string                     `json:"_index"`
+	ID      string                     `json:"_id"`
+	Version int64                      `json:"_version"`
+	Found   bool                       `json:"found"`
+	Fields  map[string]FieldTermVector `json:"term_vectors"`
+}
+
+// FieldTermVector is the terms of one field, keyed by term, and the statistics of the field across the shard
+type FieldTermVector struct {
+	FieldStatistics *FieldStatistics      `json:"field_statistics"`
+	Terms           map[string]TermVector `json:"terms"`
+}
+
+// FieldStatistics are statistics of a field across the documents in a shard
+type FieldStatistics struct {
+	DocCount   int64 `json:"doc_count"`    // documents with the field
+	SumDocFreq int64 `json:"sum_doc_freq"` // sum of the document frequency of every term
+	SumTTF     int64 `json:"sum_ttf"`      // sum of the total term frequency of every term
+}
+
+// TermVector is a term of a field, how often it occurs, and where. DocFreq and TTF, the total number of times it
+// occurs in the shard, are only set with TermStatistics.
+type TermVector struct {
+	TermFreq int               `json:"term_freq"`
+	DocFreq  int64             `json:"doc_freq"`
+	TTF      int64             `json:"ttf"`
+	Tokens   []TermVectorToken `json:"tokens"`
+}
+
+// TermVectorToken is an occurrence of a term
+type TermVectorToken struct {
+	Position    int `json:"position"`
+	StartOffset int `json:"start_offset"`
+	EndOffset   int `json:"end_offset"`
+}
+
+// TermVectors returns the terms of the text fields of a document, as analyzed when it was indexed, with their
+// frequencies and positions, eg to see why documents are scored as similar. Pass TermVectorFields to limit the
+// fields and TermStatistics for the frequency of each term across the shard. If the document does not exist the
+// error satisfies errors.Is(err, ErrNotFound).
+// See: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-termvectors.html
+func (c *Client) TermVectors(ctx context.Context, index, id string, opts ...RequestOption) (*TermVectors, error) {
+
+	if id == "" {
+		return nil, errors.New("TermVectors - id must be specified")
+	}
+
+	u := "/" + strings.ToLower(index) + "/_termvectors/" + url.PathEscape(id)
+	if !c.typeless(ctx) {
+		u = "/" + strings.ToLower(index) + "/_doc/" + url.PathEscape(id) + "/_termvectors"
+	}
+	xb, err := c.request(ctx, "GET", withOptions(u, opts), nil, standardHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, "TermVectors")
+	}
+
+	var tv TermVectors
+	if err := json.Unmarshal(xb, &tv); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	if !tv.Found {
+		e := &Error{StatusCode: http.StatusNotFound, Reason: "document not found", Index: tv.Index, Body: xb}
+		return nil, errors.Wrap(e, "TermVectors")
+	}
+	return &tv, nil
+}
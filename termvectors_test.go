@@ -0,0 +1,44 @@
+package elastic_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+)
+
+func TestTermVectors(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	var query string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Path + "?" + r.URL.RawQuery
+		if r.URL.Path == "/articles/_termvectors/2" {
+			w.Write([]byte(`{"_index":"articles","_id":"2","_version":0,"found":false,"took":0}`))
+			return
+		}
+		w.Write([]byte(`{"_index":"articles","_id":"1","_version":1,"found":true,"took":1,"term_vectors":{"title":{` +
+			`"field_statistics":{"sum_doc_freq":6,"doc_count":2,"sum_ttf":7},"terms":{` +
+			`"fox":{"doc_freq":2,"ttf":3,"term_freq":2,"tokens":[` +
+			`{"position":1,"start_offset":6,"end_offset":9},{"position":4,"start_offset":21,"end_offset":24}]}}}}}`))
+	}))
+	defer s.Close()
+
+	e := elastic.NewClient(s.URL, elastic.WithBasicAuth(user, pass), elastic.WithVersion(8))
+	tv, err := e.TermVectors(ctx, "articles", "1", elastic.TermVectorFields("title"), elastic.TermStatistics())
+	is.NoErr(err)
+	is.Equal(query, "/articles/_termvectors/1?fields=title&term_statistics=true")
+	title := tv.Fields["title"]
+	is.Equal(title.FieldStatistics.DocCount, int64(2))
+	is.Equal(title.Terms["fox"].TermFreq, 2)
+	is.Equal(title.Terms["fox"].TTF, int64(3))
+	is.Equal(title.Terms["fox"].Tokens[1], elastic.TermVectorToken{Position: 4, StartOffset: 21, EndOffset: 24})
+
+	_, err = e.TermVectors(ctx, "articles", "2")
+	is.True(errors.Is(err, elastic.ErrNotFound))
+}
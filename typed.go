@@ -0,0 +1,104 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TypedIndex reads and writes the documents of an index as values of T, eg a struct with json tags, rather than as
+// JSON strings, eg
+//
+//	articles := elastic.NewTypedIndex[Article](c, "articles")
+//	a, err := articles.Get(ctx, "1")
+type TypedIndex[T any] struct {
+	c     *Client
+	index string
+}
+
+// TypedHit is a search hit with its source unmarshalled into Doc
+type TypedHit[T any] struct {
+	Hit
+	Doc T
+}
+
+// NewTypedIndex returns a TypedIndex for index, which may also be an alias or data stream
+func NewTypedIndex[T any](c *Client, index string) *TypedIndex[T] {
+	return &TypedIndex[T]{c: c, index: strings.ToLower(index)}
+}
+
+// Name returns the name of the index
+func (x *TypedIndex[T]) Name() string {
+	return x.index
+}
+
+// Get fetches the document with id. If it does not exist the error satisfies errors.Is(err, ErrNotFound).
+func (x *TypedIndex[T]) Get(ctx context.Context, id string, opts ...RequestOption) (T, error) {
+	var doc T
+	if _, err := x.c.GetDoc(ctx, x.index, id, &doc, opts...); err != nil {
+		return doc, errors.Wrap(err, "TypedIndex.Get")
+	}
+	return doc, nil
+}
+
+// Index adds or replaces the document with id, or adds it with a generated id if id is empty, as for IndexDoc
+func (x *TypedIndex[T]) Index(ctx context.Context, id string, doc T, opts ...RequestOption) (*DocResponse, error) {
+	r, err := x.c.IndexDocStruct(ctx, x.index, id, doc, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "TypedIndex.Index")
+	}
+	return r, nil
+}
+
+// Delete deletes the document with id
+func (x *TypedIndex[T]) Delete(ctx context.Context, id string, opts ...RequestOption) error {
+	if _, err := x.c.DeleteDoc(ctx, x.index, id, opts...); err != nil {
+		return errors.Wrap(err, "TypedIndex.Delete")
+	}
+	return nil
+}
+
+// Search runs a search request body, as for Search, and returns the hits
+func (x *TypedIndex[T]) Search(ctx context.Context, query string, opts ...RequestOption) ([]TypedHit[T], error) {
+	sr, err := x.c.Search(ctx, x.index, query, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "TypedIndex.Search")
+	}
+	xs, err := typedHits[T](sr.Hits.Hits)
+	if err != nil {
+		return nil, errors.Wrap(err, "TypedIndex.Search")
+	}
+	return xs, nil
+}
+
+// SearchWith runs the search described by r on the index, ignoring r.Index, and returns the hits
+func (x *TypedIndex[T]) SearchWith(ctx context.Context, r SearchRequest, opts ...RequestOption) ([]TypedHit[T], error) {
+	r.Index = x.index
+	sr, err := x.c.SearchWith(ctx, r, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "TypedIndex.SearchWith")
+	}
+	xs, err := typedHits[T](sr.Hits.Hits)
+	if err != nil {
+		return nil, errors.Wrap(err, "TypedIndex.SearchWith")
+	}
+	return xs, nil
+}
+
+// typedHits unmarshals the source of each hit. A hit without a source, eg from a search with "_source": false, has
+// the zero Doc.
+func typedHits[T any](hits []Hit) ([]TypedHit[T], error) {
+	xs := make([]TypedHit[T], len(hits))
+	for i, h := range hits {
+		xs[i].Hit = h
+		if len(h.Source) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(h.Source, &xs[i].Doc); err != nil {
+			return nil, errors.Wrap(err, "Unmarshal - "+h.ID)
+		}
+	}
+	return xs, nil
+}
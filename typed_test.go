@@ -0,0 +1,62 @@
+package elastic_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/mikedonnici/elastic"
+	"github.com/mikedonnici/elastic/elastictest"
+)
+
+type article struct {
+	Title string   `json:"title"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+func TestTypedIndex(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	tr := elastictest.NewTransport()
+	tr.Handle("GET", "/articles/_doc/1", http.StatusOK, `{"_index":"articles","_id":"1","found":true,`+
+		`"_source":{"title":"Go generics","tags":["go"]}}`)
+	tr.Handle("GET", "/articles/_doc/2", http.StatusNotFound, `{"_index":"articles","_id":"2","found":false}`)
+	tr.Handle("PUT", "/articles/_doc/3", http.StatusCreated, `{"_index":"articles","_id":"3","result":"created"}`)
+	tr.Handle("POST", "/articles/_search", http.StatusOK, `{"hits":{"total":{"value":2,"relation":"eq"},"hits":[`+
+		`{"_id":"1","_score":2.5,"_source":{"title":"Go generics"}},{"_id":"4","_score":1}]}}`)
+	e := elastictest.NewClient(tr)
+
+	articles := elastic.NewTypedIndex[article](e, "Articles")
+	is.Equal(articles.Name(), "articles")
+
+	a, err := articles.Get(ctx, "1")
+	is.NoErr(err)
+	is.Equal(a.Title, "Go generics")
+	is.Equal(a.Tags, []string{"go"})
+
+	_, err = articles.Get(ctx, "2")
+	is.True(errors.Is(err, elastic.ErrNotFound))
+
+	r, err := articles.Index(ctx, "3", article{Title: "Typed documents"})
+	is.NoErr(err)
+	is.Equal(r.Result, "created")
+	req, _ := tr.LastRequest("PUT", "/articles/_doc/3")
+	is.Equal(string(req.Body), `{"title":"Typed documents"}`)
+
+	hits, err := articles.Search(ctx, `{"query":{"match":{"title":"go"}}}`)
+	is.NoErr(err)
+	is.Equal(len(hits), 2)
+	is.Equal(hits[0].Doc.Title, "Go generics")
+	is.Equal(hits[0].Score, 2.5)
+	is.Equal(hits[1].ID, "4")
+	is.Equal(hits[1].Doc, article{}) // no source
+
+	size := 1
+	_, err = articles.SearchWith(ctx, elastic.SearchRequest{Index: "other", Size: &size})
+	is.NoErr(err)
+	req, _ = tr.LastRequest("POST", "/articles/_search")
+	is.Equal(string(req.Body), `{"size":1}`)
+}